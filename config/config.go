@@ -0,0 +1,156 @@
+// Package config loads configuration of the parking lot from JSON file:
+//
+//	{
+//		"preset": "UK",
+//		"registration_format": "^[A-Z]{2}[0-9]{2} ?[A-Z]{3}$",
+//		"colours": ["White", "Black", "Grey"],
+//		"colour_aliases": {"gray": "Grey", "silver": "White"},
+//		"webhooks": [{"url": "https://example.com/hooks", "events": ["car_parked"], "secret": "s3cr3t"}],
+//		"webhook_outbox": "outbox.jsonl"
+//	}
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"parking_lot/database"
+	"parking_lot/errors"
+	"parking_lot/webhook"
+)
+
+// Config is the parking lot configuration.
+type Config struct {
+	// Preset is country code of built-in validation rules, see
+	// database.Presets. Empty preset means database.DefaultRules.
+	Preset string `json:"preset"`
+
+	// RegistrationFormat is regular expression of valid registration
+	// numbers. Empty format means format of the Preset.
+	RegistrationFormat string `json:"registration_format"`
+
+	// Colours is list of valid car colours. Empty list means colours
+	// of the Preset.
+	Colours []string `json:"colours"`
+
+	// ColourAliases maps alternative colour names to Colours.
+	ColourAliases map[string]string `json:"colour_aliases"`
+
+	// Webhooks are endpoints receiving events of the parking lot, see
+	// package webhook.
+	Webhooks []webhook.Hook `json:"webhooks"`
+
+	// WebhookOutbox is file keeping webhook deliveries until they are
+	// delivered. Empty file means deliveries are kept in memory.
+	WebhookOutbox string `json:"webhook_outbox"`
+
+	rules *database.Rules
+}
+
+// Load reads and validates configuration file.
+func Load(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return c, nil
+}
+
+// Parse parses and validates JSON configuration.
+func Parse(data []byte) (*Config, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+
+	c := &Config{}
+	if err := d.Decode(c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validate builds validation rules and checks that all aliases refer to
+// known colours and that webhooks are valid.
+func (c *Config) validate() error {
+	rules := database.DefaultRules
+	if c.Preset != "" {
+		var err error
+		if rules, err = database.Preset(c.Preset); err != nil {
+			return err
+		}
+	}
+
+	c.rules = &database.Rules{
+		Name:               rules.Name,
+		RegistrationNumber: rules.RegistrationNumber,
+		Colors:             rules.Colors,
+		Canonical:          rules.Canonical,
+	}
+	if c.RegistrationFormat != "" {
+		re, err := regexp.Compile(c.RegistrationFormat)
+		if err != nil {
+			return errors.Errorf(errors.InvalidConfig, "invalid registration format: %w", err)
+		}
+		// canonical form of the preset may not match the format
+		c.rules.RegistrationNumber = re
+		c.rules.Canonical = nil
+	}
+	if len(c.Colours) > 0 {
+		c.rules.Colors = c.Colours
+	}
+
+	colours := c.rules.Colors
+	for alias, colour := range c.ColourAliases {
+		found := false
+		for i := range colours {
+			found = found || strings.EqualFold(colours[i], colour)
+		}
+		if !found {
+			return fmt.Errorf("colour alias %q refers to unknown colour %q", alias, colour)
+		}
+	}
+
+	urls := make(map[string]bool)
+	for i := range c.Webhooks {
+		h := &c.Webhooks[i]
+		if err := h.Validate(); err != nil {
+			return err
+		}
+		if urls[h.URL] {
+			return fmt.Errorf("duplicate webhook %s", h.URL)
+		}
+		urls[h.URL] = true
+	}
+	return nil
+}
+
+// Rules returns validation rules of the configuration.
+func (c *Config) Rules() *database.Rules {
+	return c.rules
+}
+
+// Normalizer returns normalizer of user input for the configuration.
+func (c *Config) Normalizer() *database.Normalizer {
+	return &database.Normalizer{
+		Colors:       c.rules.Colors,
+		ColorAliases: c.ColourAliases,
+		Canonical:    c.rules.Canonical,
+	}
+}
+
+// Configure sets validator and normalizer of the database.
+func (c *Config) Configure(db *database.Database) {
+	db.Validator = c.Rules()
+	db.Normalizer = c.Normalizer()
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/event"
+	"parking_lot/webhook"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(`{"colours": ["White", "Grey"], "colour_aliases": {"gray": "grey", "silver": "White"}}`))
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	n := c.Normalizer()
+	tests := []struct {
+		colour string
+		want   string
+	}{
+		{"white", "White"},
+		{"GRAY", "Grey"},
+		{"grey", "Grey"},
+		{"Silver", "White"},
+		{"Black", "Black"},
+	}
+	for _, tt := range tests {
+		if colour := n.Color(tt.colour); colour != tt.want {
+			t.Errorf("colour %q - want: %q, got: %q", tt.colour, tt.want, colour)
+		}
+	}
+
+	db := database.NewDatabase(database.NewMemoryWriter())
+	c.Configure(db)
+	if _, err := db.NewCar("ka-01-hh-1234", "gray"); err != nil {
+		t.Errorf("car with aliased colour expected no error but got: %s", err)
+	}
+	if _, err := db.NewCar("KA-01-HH-1234", "Black"); err == nil {
+		t.Errorf("car with colour missing in config expected error")
+	}
+}
+
+func TestParsePreset(t *testing.T) {
+	tests := []struct {
+		src                string
+		registrationNumber string
+		colour             string
+	}{
+		{`{"preset": "uk"}`, "ab12 cde", "silver"},
+		{`{"preset": "DE", "colours": ["Pink"]}`, "B-MW 1234", "pink"},
+		{`{"registration_format": "^[0-9]+$"}`, "1234", "White"},
+		{`{"preset": "UK", "registration_format": "^X$", "colour_aliases": {"argent": "Silver"}}`, "x", "argent"},
+		// spaces removed by the preset are kept for own format
+		{`{"preset": "UK", "registration_format": "^X Y$"}`, "x y", "Grey"},
+	}
+
+	for _, tt := range tests {
+		c, err := Parse([]byte(tt.src))
+		if err != nil {
+			t.Fatalf("parse %s error: %s", tt.src, err)
+		}
+
+		db := database.NewDatabase(database.NewMemoryWriter())
+		c.Configure(db)
+		if _, err := db.NewCar(tt.registrationNumber, tt.colour); err != nil {
+			t.Errorf("config %s car(%s, %s) expected no error but got: %s", tt.src, tt.registrationNumber, tt.colour, err)
+		}
+		if _, err := db.NewCar("KA-01-HH-1234", "Blue"); err == nil {
+			t.Errorf("config %s car of default rules expected error", tt.src)
+		}
+	}
+}
+
+func TestParseWebhooks(t *testing.T) {
+	src := `{"webhooks": [{"url": "https://example.com/a", "events": ["car_parked", "car_left"], "secret": "s3cr3t"}, {"url": "http://localhost:8080"}], "webhook_outbox": "outbox.jsonl"}`
+	c, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	want := []webhook.Hook{
+		{URL: "https://example.com/a", Events: []event.Kind{event.KindCarParked, event.KindCarLeft}, Secret: "s3cr3t"},
+		{URL: "http://localhost:8080"},
+	}
+	if !reflect.DeepEqual(c.Webhooks, want) {
+		t.Errorf("invalid webhooks - want: %v, got: %v", want, c.Webhooks)
+	}
+	if c.WebhookOutbox != "outbox.jsonl" {
+		t.Errorf("invalid outbox - want: %s, got: %s", "outbox.jsonl", c.WebhookOutbox)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`{"colours": "White"}`,
+		`{"colour": ["White"]}`,
+		`{"colour_aliases": {"silver": "Silver"}}`,
+		`{"colours": ["White"], "colour_aliases": {"black": "Black"}}`,
+		`{"preset": "XX"}`,
+		`{"registration_format": "["}`,
+		`{"preset": "IN", "colour_aliases": {"gray": "Grey"}}`,
+		`{"webhooks": [{"url": "example.com"}]}`,
+		`{"webhooks": [{"url": "http://example.com", "events": ["car_washed"]}]}`,
+		`{"webhooks": [{"url": "http://example.com"}, {"url": "http://example.com", "secret": "s"}]}`,
+		`{"webhooks": [{"url": "http://example.com", "filter": ["car_parked"]}]}`,
+	}
+
+	for _, src := range tests {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("parse %q expected error", src)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(file, []byte(`{"colour_aliases": {"silver": "White"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(file)
+	if err != nil {
+		t.Fatalf("load error: %s", err)
+	}
+	if colour := c.Normalizer().Color("silver"); colour != "White" {
+		t.Errorf("colour - want: %q, got: %q", "White", colour)
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("load of missing file expected error")
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []string{
+		`{"preset": "XX"}`,
+		`{"registration_format": "["}`,
+	}
+
+	for _, src := range tests {
+		file := filepath.Join(dir, "config.json")
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(file)
+		if code := lerrors.CodeOf(err); code != lerrors.InvalidConfig {
+			t.Errorf("load %q invalid error code - want: %s, got: %s", src, lerrors.InvalidConfig, code)
+		}
+	}
+}
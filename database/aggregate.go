@@ -0,0 +1,86 @@
+package database
+
+import "sort"
+
+// ColorCount is the number of parked cars of a single color.
+type ColorCount struct {
+	Color string
+	Count int
+}
+
+// Occupancy describes usage of the parking lot.
+type Occupancy struct {
+	Capacity int // number of all slots
+	Occupied int // number of slots with parked car
+}
+
+// Free returns the number of free slots.
+func (o Occupancy) Free() int {
+	return o.Capacity - o.Occupied
+}
+
+// Percent returns the occupied part of the parking lot in percents,
+// rounded down. Empty parking lot without slots has zero occupancy.
+func (o Occupancy) Percent() int {
+	if o.Capacity == 0 {
+		return 0
+	}
+	return o.Occupied * 100 / o.Capacity
+}
+
+// Count returns the number of parked cars.
+func (db *Database) Count() (int, error) {
+	o, err := db.Occupancy()
+	if err != nil {
+		return 0, err
+	}
+	return o.Occupied, nil
+}
+
+// CountByColor returns the number of parked cars per color, sorted by color.
+// Colors without parked car are omitted.
+func (db *Database) CountByColor() ([]ColorCount, error) {
+	cars, err := db.FilterCars(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, car := range cars {
+		counts[car.color]++
+	}
+
+	result := make([]ColorCount, 0, len(counts))
+	for color, n := range counts {
+		result = append(result, ColorCount{Color: color, Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Color < result[j].Color
+	})
+	return result, nil
+}
+
+// Occupancy returns the current usage of the parking lot.
+func (db *Database) Occupancy() (Occupancy, error) {
+	cars, err := db.GetAll()
+	if err != nil {
+		return Occupancy{}, err
+	}
+
+	o := Occupancy{Capacity: len(cars)}
+	for _, car := range cars {
+		if car != nil {
+			o.Occupied++
+		}
+	}
+	return o, nil
+}
+
+// FreeSlots returns the number of free slots.
+func (db *Database) FreeSlots() (int, error) {
+	o, err := db.Occupancy()
+	if err != nil {
+		return 0, err
+	}
+	return o.Free(), nil
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDatabaseAggregates(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+	db.Init(5)
+	db.Save(testCars[0])
+	db.Save(testCars[1])
+	db.Save(extraTestCar)
+	db.Save(MustNewCar("AA-00-A-003", "White"))
+	db.Remove(1)
+
+	if n, _ := db.Count(); n != 3 {
+		t.Errorf("count - want: %d, got: %d", 3, n)
+	}
+
+	if n, _ := db.FreeSlots(); n != 2 {
+		t.Errorf("free slots - want: %d, got: %d", 2, n)
+	}
+
+	want := Occupancy{Capacity: 5, Occupied: 3}
+	if o, _ := db.Occupancy(); o != want {
+		t.Errorf("occupancy - want: %v, got: %v", want, o)
+	}
+	if p := want.Percent(); p != 60 {
+		t.Errorf("occupancy percent - want: %d, got: %d", 60, p)
+	}
+
+	counts, _ := db.CountByColor()
+	wantCounts := []ColorCount{{"Red", 1}, {"White", 2}}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("count by color - want: %v, got: %v", wantCounts, counts)
+	}
+}
+
+func TestOccupancyEmpty(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+
+	o, err := db.Occupancy()
+	if err != nil {
+		t.Fatalf("occupancy error: %s", err)
+	}
+	if o.Capacity != 0 || o.Percent() != 0 || o.Free() != 0 {
+		t.Errorf("empty occupancy - want: zero, got: %v", o)
+	}
+
+	counts, _ := db.CountByColor()
+	if len(counts) != 0 {
+		t.Errorf("count by color - want: empty, got: %v", counts)
+	}
+}
@@ -1,11 +1,8 @@
 package database
 
-import (
-	"fmt"
-	"regexp"
-)
+import "regexp"
 
-// Colors is list of all valid colors.
+// Colors is list of valid colors of the default (IN) preset.
 var Colors = []string{
 	"White",
 	"Yellow",
@@ -16,7 +13,7 @@ var Colors = []string{
 	"Black",
 }
 
-// regexp to validate registration number.
+// regexp to validate registration number of the default (IN) preset.
 var validRegistrationNumber = regexp.MustCompile(`^[A-Z]{2}-\d{2}-[A-Z]{1,2}-\d{3,4}$`)
 
 // Car holds information like color and registration number.
@@ -25,21 +22,17 @@ type Car struct {
 	color              string
 }
 
-// NewCar creates a car.
+// NewCar creates a car validated with DefaultRules. The registration number
+// and color must be given in canonical form, see Normalizer for conversion
+// of user input.
 func NewCar(registrationNumber, color string) (*Car, error) {
-	if !validRegistrationNumber.Match([]byte(registrationNumber)) {
-		return nil, fmt.Errorf("car registration number %q is invalid", registrationNumber)
-	}
-
-	found := false
-	for i := range Colors {
-		if Colors[i] == color {
-			found = true
-		}
-	}
+	return newCar(registrationNumber, color, DefaultRules)
+}
 
-	if !found {
-		return nil, fmt.Errorf("car colour %q is invalid", color)
+// newCar creates a car validated with v.
+func newCar(registrationNumber, color string, v Validator) (*Car, error) {
+	if err := v.Validate(registrationNumber, color); err != nil {
+		return nil, err
 	}
 
 	return &Car{
@@ -1,8 +1,38 @@
 package database
 
+import (
+	"sort"
+
+	"parking_lot/event"
+)
+
 // Database handles filter and searching of cars.
+// Cars must be saved and removed through the Database, so its registration
+// index stays up to date.
 type Database struct {
 	Writer
+
+	// Validator validates cars parked in this lot. Nil means DefaultRules.
+	Validator Validator
+
+	// Normalizer converts user input to canonical form. Nil means
+	// normalizer of Validator colors if it is Rules, otherwise of
+	// default Colors.
+	Normalizer *Normalizer
+
+	// Events publishes LotCreated, CarParked, CarLeft and LotFull events
+	// of changes. Nil means changes are not published. Events are posted
+	// after the change is made, so errors of subscribers do not fail it,
+	// they are returned by Events.Close.
+	Events *event.Bus
+
+	// index is built on first search and then updated by Save and Remove.
+	index *registrationIndex
+
+	// occupancy is known after Init or counted on first parking with
+	// Events, and then updated by Save and Remove, so LotFull events do
+	// not read all cars.
+	occupancy *Occupancy
 }
 
 // NewDatabase creates new database with given writer.
@@ -10,8 +40,154 @@ func NewDatabase(w Writer) *Database {
 	return &Database{Writer: w}
 }
 
+// validator returns validator of the database.
+func (db *Database) validator() Validator {
+	if db.Validator != nil {
+		return db.Validator
+	}
+	return DefaultRules
+}
+
+// normalizer returns normalizer of the database.
+func (db *Database) normalizer() *Normalizer {
+	if db.Normalizer != nil {
+		return db.Normalizer
+	}
+	if r, ok := db.validator().(*Rules); ok {
+		return &Normalizer{Colors: r.Colors, Canonical: r.Canonical}
+	}
+	return NewNormalizer()
+}
+
+// NewCar creates a car from normalized user input (see Normalizer),
+// validated with the database validator.
+func (db *Database) NewCar(registrationNumber, color string) (*Car, error) {
+	n := db.normalizer()
+	return newCar(n.RegistrationNumber(registrationNumber), n.Color(color), db.validator())
+}
+
+// NormalizeRegistrationNumber returns canonical form of the registration
+// number or pattern.
+func (db *Database) NormalizeRegistrationNumber(registrationNumber string) string {
+	return db.normalizer().RegistrationNumber(registrationNumber)
+}
+
+// NormalizeColor returns canonical form of the color.
+func (db *Database) NormalizeColor(color string) string {
+	return db.normalizer().Color(color)
+}
+
+// Init initializes writer with given capacity.
+func (db *Database) Init(capacity int) error {
+	db.index = nil
+	db.occupancy = nil
+	if err := db.Writer.Init(capacity); err != nil {
+		return err
+	}
+	db.occupancy = &Occupancy{Capacity: capacity}
+	db.Events.Post(event.LotCreated{Capacity: capacity})
+	return nil
+}
+
+// Save saves given car in the first free slot.
+func (db *Database) Save(car *Car) (int, error) {
+	pos, err := db.Writer.Save(car)
+	if err != nil {
+		return pos, err
+	}
+	if db.index != nil {
+		db.index.insert(car.registrationNumber, pos)
+	}
+	if db.occupancy != nil {
+		db.occupancy.Occupied++
+	}
+	db.publishParked(car, pos)
+	return pos, nil
+}
+
+// publishParked posts CarParked event of car parked at pos and LotFull
+// event if it took the last free slot. LotFull is not posted if the
+// occupancy cannot be read, the car is parked anyway.
+func (db *Database) publishParked(car *Car, pos int) {
+	if db.Events == nil {
+		return
+	}
+
+	db.Events.Post(event.CarParked{Slot: pos + 1, RegistrationNumber: car.registrationNumber, Colour: car.color})
+	if db.occupancy == nil {
+		o, err := db.Occupancy()
+		if err != nil {
+			return
+		}
+		db.occupancy = &o
+	}
+	if db.occupancy.Free() == 0 {
+		db.Events.Post(event.LotFull{Capacity: db.occupancy.Capacity})
+	}
+}
+
+// Remove removes car from given position.
+func (db *Database) Remove(pos int) error {
+	var car *Car
+	if db.index != nil || db.Events != nil {
+		cars, err := db.GetAll()
+		if err != nil {
+			return err
+		}
+		if pos >= 0 && pos < len(cars) {
+			car = cars[pos]
+		}
+	}
+
+	if err := db.Writer.Remove(pos); err != nil {
+		return err
+	}
+	if car == nil {
+		if db.index == nil && db.Events == nil {
+			// the slot was not read, it may have been free
+			db.occupancy = nil
+		}
+		return nil
+	}
+	if db.occupancy != nil {
+		db.occupancy.Occupied--
+	}
+	if db.index != nil {
+		db.index.remove(car.registrationNumber)
+	}
+	db.Events.Post(event.CarLeft{Slot: pos + 1, RegistrationNumber: car.registrationNumber, Colour: car.color})
+	return nil
+}
+
+// SlotNumbersByRegistrationPattern returns positions of cars with
+// registration number matching the pattern (see MatchRegistrationNumber),
+// in ascending order. Cars are searched in the registration index.
+func (db *Database) SlotNumbersByRegistrationPattern(pattern string) ([]int, error) {
+	if _, err := MatchRegistrationNumber(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	if db.index == nil {
+		cars, err := db.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		db.index = newRegistrationIndex(cars)
+	}
+
+	var slots []int
+	for _, entry := range db.index.prefix(literalPrefix(pattern)) {
+		if ok, _ := MatchRegistrationNumber(pattern, entry.registrationNumber); ok {
+			slots = append(slots, entry.slot)
+		}
+	}
+	sort.Ints(slots)
+	return slots, nil
+}
+
 // FilterCars filters cars with given filter and returns them.
-// Passing nil fillter will cause in returing all cars.
+// Passing nil fillter will cause in returing all cars. Free slots are
+// never passed to the filter.
 func (db *Database) FilterCars(fok Filter) ([]*Car, error) {
 	cars, err := db.GetAll()
 	if err != nil {
@@ -20,7 +196,7 @@ func (db *Database) FilterCars(fok Filter) ([]*Car, error) {
 
 	var newCars []*Car
 	for _, car := range cars {
-		if fok == nil || fok(car) {
+		if car != nil && (fok == nil || fok(car)) {
 			newCars = append(newCars, car)
 		}
 	}
@@ -28,7 +204,8 @@ func (db *Database) FilterCars(fok Filter) ([]*Car, error) {
 }
 
 // FilterSlotNumbers filters cars with given filter and returns it's position in database.
-// Passing nil fillter will cause in returing all cars slots.
+// Passing nil fillter will cause in returing all cars slots. Free slots are
+// never passed to the filter.
 func (db *Database) FilterSlotNumbers(fok Filter) ([]int, error) {
 	cars, err := db.GetAll()
 	if err != nil {
@@ -37,7 +214,7 @@ func (db *Database) FilterSlotNumbers(fok Filter) ([]int, error) {
 
 	var slots []int
 	for i, car := range cars {
-		if fok == nil || fok(car) {
+		if car != nil && (fok == nil || fok(car)) {
 			slots = append(slots, i)
 		}
 	}
@@ -60,3 +237,67 @@ func FilterByColor(color string) Filter {
 		return c.color == color
 	}
 }
+
+// FilterByColors filters cars with one of given colors.
+func FilterByColors(colors ...string) Filter {
+	return func(c *Car) bool {
+		for i := range colors {
+			if c.color == colors[i] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterByRegistrationPattern filters cars with registration number
+// matching glob pattern, e.g. "KA-01-*-12??", or region prefix, e.g.
+// "KA-01", see MatchRegistrationNumber.
+func FilterByRegistrationPattern(pattern string) (Filter, error) {
+	if _, err := MatchRegistrationNumber(pattern, ""); err != nil {
+		return nil, err
+	}
+	return func(c *Car) bool {
+		ok, _ := MatchRegistrationNumber(pattern, c.registrationNumber)
+		return ok
+	}, nil
+}
+
+// FilterByRegion filters cars with registration number starting with region
+// prefix, e.g. "KA" or "KA-01".
+func FilterByRegion(region string) Filter {
+	return func(c *Car) bool {
+		return inRegion(region, c.registrationNumber)
+	}
+}
+
+// And returns filter which matches cars matched by all filters.
+func And(filters ...Filter) Filter {
+	return func(c *Car) bool {
+		for _, f := range filters {
+			if !f(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns filter which matches cars matched by any of filters.
+func Or(filters ...Filter) Filter {
+	return func(c *Car) bool {
+		for _, f := range filters {
+			if f(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns filter which matches cars not matched by f.
+func Not(f Filter) Filter {
+	return func(c *Car) bool {
+		return !f(c)
+	}
+}
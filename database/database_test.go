@@ -1,6 +1,13 @@
 package database
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"parking_lot/event"
+)
 
 func TestDatabaseFilterCars(t *testing.T) {
 	db := NewDatabase(NewMemoryWriter())
@@ -45,3 +52,159 @@ func TestDatabaseFilterSlotNumbers(t *testing.T) {
 		t.Errorf("filter by color failed")
 	}
 }
+
+func TestDatabaseFilterSkipsFreeSlots(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+	db.Init(3)
+	db.Save(testCars[0])
+	db.Save(testCars[1])
+	db.Remove(0)
+
+	cars, _ := db.FilterCars(FilterByColor("Black"))
+	if len(cars) != 1 {
+		t.Errorf("filter by color with free slot failed")
+	}
+
+	slots, _ := db.FilterSlotNumbers(nil)
+	if len(slots) != 1 || slots[0] != 1 {
+		t.Errorf("nil filter with free slot failed")
+	}
+}
+
+func TestFilterCombinators(t *testing.T) {
+	white, black, red := testCars[0], testCars[1], extraTestCar
+
+	pattern, err := FilterByRegistrationPattern("AA-00-?-00[01]")
+	if err != nil {
+		t.Fatalf("pattern error: %s", err)
+	}
+	if _, err := FilterByRegistrationPattern("AA-["); err == nil {
+		t.Errorf("invalid pattern expected error")
+	}
+	// patterns without special characters are region prefixes, as in
+	// MatchRegistrationNumber
+	region, _ := FilterByRegistrationPattern("AA-00")
+	partialRegion, _ := FilterByRegistrationPattern("AA-0")
+
+	tests := []struct {
+		name   string
+		filter Filter
+		cars   []*Car
+	}{
+		{"colors", FilterByColors("White", "Red"), []*Car{white, red}},
+		{"pattern", pattern, []*Car{white, black}},
+		{"region", region, []*Car{white, black, red}},
+		{"partial region", partialRegion, nil},
+		{"and", And(FilterByColors("White", "Red"), pattern), []*Car{white}},
+		{"or", Or(FilterByColor("Black"), FilterByColor("Red")), []*Car{black, red}},
+		{"not", Not(FilterByColor("Black")), []*Car{white, red}},
+		{"empty and", And(), []*Car{white, black, red}},
+		{"empty or", Or(), nil},
+	}
+
+	for _, tt := range tests {
+		var cars []*Car
+		for _, car := range []*Car{white, black, red} {
+			if tt.filter(car) {
+				cars = append(cars, car)
+			}
+		}
+		if len(cars) != len(tt.cars) {
+			t.Errorf("filter %s - want: %v, got: %v", tt.name, tt.cars, cars)
+			continue
+		}
+		for i := range cars {
+			if cars[i] != tt.cars[i] {
+				t.Errorf("filter %s - want: %v, got: %v", tt.name, tt.cars, cars)
+			}
+		}
+	}
+}
+
+func TestDatabaseEvents(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+	db.Events = event.NewBus()
+	var events []event.Event
+	db.Events.Subscribe(func(e event.Event) error {
+		events = append(events, e)
+		return nil
+	})
+
+	db.Init(2)
+	db.Save(testCars[0])
+	db.Save(testCars[1])
+	db.Save(extraTestCar)
+	db.Remove(0)
+	db.Remove(0)
+
+	want := []event.Event{
+		event.LotCreated{Capacity: 2},
+		event.CarParked{Slot: 1, RegistrationNumber: "AA-00-A-000", Colour: "White"},
+		event.CarParked{Slot: 2, RegistrationNumber: "AA-00-A-001", Colour: "Black"},
+		event.LotFull{Capacity: 2},
+		event.CarLeft{Slot: 1, RegistrationNumber: "AA-00-A-000", Colour: "White"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("invalid events - want: %v, got: %v", want, events)
+	}
+
+	// errors of subscribers of CarParked and LotFull do not fail the
+	// change, they are returned by Close of the bus
+	db.Events.Subscribe(func(e event.Event) error { return fmt.Errorf("%s failed", e.Kind()) })
+	if _, err := db.Save(testCars[0]); err != nil {
+		t.Errorf("save failed on subscriber error: %s", err)
+	}
+	if n, _ := db.Count(); n != 2 {
+		t.Errorf("car not saved on subscriber error")
+	}
+	if err := db.Remove(1); err != nil {
+		t.Errorf("remove failed on subscriber error: %s", err)
+	}
+	msg := "car_parked failed\nlot_full failed\ncar_left failed"
+	if err := db.Events.Close(); err == nil || err.Error() != msg {
+		t.Errorf("invalid close error - want: %q, got: %v", msg, err)
+	}
+}
+
+// brokenReader is a writer which saves cars but fails to read them.
+type brokenReader struct {
+	*MemoryWriter
+}
+
+func (w brokenReader) GetAll() ([]*Car, error) {
+	return nil, errors.New("read failed")
+}
+
+func TestDatabaseEventsWithoutInit(t *testing.T) {
+	w := NewMemoryWriter()
+	w.Init(2)
+	w.Save(testCars[0])
+
+	// occupancy of lot created before is counted once
+	db := NewDatabase(w)
+	db.Events = event.NewBus()
+	var full int
+	db.Events.Subscribe(func(e event.Event) error {
+		if _, ok := e.(event.LotFull); ok {
+			full++
+		}
+		return nil
+	})
+	db.Save(testCars[1])
+	db.Remove(1)
+	db.Save(testCars[1])
+	if full != 2 {
+		t.Errorf("invalid LotFull events - want: %d, got: %d", 2, full)
+	}
+
+	// failed lookup of occupancy does not fail the parking
+	db = NewDatabase(brokenReader{w})
+	db.Events = event.NewBus()
+	w.Remove(1)
+	if _, err := db.Save(testCars[1]); err != nil {
+		t.Errorf("save failed on occupancy error: %s", err)
+	}
+	if err := db.Events.Close(); err != nil {
+		t.Errorf("invalid close error - want: %v, got: %s", nil, err)
+	}
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// indexEntry is a parked car in registration index.
+type indexEntry struct {
+	registrationNumber string
+	slot               int
+}
+
+// registrationIndex keeps parked cars sorted by registration number, so
+// cars with common prefix are found by binary search.
+type registrationIndex struct {
+	entries []indexEntry
+}
+
+// newRegistrationIndex creates index of given cars.
+func newRegistrationIndex(cars []*Car) *registrationIndex {
+	idx := &registrationIndex{}
+	for i, car := range cars {
+		if car != nil {
+			idx.entries = append(idx.entries, indexEntry{car.registrationNumber, i})
+		}
+	}
+	sort.Slice(idx.entries, func(i, j int) bool {
+		return idx.entries[i].registrationNumber < idx.entries[j].registrationNumber
+	})
+	return idx
+}
+
+// search returns position of the first entry not less than registrationNumber.
+func (idx *registrationIndex) search(registrationNumber string) int {
+	return sort.Search(len(idx.entries), func(i int) bool {
+		return idx.entries[i].registrationNumber >= registrationNumber
+	})
+}
+
+func (idx *registrationIndex) insert(registrationNumber string, slot int) {
+	i := idx.search(registrationNumber)
+	idx.entries = append(idx.entries, indexEntry{})
+	copy(idx.entries[i+1:], idx.entries[i:])
+	idx.entries[i] = indexEntry{registrationNumber, slot}
+}
+
+func (idx *registrationIndex) remove(registrationNumber string) {
+	i := idx.search(registrationNumber)
+	if i < len(idx.entries) && idx.entries[i].registrationNumber == registrationNumber {
+		idx.entries = append(idx.entries[:i], idx.entries[i+1:]...)
+	}
+}
+
+// prefix returns entries with registration number starting with prefix.
+func (idx *registrationIndex) prefix(prefix string) []indexEntry {
+	i := idx.search(prefix)
+	j := i
+	for j < len(idx.entries) && strings.HasPrefix(idx.entries[j].registrationNumber, prefix) {
+		j++
+	}
+	return idx.entries[i:j]
+}
+
+// IsRegistrationPattern reports whether pattern contains glob special
+// characters (see path.Match).
+func IsRegistrationPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// MatchRegistrationNumber reports whether registration number matches the
+// pattern. Glob patterns (e.g. "KA-01-*-12??") must match the whole number,
+// other patterns are region prefixes matching whole dash separated parts,
+// so "KA-01" matches "KA-01-HH-1234", but not "KA-011-HH-1234".
+func MatchRegistrationNumber(pattern, registrationNumber string) (bool, error) {
+	if IsRegistrationPattern(pattern) {
+		return path.Match(pattern, registrationNumber)
+	}
+	return inRegion(pattern, registrationNumber), nil
+}
+
+// inRegion reports whether registration number starts with region prefix.
+func inRegion(region, registrationNumber string) bool {
+	return registrationNumber == region || strings.HasPrefix(registrationNumber, region+"-")
+}
+
+// literalPrefix returns the part of pattern before the first special character.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
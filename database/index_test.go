@@ -0,0 +1,86 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchRegistrationNumber(t *testing.T) {
+	tests := []struct {
+		pattern string
+		reg     string
+		ok      bool
+	}{
+		{"KA-01-*-12??", "KA-01-HH-1234", true},
+		{"KA-01-*-12??", "KA-01-HH-1334", false},
+		{"KA-01-*", "KA-01-HH-1234", true},
+		{"KA-0[12]-HH-1234", "KA-02-HH-1234", true},
+		{"KA", "KA-01-HH-1234", true},
+		{"KA-01", "KA-01-HH-1234", true},
+		{"KA-01", "KA-011-HH-1234", false},
+		{"KA-01-HH-1234", "KA-01-HH-1234", true},
+		{"KA-01-H", "KA-01-HH-1234", false},
+	}
+
+	for _, tt := range tests {
+		ok, err := MatchRegistrationNumber(tt.pattern, tt.reg)
+		if err != nil || ok != tt.ok {
+			t.Errorf("match %q with %q - want: %v, got: %v (%v)", tt.pattern, tt.reg, tt.ok, ok, err)
+		}
+	}
+
+	if _, err := MatchRegistrationNumber("KA-[", "KA-01-HH-1234"); err == nil {
+		t.Errorf("invalid pattern expected error")
+	}
+}
+
+func TestDatabaseSlotNumbersByRegistrationPattern(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+	db.Init(5)
+	for _, reg := range []string{"KA-01-HH-1299", "KA-02-HH-1234", "KA-01-AB-1200", "MH-01-HH-1234"} {
+		db.Save(MustNewCar(reg, "White"))
+	}
+
+	tests := []struct {
+		pattern string
+		slots   []int
+	}{
+		{"KA-01-*-12??", []int{0, 2}},
+		{"KA", []int{0, 1, 2}},
+		{"*-HH-1234", []int{1, 3}},
+		{"MH-01-HH-1234", []int{3}},
+		{"DL", nil},
+	}
+
+	check := func() {
+		t.Helper()
+		for _, tt := range tests {
+			slots, err := db.SlotNumbersByRegistrationPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("pattern %q error: %s", tt.pattern, err)
+			}
+			if !reflect.DeepEqual(slots, tt.slots) {
+				t.Errorf("pattern %q - want: %v, got: %v", tt.pattern, tt.slots, slots)
+			}
+		}
+	}
+	check()
+
+	// index must follow changes of the parking lot
+	db.Remove(0)
+	db.Remove(4)
+	db.Save(MustNewCar("DL-01-HH-1299", "Red"))
+	tests[0].slots = []int{2}
+	tests[1].slots = []int{1, 2}
+	tests[4].slots = []int{0}
+	check()
+
+	db.Init(2)
+	if slots, _ := db.SlotNumbersByRegistrationPattern("*"); len(slots) != 0 {
+		t.Errorf("pattern after init - want: [], got: %v", slots)
+	}
+
+	if _, err := db.SlotNumbersByRegistrationPattern("KA-["); err == nil {
+		t.Errorf("invalid pattern expected error")
+	}
+}
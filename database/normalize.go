@@ -0,0 +1,52 @@
+package database
+
+import "strings"
+
+// Normalizer converts car attributes given by users to their canonical form,
+// so "ka-01-hh-1234 white" is the same car as "KA-01-HH-1234 White".
+type Normalizer struct {
+	// Colors is list of valid colors in canonical form.
+	Colors []string
+
+	// ColorAliases maps alternative names to colors, e.g. "silver" to
+	// "White". Aliases and colors are matched case-insensitively.
+	ColorAliases map[string]string
+
+	// Canonical converts upper case registration number to canonical form
+	// of the rules, see Rules.Canonical. Nil means the number is kept.
+	Canonical func(registrationNumber string) string
+}
+
+// NewNormalizer creates normalizer of default Colors without aliases.
+func NewNormalizer() *Normalizer {
+	return &Normalizer{Colors: Colors}
+}
+
+// RegistrationNumber returns canonical registration number: upper case
+// without surrounding white space, converted by Canonical.
+func (n *Normalizer) RegistrationNumber(registrationNumber string) string {
+	registrationNumber = strings.ToUpper(strings.TrimSpace(registrationNumber))
+	if n.Canonical != nil {
+		registrationNumber = n.Canonical(registrationNumber)
+	}
+	return registrationNumber
+}
+
+// Color returns canonical color of the color or its alias. Unknown colors
+// are returned unchanged.
+func (n *Normalizer) Color(color string) string {
+	color = strings.TrimSpace(color)
+	for alias, c := range n.ColorAliases {
+		if strings.EqualFold(alias, color) {
+			color = c
+			break
+		}
+	}
+
+	for _, c := range n.Colors {
+		if strings.EqualFold(c, color) {
+			return c
+		}
+	}
+	return color
+}
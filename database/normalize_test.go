@@ -0,0 +1,64 @@
+package database
+
+import "testing"
+
+func TestNormalizer(t *testing.T) {
+	n := &Normalizer{Colors: Colors, ColorAliases: map[string]string{"Silver": "White"}}
+
+	if reg := n.RegistrationNumber(" ka-01-hh-1234 "); reg != "KA-01-HH-1234" {
+		t.Errorf("registration number - want: %q, got: %q", "KA-01-HH-1234", reg)
+	}
+	uk := &Normalizer{Canonical: Presets["UK"].Canonical}
+	if reg := uk.RegistrationNumber(" ab12 cde "); reg != "AB12CDE" {
+		t.Errorf("registration number of UK - want: %q, got: %q", "AB12CDE", reg)
+	}
+
+	tests := []struct {
+		color string
+		want  string
+	}{
+		{"White", "White"},
+		{"wHITE", "White"},
+		{"silver", "White"},
+		{"Purple", "Purple"},
+	}
+	for _, tt := range tests {
+		if color := n.Color(tt.color); color != tt.want {
+			t.Errorf("color %q - want: %q, got: %q", tt.color, tt.want, color)
+		}
+	}
+
+	db := NewDatabase(NewMemoryWriter())
+	db.Normalizer = n
+
+	car, err := db.NewCar("ka-01-hh-1234", "SILVER")
+	if err != nil {
+		t.Fatalf("new car error: %s", err)
+	}
+	if car.String() != "KA-01-HH-1234 White" {
+		t.Errorf("new car - want: %q, got: %q", "KA-01-HH-1234 White", car)
+	}
+	if _, err := db.NewCar("KA-01-HH-1234", "Purple"); err == nil {
+		t.Errorf("car with invalid color expected error")
+	}
+}
+
+func TestDatabaseNormalizer(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+	db.Init(1)
+
+	car, err := db.NewCar("ka-01-hh-1234", "white")
+	if err != nil {
+		t.Fatalf("new car error: %s", err)
+	}
+	db.Save(car)
+
+	slots, _ := db.FilterSlotNumbers(FilterByColor(db.NormalizeColor("WHITE")))
+	if len(slots) != 1 {
+		t.Errorf("filter by normalized color failed")
+	}
+	slots, _ = db.FilterSlotNumbers(FilterByRegistrationNumber(db.NormalizeRegistrationNumber("Ka-01-Hh-1234")))
+	if len(slots) != 1 {
+		t.Errorf("filter by normalized registration number failed")
+	}
+}
@@ -0,0 +1,123 @@
+package database
+
+// OverlayWriter is a copy-on-write Writer over base writer. Cars are read
+// from the base until the first change, then the changes are kept in
+// memory and never written to the base.
+type OverlayWriter struct {
+	base   Writer
+	mem    *MemoryWriter
+	copied bool // mem holds copy of the base
+}
+
+// NewOverlayWriter creates overlay of the base writer.
+func NewOverlayWriter(base Writer) *OverlayWriter {
+	return &OverlayWriter{base: base, mem: NewMemoryWriter()}
+}
+
+// copy copies cars of the base before the first change.
+func (w *OverlayWriter) copy() error {
+	if w.copied {
+		return nil
+	}
+
+	cars, err := w.base.GetAll()
+	if err != nil {
+		return err
+	}
+	// MemoryWriter checks positions against capacity of cars, the copy
+	// must not have more
+	w.mem.cars = make([]*Car, len(cars))
+	copy(w.mem.cars, cars)
+	w.copied = true
+	return nil
+}
+
+// Init initializes overlay with given capacity, the base is not changed.
+func (w *OverlayWriter) Init(capacity int) error {
+	if err := w.mem.Init(capacity); err != nil {
+		return err
+	}
+	w.copied = true
+	return nil
+}
+
+// Save saves given car in the first free slot of the overlay.
+func (w *OverlayWriter) Save(car *Car) (int, error) {
+	if err := w.copy(); err != nil {
+		return -1, err
+	}
+	return w.mem.Save(car)
+}
+
+// Remove removes car from given position of the overlay.
+func (w *OverlayWriter) Remove(pos int) error {
+	if err := w.copy(); err != nil {
+		return err
+	}
+	return w.mem.Remove(pos)
+}
+
+// GetAll returns all the cars of the overlay.
+func (w *OverlayWriter) GetAll() ([]*Car, error) {
+	if !w.copied {
+		return w.base.GetAll()
+	}
+	return w.mem.GetAll()
+}
+
+// Base returns the base writer.
+func (w *OverlayWriter) Base() Writer {
+	return w.base
+}
+
+// Changes returns differences of the overlay from the base.
+func (w *OverlayWriter) Changes() ([]SlotChange, error) {
+	before, err := w.base.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	after, err := w.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return DiffSlots(before, after), nil
+}
+
+// SlotChange is a change of single slot. Before or After is nil if the
+// slot is free (or does not exist).
+type SlotChange struct {
+	Pos    int // position of the slot
+	Before *Car
+	After  *Car
+}
+
+// DiffSlots returns changed slots of parking lot in order of positions.
+func DiffSlots(before, after []*Car) []SlotChange {
+	n := len(before)
+	if len(after) > n {
+		n = len(after)
+	}
+
+	var changes []SlotChange
+	for i := 0; i < n; i++ {
+		var b, a *Car
+		if i < len(before) {
+			b = before[i]
+		}
+		if i < len(after) {
+			a = after[i]
+		}
+		if !sameCar(b, a) {
+			changes = append(changes, SlotChange{Pos: i, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// sameCar reports whether cars (or free slots) are equal.
+func sameCar(a, b *Car) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOverlayWriter(t *testing.T) {
+	base := NewMemoryWriter()
+	base.Init(3)
+	base.Save(testCars[0])
+	base.Save(testCars[1])
+
+	w := NewOverlayWriter(base)
+	if cars, _ := w.GetAll(); len(cars) != 3 || cars[0] != testCars[0] {
+		t.Fatalf("overlay should read base before change, got: %v", cars)
+	}
+
+	w.Remove(0)
+	w.Save(extraTestCar)
+	w.Save(MustNewCar("AA-00-A-003", "Black"))
+	if _, err := w.Save(MustNewCar("AA-00-A-004", "Black")); err != ErrFull {
+		t.Errorf("save to full overlay - want: %v, got: %v", ErrFull, err)
+	}
+
+	baseCars, _ := base.GetAll()
+	if baseCars[0] != testCars[0] || baseCars[1] != testCars[1] || baseCars[2] != nil {
+		t.Errorf("base changed by overlay: %v", baseCars)
+	}
+
+	changes, err := w.Changes()
+	if err != nil {
+		t.Fatalf("changes error: %s", err)
+	}
+	want := []SlotChange{
+		{Pos: 0, Before: testCars[0], After: extraTestCar},
+		{Pos: 2, Before: nil, After: MustNewCar("AA-00-A-003", "Black")},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes - want: %v, got: %v", want, changes)
+	}
+
+	w.Init(1)
+	changes, _ = w.Changes()
+	want = []SlotChange{
+		{Pos: 0, Before: testCars[0], After: nil},
+		{Pos: 1, Before: testCars[1], After: nil},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes after init - want: %v, got: %v", want, changes)
+	}
+}
+
+// Dry run removes cars through overlay, positions past the base must fail.
+func TestOverlayWriterRemoveOutOfRange(t *testing.T) {
+	tests := []struct {
+		capacity int
+		pos      int
+	}{
+		{1, 1},
+		{3, 3},
+		{5, 5},
+		{7, 7},
+		{5, -1},
+	}
+
+	for _, tt := range tests {
+		base := NewMemoryWriter()
+		base.Init(tt.capacity)
+		w := NewOverlayWriter(base)
+
+		err := w.Remove(tt.pos)
+		want := &ErrOutOfRange{tt.pos, tt.capacity}
+		if !reflect.DeepEqual(err, want) {
+			t.Errorf("remove %d from overlay of %d slots - want: %v, got: %v", tt.pos, tt.capacity, want, err)
+		}
+		if cars, _ := w.GetAll(); len(cars) != tt.capacity {
+			t.Errorf("overlay of %d slots has %d slots after remove", tt.capacity, len(cars))
+		}
+	}
+}
+
+func TestDiffSlots(t *testing.T) {
+	same := MustNewCar(testCars[0].registrationNumber, testCars[0].color)
+	changes := DiffSlots([]*Car{testCars[0], nil}, []*Car{same, nil, testCars[1]})
+
+	want := []SlotChange{{Pos: 2, Before: nil, After: testCars[1]}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("diff - want: %v, got: %v", want, changes)
+	}
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"parking_lot/errors"
+)
+
+// Validator validates car attributes in canonical form before the car
+// is created.
+type Validator interface {
+	Validate(registrationNumber, color string) error
+}
+
+// ValidatorFunc is an adapter to use ordinary function as Validator.
+type ValidatorFunc func(registrationNumber, color string) error
+
+// Validate calls f(registrationNumber, color).
+func (f ValidatorFunc) Validate(registrationNumber, color string) error {
+	return f(registrationNumber, color)
+}
+
+// ValidatorSet is a Validator which runs all validators in order and
+// returns the first error.
+type ValidatorSet []Validator
+
+// Validate validates car with all validators of the set.
+func (s ValidatorSet) Validate(registrationNumber, color string) error {
+	for _, v := range s {
+		if err := v.Validate(registrationNumber, color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rules validates registration number format and color catalogue.
+type Rules struct {
+	// Name of the rules, e.g. country code of preset.
+	Name string
+
+	// RegistrationNumber is the format of registration numbers.
+	RegistrationNumber *regexp.Regexp
+
+	// Colors is list of valid colors.
+	Colors []string
+
+	// Canonical converts upper case registration number to its canonical
+	// form, so different spellings of a number are the same car. Nil means
+	// the number is kept.
+	Canonical func(registrationNumber string) string
+}
+
+// Validate checks that registration number has valid format and color
+// is in catalogue.
+func (r *Rules) Validate(registrationNumber, color string) error {
+	if !r.RegistrationNumber.MatchString(registrationNumber) {
+		return errors.Errorf(errors.InvalidReg, "car registration number %q is invalid", registrationNumber)
+	}
+
+	for i := range r.Colors {
+		if r.Colors[i] == color {
+			return nil
+		}
+	}
+	return errors.Errorf(errors.InvalidColour, "car colour %q is invalid", color)
+}
+
+// Presets are built-in rules of several countries, by country code.
+var Presets = map[string]*Rules{
+	"IN": {
+		Name:               "IN",
+		RegistrationNumber: validRegistrationNumber, // KA-01-HH-1234
+		Colors:             Colors,
+	},
+	"UK": {
+		Name:               "UK",
+		RegistrationNumber: regexp.MustCompile(`^[A-Z]{2}\d{2} ?[A-Z]{3}$`), // AB12 CDE
+		Colors:             []string{"White", "Black", "Silver", "Grey", "Blue", "Red", "Green", "Yellow", "Brown"},
+		Canonical:          removeSpaces, // AB12CDE
+	},
+	"US": {
+		Name:               "US",
+		RegistrationNumber: regexp.MustCompile(`^[A-Z0-9]([A-Z0-9 -]{0,6}[A-Z0-9])?$`), // 7ABC123
+		Colors:             []string{"White", "Black", "Silver", "Gray", "Blue", "Red", "Green", "Yellow", "Brown"},
+	},
+	"DE": {
+		Name:               "DE",
+		RegistrationNumber: regexp.MustCompile(`^[A-Z]{1,3}-[A-Z]{1,2} [1-9]\d{0,3}[EH]?$`), // B-MW 1234
+		Colors:             []string{"White", "Black", "Silver", "Grey", "Blue", "Red", "Green", "Yellow", "Brown"},
+	},
+}
+
+// removeSpaces removes white space of registration number.
+func removeSpaces(registrationNumber string) string {
+	return strings.Join(strings.Fields(registrationNumber), "")
+}
+
+// DefaultRules are rules used by NewCar and Database without Validator.
+var DefaultRules = Presets["IN"]
+
+// Preset returns built-in rules of the country, the code is case-insensitive.
+func Preset(country string) (*Rules, error) {
+	if r, ok := Presets[strings.ToUpper(country)]; ok {
+		return r, nil
+	}
+
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, errors.Errorf(errors.InvalidConfig, "unknown preset %q: use one of %s", country, strings.Join(names, ", "))
+}
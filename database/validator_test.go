@@ -0,0 +1,100 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	tests := []struct {
+		preset             string
+		registrationNumber string
+		color              string
+		expectedError      bool
+	}{
+		{"IN", "KA-01-HH-1234", "White", false},
+		{"IN", "AB12 CDE", "White", true},
+		{"IN", "KA-01-HH-1234", "Silver", true},
+		{"uk", "AB12 CDE", "Silver", false},
+		{"UK", "AB12CDE", "Grey", false},
+		{"UK", "KA-01-HH-1234", "White", true},
+		{"US", "7ABC123", "Gray", false},
+		{"US", "ABC-123", "White", false},
+		{"US", "ABCDEFGHI", "White", true},
+		{"DE", "B-MW 1234", "Black", false},
+		{"DE", "M-A 1E", "Black", false},
+		{"DE", "B-MW 0123", "Black", true},
+	}
+
+	for _, tt := range tests {
+		r, err := Preset(tt.preset)
+		if err != nil {
+			t.Fatalf("preset %s error: %s", tt.preset, err)
+		}
+
+		err = r.Validate(tt.registrationNumber, tt.color)
+		if tt.expectedError && err == nil {
+			t.Errorf("preset %s car(%s, %s) expected error but got: <nil>", tt.preset, tt.registrationNumber, tt.color)
+		}
+		if !tt.expectedError && err != nil {
+			t.Errorf("preset %s car(%s, %s) expected no error but got: %s", tt.preset, tt.registrationNumber, tt.color, err)
+		}
+	}
+
+	if _, err := Preset("XX"); err == nil {
+		t.Errorf("unknown preset expected error")
+	}
+}
+
+func TestValidatorSet(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	blocked := ValidatorFunc(func(registrationNumber, color string) error {
+		if registrationNumber == "KA-01-HH-6666" {
+			return errBlocked
+		}
+		return nil
+	})
+
+	db := NewDatabase(NewMemoryWriter())
+	db.Validator = ValidatorSet{DefaultRules, blocked}
+
+	if _, err := db.NewCar("KA-01-HH-1234", "White"); err != nil {
+		t.Errorf("valid car expected no error but got: %s", err)
+	}
+	if _, err := db.NewCar("KA-01-HH-6666", "White"); err != errBlocked {
+		t.Errorf("blocked car - want: %v, got: %v", errBlocked, err)
+	}
+	if _, err := db.NewCar("KA-01-HH-1234", "Pink"); err == nil {
+		t.Errorf("invalid color expected error")
+	}
+}
+
+func TestDatabaseValidatorPreset(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+	db.Validator = Presets["UK"]
+
+	// colors of the preset are normalized too
+	car, err := db.NewCar("ab12 cde", "silver")
+	if err != nil {
+		t.Fatalf("new car error: %s", err)
+	}
+	if car.String() != "AB12CDE Silver" {
+		t.Errorf("new car - want: %q, got: %q", "AB12CDE Silver", car)
+	}
+
+	// spellings of UK number with and without space are the same car
+	db.Init(2)
+	if _, err := db.Save(car); err != nil {
+		t.Fatalf("save error: %s", err)
+	}
+	same, err := db.NewCar("AB12CDE", "White")
+	if err != nil {
+		t.Fatalf("new car error: %s", err)
+	}
+	if _, err := db.Save(same); err != ErrIdentity {
+		t.Errorf("save of the same car - want: %v, got: %v", ErrIdentity, err)
+	}
+	if reg := db.NormalizeRegistrationNumber(" ab12  cde"); reg != "AB12CDE" {
+		t.Errorf("normalized registration number - want: %q, got: %q", "AB12CDE", reg)
+	}
+}
@@ -1,8 +1,9 @@
 package database
 
 import (
-	"errors"
 	"fmt"
+
+	"parking_lot/errors"
 )
 
 // Writer is an interface for stroing the cars.
@@ -13,7 +14,7 @@ type Writer interface {
 	GetAll() ([]*Car, error)
 }
 
-// ErrOutOfRange is out of range error.
+// ErrOutOfRange is out of range error, it has code SLOT_OUT_OF_RANGE.
 type ErrOutOfRange struct {
 	pos      int
 	capacity int
@@ -23,12 +24,22 @@ func (e *ErrOutOfRange) Error() string {
 	return fmt.Sprintf("slot number %d out of range [1, %d]", e.pos, e.capacity)
 }
 
+// ErrorCode returns SLOT_OUT_OF_RANGE.
+func (e *ErrOutOfRange) ErrorCode() errors.Code {
+	return errors.SlotOutOfRange
+}
+
+// Is reports whether target is SLOT_OUT_OF_RANGE.
+func (e *ErrOutOfRange) Is(target error) bool {
+	return target == errors.SlotOutOfRange
+}
+
 var (
 	// ErrFull is returned when there is no more parking slots.
-	ErrFull = errors.New("sorry, parking lot is full")
+	ErrFull = errors.New(errors.LotFull, "sorry, parking lot is full")
 	// ErrIdentity is returned when two cars with the same registration number
 	// are saved.
-	ErrIdentity = errors.New("identity thieves are not welcome, calling police")
+	ErrIdentity = errors.New(errors.DuplicateReg, "identity thieves are not welcome, calling police")
 )
 
 // MemoryWriter is writer that keeps everything in memory.
@@ -44,6 +55,9 @@ func NewMemoryWriter() *MemoryWriter {
 // Init initializes writer with given capacity.
 // Call Init again will remove all cars from current writer.
 func (w *MemoryWriter) Init(capacity int) error {
+	if capacity < 0 {
+		return errors.Errorf(errors.InvalidCapacity, "invalid parking lot capacity %d", capacity)
+	}
 	w.cars = make([]*Car, capacity)
 	return nil
 }
@@ -58,7 +72,6 @@ func (w *MemoryWriter) Save(car *Car) (int, error) {
 
 		if w.cars[i] == nil && saveIndex == -1 {
 			saveIndex = i
-			break
 		}
 	}
 
@@ -84,30 +97,33 @@ func (w *MemoryWriter) GetAll() ([]*Car, error) {
 	return w.cars, nil
 }
 
+// errUnimplemented is returned by FileWriter.
+var errUnimplemented = errors.New(errors.StorageError, "unimplemented")
+
 // FileWriter writes cars info to file.
 type FileWriter struct{}
 
 // NewFileWriter creates new file writer.
 func NewFileWriter(file string) (*FileWriter, error) {
-	return nil, errors.New("unimplemented")
+	return nil, errUnimplemented
 }
 
 // Init initializes writer with given capacity.
 func (w *FileWriter) Init(capacity int) error {
-	return errors.New("unimplemented")
+	return errUnimplemented
 }
 
 // Save saves given car in the first free slot.
 func (w *FileWriter) Save(car *Car) (int, error) {
-	return -1, errors.New("unimplemented")
+	return -1, errUnimplemented
 }
 
 // Remove removes cars from given position.
 func (w *FileWriter) Remove(pos int) error {
-	return errors.New("unimplemented")
+	return errUnimplemented
 }
 
 // GetAll returns all the cars.
 func (w *FileWriter) GetAll() ([]*Car, error) {
-	return nil, errors.New("unimplemented")
+	return nil, errUnimplemented
 }
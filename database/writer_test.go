@@ -1,6 +1,11 @@
 package database
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	lerrors "parking_lot/errors"
+)
 
 var (
 	testCars = []*Car{
@@ -33,6 +38,10 @@ func TestMemoryWriterInit(t *testing.T) {
 	if l := cap(w.cars); l != 0 {
 		t.Fatalf("reinit invalid capacity - want: %d, got: %d", 0, l)
 	}
+
+	if err := w.Init(-1); err == nil {
+		t.Fatalf("init with negative capacity expected error")
+	}
 }
 
 func TestMemoryWriterSave(t *testing.T) {
@@ -76,6 +85,22 @@ func TestMemoryWriterSave(t *testing.T) {
 	}
 }
 
+func TestMemoryWriterSaveAfterFreeSlot(t *testing.T) {
+	w := NewMemoryWriter()
+	w.Init(2)
+	w.Save(testCars[0])
+	w.Save(testCars[1])
+	w.Remove(0)
+
+	// the car parked after the free slot must be found too
+	if _, err := w.Save(testCars[1]); err != ErrIdentity {
+		t.Fatalf("save error - want: %s, got: %v", ErrIdentity, err)
+	}
+	if w.cars[0] != nil {
+		t.Fatalf("car[0] must be free - got: %s", w.cars[0])
+	}
+}
+
 func TestMemoryWriterRemove(t *testing.T) {
 	w := NewMemoryWriter()
 
@@ -120,3 +145,45 @@ func TestMemoryWriterGetAll(t *testing.T) {
 		t.Fatalf("get all returned invalid number of cars - want: %d, got: %d", len(testCars), len(newCars))
 	}
 }
+
+func TestErrorCodes(t *testing.T) {
+	db := NewDatabase(NewMemoryWriter())
+	_, errRegistration := db.NewCar("KA-01", "White")
+	_, errColour := db.NewCar("KA-01-HH-1234", "Pink")
+	errCapacity := db.Init(-1)
+	db.Init(1)
+	db.Save(testCars[0])
+	_, errIdentity := db.Save(testCars[0])
+	_, errFull := db.Save(testCars[1])
+	errOutOfRange := db.Remove(1)
+
+	tests := []struct {
+		err  error
+		code lerrors.Code
+	}{
+		{errRegistration, lerrors.InvalidReg},
+		{errColour, lerrors.InvalidColour},
+		{errCapacity, lerrors.InvalidCapacity},
+		{errIdentity, lerrors.DuplicateReg},
+		{errFull, lerrors.LotFull},
+		{errOutOfRange, lerrors.SlotOutOfRange},
+		{errUnimplemented, lerrors.StorageError},
+	}
+
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.code) {
+			t.Errorf("error %v is not %s", tt.err, tt.code)
+		}
+		if code := lerrors.CodeOf(tt.err); code != tt.code {
+			t.Errorf("error %v invalid code - want: %s, got: %s", tt.err, tt.code, code)
+		}
+	}
+
+	if errFull != ErrFull {
+		t.Errorf("invalid error - want: %v, got: %v", ErrFull, errFull)
+	}
+	var outOfRange *ErrOutOfRange
+	if !errors.As(errOutOfRange, &outOfRange) {
+		t.Errorf("error %v is not ErrOutOfRange", errOutOfRange)
+	}
+}
@@ -0,0 +1,159 @@
+// Package diff computes differences of texts by lines in unified format.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines around changes.
+const context = 3
+
+// edit is a single line of edit script.
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// Diff returns unified diff of old and new texts, or nil if they are equal.
+func Diff(oldName string, old []byte, newName string, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	edits := lineEdits(splitLines(old), splitLines(new))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+
+	// line numbers of the next edit in old and new text, 1-based
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// hunk starts with context before the change and ends when there
+		// are more unchanged lines than two contexts
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			n := 0
+			for end+n < len(edits) && edits[end+n].op == ' ' {
+				n++
+			}
+			if end+n == len(edits) || n > 2*context {
+				end += min(n, context)
+				break
+			}
+			end += n
+		}
+
+		hunkOld, hunkNew := oldLine-(i-start), newLine-(i-start)
+		var oldCount, newCount int
+		var hunk strings.Builder
+		for _, e := range edits[start:end] {
+			switch e.op {
+			case ' ':
+				oldCount++
+				newCount++
+			case '-':
+				oldCount++
+			case '+':
+				newCount++
+			}
+			hunk.WriteByte(e.op)
+			hunk.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				hunk.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount))
+		buf.WriteString(hunk.String())
+
+		for _, e := range edits[i:end] {
+			if e.op != '+' {
+				oldLine++
+			}
+			if e.op != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return buf.Bytes()
+}
+
+// hunkRange formats start and length of hunk lines.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// splitLines splits text after new lines.
+func splitLines(text []byte) []string {
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineEdits returns the shortest edit script changing lines a to b,
+// computed from their longest common subsequence.
+func lineEdits(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
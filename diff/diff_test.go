@@ -0,0 +1,47 @@
+package diff
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old  string
+		new  string
+		diff string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"",
+			"a\n",
+			"--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			"a\nb",
+			"a\nb\n",
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"--- old\n+++ new\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n",
+			"1\n2\nthree\n4\n5\n6\nseven\n",
+			"--- old\n+++ new\n@@ -1,7 +1,7 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n-7\n+seven\n",
+		},
+	}
+
+	for _, tt := range tests {
+		diff := string(Diff("old", []byte(tt.old), "new", []byte(tt.new)))
+		if diff != tt.diff {
+			t.Errorf("diff %q and %q - want:\n%s\ngot:\n%s", tt.old, tt.new, tt.diff, diff)
+		}
+	}
+}
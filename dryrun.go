@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"parking_lot/database"
+)
+
+// printDryRun prints slots changed in the overlay of dry run.
+func printDryRun(w io.Writer, overlay *database.OverlayWriter) error {
+	before, err := overlay.Base().GetAll()
+	if err != nil {
+		return err
+	}
+	after, err := overlay.GetAll()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "Dry run, no changes were saved")
+	if len(before) != len(after) {
+		fmt.Fprintf(w, "Capacity: %d -> %d\n", len(before), len(after))
+	}
+
+	changes := database.DiffSlots(before, after)
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No slots changed")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 4, ' ', 0)
+	fmt.Fprintf(tw, "Slot No.\tBefore\tAfter\n")
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", c.Pos+1, slotString(c.Before), slotString(c.After))
+	}
+	return tw.Flush()
+}
+
+// slotString returns description of a car in slot.
+func slotString(car *database.Car) string {
+	if car == nil {
+		return "-"
+	}
+	return car.String()
+}
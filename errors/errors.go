@@ -1,10 +1,100 @@
+// Package errors defines errors with stable codes shared by database,
+// parser and executor.
+//
+// A Code is itself an error, so coded errors are matched by code with
+// errors.Is of the standard library:
+//
+//	if errors.Is(err, errors.LotFull) { ... }
 package errors
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
+// Code is a stable code of error.
+type Code string
+
+// Error codes.
+const (
+	LotFull         Code = "LOT_FULL"          // no free slot
+	DuplicateReg    Code = "DUPLICATE_REG"     // registration number is already parked
+	InvalidReg      Code = "INVALID_REG"       // invalid registration number
+	InvalidColour   Code = "INVALID_COLOUR"    // invalid colour
+	InvalidCapacity Code = "INVALID_CAPACITY"  // invalid parking lot capacity
+	SlotOutOfRange  Code = "SLOT_OUT_OF_RANGE" // slot number out of parking lot
+	NotFound        Code = "NOT_FOUND"         // query found no car
+	SyntaxError     Code = "SYNTAX_ERROR"      // invalid source code
+	LimitExceeded   Code = "LIMIT_EXCEEDED"    // execution limit exceeded
+	InvalidConfig   Code = "INVALID_CONFIG"    // invalid configuration
+	StorageError    Code = "STORAGE_ERROR"     // storage failed
+	ExecutionFailed Code = "EXECUTION_FAILED"  // any other error of statement
+)
+
+// Error returns the code.
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Coder is implemented by errors with code.
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Error is an error with code.
+type Error struct {
+	Code Code
+	Msg  string
+	Err  error // wrapped error, may be nil
+}
+
+// New creates error with code and message.
+func New(code Code, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+// Errorf creates error with code and formatted message. Error of %w verb
+// is wrapped.
+func Errorf(code Code, format string, args ...interface{}) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Msg: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// Error returns the message.
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the code of e.
+func (e *Error) Is(target error) bool {
+	return target == e.Code
+}
+
+// ErrorCode returns the code of e.
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// CodeOf returns the code of the first error in err's chain which has
+// one, or empty code.
+func CodeOf(err error) Code {
+	var c Coder
+	if errors.As(err, &c) {
+		return c.ErrorCode()
+	}
+	var code Code
+	if errors.As(err, &code) {
+		return code
+	}
+	return ""
+}
+
 // Join combines multiple error into one sperated with new line.
 func Join(errs []error) error {
 	if len(errs) == 0 {
@@ -2,6 +2,7 @@ package errors
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -15,3 +16,57 @@ func TestJoin(t *testing.T) {
 		t.Fatalf("join errors failed - want: %q, got: %q", "e1\ne2", err.Error())
 	}
 }
+
+// outOfRange is an error type with code.
+type outOfRange struct{}
+
+func (outOfRange) Error() string   { return "out of range" }
+func (outOfRange) ErrorCode() Code { return SlotOutOfRange }
+
+func TestCode(t *testing.T) {
+	full := New(LotFull, "sorry, parking lot is full")
+
+	tests := []struct {
+		err  error
+		code Code
+		msg  string
+	}{
+		{full, LotFull, "sorry, parking lot is full"},
+		{fmt.Errorf("park: %w", full), LotFull, "park: sorry, parking lot is full"},
+		{Errorf(InvalidColour, "car colour %q is invalid", "Pink"), InvalidColour, `car colour "Pink" is invalid`},
+		{Errorf(StorageError, "open: %w", full), StorageError, "open: sorry, parking lot is full"},
+		{outOfRange{}, SlotOutOfRange, "out of range"},
+		{NotFound, NotFound, "NOT_FOUND"},
+		{errors.New("e1"), "", "e1"},
+		{nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		if code := CodeOf(tt.err); code != tt.code {
+			t.Errorf("error %v invalid code - want: %s, got: %s", tt.err, tt.code, code)
+		}
+		if tt.err == nil {
+			continue
+		}
+		if tt.err.Error() != tt.msg {
+			t.Errorf("invalid message - want: %s, got: %s", tt.msg, tt.err.Error())
+		}
+		if e, ok := tt.err.(*Error); ok && !errors.Is(tt.err, tt.code) {
+			t.Errorf("error %v is not %s", e, tt.code)
+		}
+	}
+
+	// code of the outer error wins, the wrapped error is still matched
+	err := Errorf(StorageError, "open: %w", full)
+	if !errors.Is(err, full) || !errors.Is(err, LotFull) || !errors.Is(err, StorageError) {
+		t.Errorf("error %v does not match wrapped error", err)
+	}
+	if errors.Is(full, DuplicateReg) {
+		t.Errorf("error %v matches other code", full)
+	}
+
+	var e *Error
+	if !errors.As(fmt.Errorf("park: %w", full), &e) || e != full {
+		t.Errorf("error %v is not found by errors.As", full)
+	}
+}
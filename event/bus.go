@@ -0,0 +1,219 @@
+package event
+
+import (
+	"errors"
+	"sync"
+)
+
+// queueSize is the number of events queued for an async subscriber before
+// Publish blocks.
+const queueSize = 64
+
+// Handler handles an event.
+type Handler func(e Event) error
+
+// subscriber is a handler of events of some kinds, all kinds if kinds is
+// empty. Events of async subscriber are queued and handled by its own
+// goroutine until done is closed.
+type subscriber struct {
+	handler Handler
+	kinds   []Kind
+	queue   chan Event    // nil for synchronous subscriber
+	done    chan struct{} // closed when async subscriber is removed
+}
+
+// send queues event for async subscriber. It blocks while the queue is
+// full, unless the subscriber is removed meanwhile.
+func (s *subscriber) send(e Event) {
+	select {
+	case s.queue <- e:
+	case <-s.done:
+	}
+}
+
+// run handles queued events until the subscriber is removed, then handles
+// events queued before.
+func (s *subscriber) run(record func(error)) {
+	for {
+		select {
+		case e := <-s.queue:
+			record(s.handler(e))
+		case <-s.done:
+			for {
+				select {
+				case e := <-s.queue:
+					record(s.handler(e))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// accepts reports whether subscriber handles events of kind k.
+func (s *subscriber) accepts(k Kind) bool {
+	if len(s.kinds) == 0 {
+		return true
+	}
+	for _, kind := range s.kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus delivers published events to subscribers. Synchronous subscribers
+// handle events in Publish, in order of subscription. Async subscribers
+// handle events in their own goroutines, in order of publishing, so slow
+// integrations do not hold the program.
+//
+// Publish on nil Bus does nothing, so publishers need not check whether
+// anyone listens.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []*subscriber
+	closed      bool
+
+	wg    sync.WaitGroup // async subscribers
+	errMu sync.Mutex
+	errs  []error // errors of async handlers and of handlers of Post
+}
+
+// NewBus creates new bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe subscribes synchronous handler of events of given kinds, or
+// of all events if no kind is given. Errors of the handler are returned
+// by Publish. The returned function unsubscribes the handler.
+func (b *Bus) Subscribe(h Handler, kinds ...Kind) (unsubscribe func()) {
+	return b.subscribe(&subscriber{handler: h, kinds: kinds})
+}
+
+// SubscribeAsync subscribes handler of events of given kinds, or of all
+// events if no kind is given, running in its own goroutine. Errors of the
+// handler are returned by Close. The returned function unsubscribes the
+// handler after it handles queued events.
+func (b *Bus) SubscribeAsync(h Handler, kinds ...Kind) (unsubscribe func()) {
+	s := &subscriber{handler: h, kinds: kinds, queue: make(chan Event, queueSize), done: make(chan struct{})}
+	unsubscribe = b.subscribe(s)
+	go func() {
+		defer b.wg.Done()
+		s.run(b.record)
+	}()
+	return unsubscribe
+}
+
+// subscribe adds subscriber and returns function removing it. It panics
+// if the bus is closed.
+func (b *Bus) subscribe(s *subscriber) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		panic("event: Subscribe on closed Bus")
+	}
+	b.subscribers = append(b.subscribers, s)
+	if s.queue != nil {
+		b.wg.Add(1)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(s) })
+	}
+}
+
+// unsubscribe removes subscriber and stops its goroutine.
+func (b *Bus) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.subscribers {
+		if b.subscribers[i] == s {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			if s.queue != nil {
+				close(s.done)
+			}
+			return
+		}
+	}
+}
+
+// Publish delivers event to subscribers of its kind. It returns errors of
+// synchronous handlers, all of them are called even if some fail. Events
+// published after Close are handled only by synchronous subscribers.
+func (b *Bus) Publish(e Event) error {
+	if b == nil {
+		return nil
+	}
+
+	// subscribers are copied, so a full queue does not block unsubscribe
+	b.mu.RLock()
+	subscribers := append([]*subscriber(nil), b.subscribers...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if !s.accepts(e.Kind()) {
+			continue
+		}
+		if s.queue != nil {
+			s.send(e)
+		} else if err := s.handler(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Post delivers event like Publish, but errors of synchronous handlers are
+// returned by Close with errors of async handlers. It is meant for
+// publishers of changes already made, which must not fail because a
+// subscriber did.
+func (b *Bus) Post(e Event) {
+	if b == nil {
+		return
+	}
+	b.record(b.Publish(e))
+}
+
+// record records error of handler returned by Close.
+func (b *Bus) record(err error) {
+	if err == nil {
+		return
+	}
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	b.errs = append(b.errs, err)
+}
+
+// Close waits until async subscribers handle queued events, stops them
+// and returns their errors and errors of handlers of Post. Synchronous
+// subscribers stay subscribed.
+func (b *Bus) Close() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		subscribers := b.subscribers[:0:0]
+		for _, s := range b.subscribers {
+			if s.queue != nil {
+				close(s.done)
+			} else {
+				subscribers = append(subscribers, s)
+			}
+		}
+		b.subscribers = subscribers
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	return errors.Join(b.errs...)
+}
@@ -0,0 +1,196 @@
+package event
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records handled events.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+	err    error // returned by handle
+}
+
+func (r *recorder) handle(e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func TestBusPublish(t *testing.T) {
+	events := []Event{
+		LotCreated{2},
+		CarParked{1, "KA-01-HH-1234", "White"},
+		CarParked{2, "KA-01-HH-9999", "Red"},
+		LotFull{2},
+		CarLeft{1, "KA-01-HH-1234", "White"},
+		ErrorOccurred{errors.New("car not found")},
+	}
+
+	tests := []struct {
+		kinds []Kind
+		want  []Event
+	}{
+		{nil, events},
+		{[]Kind{KindCarParked, KindCarLeft}, []Event{events[1], events[2], events[4]}},
+		{[]Kind{KindLotFull}, []Event{events[3]}},
+		{[]Kind{"unknown"}, nil},
+	}
+
+	bus := NewBus()
+	var synced, async []*recorder
+	for _, tt := range tests {
+		synced = append(synced, &recorder{})
+		async = append(async, &recorder{})
+		bus.Subscribe(synced[len(synced)-1].handle, tt.kinds...)
+		bus.SubscribeAsync(async[len(async)-1].handle, tt.kinds...)
+	}
+	for _, e := range events {
+		if err := bus.Publish(e); err != nil {
+			t.Fatalf("publish %v error: %s", e, err)
+		}
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatalf("close error: %s", err)
+	}
+
+	for i, tt := range tests {
+		if !reflect.DeepEqual(synced[i].events, tt.want) {
+			t.Errorf("%v invalid events of sync subscriber - want: %v, got: %v", tt.kinds, tt.want, synced[i].events)
+		}
+		if !reflect.DeepEqual(async[i].events, tt.want) {
+			t.Errorf("%v invalid events of async subscriber - want: %v, got: %v", tt.kinds, tt.want, async[i].events)
+		}
+	}
+
+	// synchronous subscribers stay subscribed after Close
+	if err := bus.Publish(LotCreated{3}); err != nil {
+		t.Fatalf("publish error: %s", err)
+	}
+	if got := len(synced[0].events); got != len(events)+1 {
+		t.Errorf("invalid number of events after close - want: %d, got: %d", len(events)+1, got)
+	}
+	if got := len(async[0].events); got != len(events) {
+		t.Errorf("invalid number of async events after close - want: %d, got: %d", len(events), got)
+	}
+}
+
+func TestBusErrors(t *testing.T) {
+	bus := NewBus()
+	first := &recorder{err: errors.New("barrier jammed")}
+	second := &recorder{}
+	async := &recorder{err: errors.New("app unavailable")}
+	bus.Subscribe(first.handle)
+	bus.Subscribe(second.handle)
+	bus.SubscribeAsync(async.handle)
+
+	err := bus.Publish(LotCreated{1})
+	if err == nil || err.Error() != "barrier jammed" {
+		t.Errorf("invalid publish error - want: barrier jammed, got: %v", err)
+	}
+	if len(second.events) != 1 {
+		t.Errorf("subscriber after failed one did not handle event")
+	}
+
+	err = bus.Close()
+	if err == nil || err.Error() != "app unavailable" {
+		t.Errorf("invalid close error - want: app unavailable, got: %v", err)
+	}
+}
+
+func TestBusPost(t *testing.T) {
+	bus := NewBus()
+	failing := &recorder{err: errors.New("barrier jammed")}
+	synced := &recorder{}
+	bus.Subscribe(failing.handle)
+	bus.Subscribe(synced.handle)
+
+	bus.Post(CarParked{Slot: 1})
+	bus.Post(CarLeft{Slot: 1})
+	want := []Event{CarParked{Slot: 1}, CarLeft{Slot: 1}}
+	if !reflect.DeepEqual(synced.events, want) {
+		t.Errorf("invalid events - want: %v, got: %v", want, synced.events)
+	}
+
+	// errors of handlers are returned by Close, not by Post
+	msg := "barrier jammed\nbarrier jammed"
+	if err := bus.Close(); err == nil || err.Error() != msg {
+		t.Errorf("invalid close error - want: %q, got: %v", msg, err)
+	}
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	synced, async := &recorder{}, &recorder{}
+	unsubscribe := bus.Subscribe(synced.handle)
+	unsubscribeAsync := bus.SubscribeAsync(async.handle)
+
+	bus.Publish(LotCreated{1})
+	unsubscribe()
+	unsubscribeAsync()
+	unsubscribeAsync()
+	bus.Publish(LotCreated{2})
+	bus.Close()
+
+	want := []Event{LotCreated{1}}
+	if !reflect.DeepEqual(synced.events, want) {
+		t.Errorf("invalid events of sync subscriber - want: %v, got: %v", want, synced.events)
+	}
+	if !reflect.DeepEqual(async.events, want) {
+		t.Errorf("invalid events of async subscriber - want: %v, got: %v", want, async.events)
+	}
+}
+
+func TestBusUnsubscribeFullQueue(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	unsubscribe := bus.SubscribeAsync(func(e Event) error {
+		<-release
+		return nil
+	})
+
+	// publisher blocks on the full queue, which must not block unsubscribe
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < queueSize+2; i++ {
+			bus.Publish(LotCreated{i})
+		}
+		close(published)
+	}()
+	queue := bus.subscribers[0].queue
+	for len(queue) < queueSize {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		unsubscribe()
+		close(unsubscribed)
+	}()
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Fatalf("unsubscribe blocked by full queue")
+	}
+	close(release)
+	<-published
+	bus.Close()
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	if err := bus.Publish(LotCreated{1}); err != nil {
+		t.Errorf("publish on nil bus error: %s", err)
+	}
+	bus.Post(LotCreated{1})
+	if err := bus.Close(); err != nil {
+		t.Errorf("close of nil bus error: %s", err)
+	}
+}
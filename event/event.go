@@ -0,0 +1,99 @@
+// Package event defines events of parking lot and the bus delivering them
+// to subscribers, e.g. integrations opening a barrier when a car parks.
+//
+// The database publishes changes of the parking lot, the executor
+// publishes failed statements and results of statements:
+//
+//	bus := event.NewBus()
+//	bus.Subscribe(openBarrier, event.KindCarParked)
+//	db.Events = bus
+package event
+
+import (
+	"encoding/json"
+
+	"parking_lot/errors"
+)
+
+// Kind is the kind of event. Kinds are stable names used in filters and
+// payloads.
+type Kind string
+
+// Kinds of events.
+const (
+	KindLotCreated    Kind = "lot_created"
+	KindCarParked     Kind = "car_parked"
+	KindCarLeft       Kind = "car_left"
+	KindLotFull       Kind = "lot_full"
+	KindErrorOccurred Kind = "error_occurred"
+)
+
+// Kinds lists kinds of events defined by this package.
+var Kinds = []Kind{KindLotCreated, KindCarParked, KindCarLeft, KindLotFull, KindErrorOccurred}
+
+// Event is an event published on Bus.
+type Event interface {
+	Kind() Kind
+}
+
+// LotCreated is published when parking lot is created. Parked cars are
+// removed.
+type LotCreated struct {
+	Capacity int `json:"capacity"`
+}
+
+// CarParked is published when a car parks. Slots of events are numbered
+// from 1.
+type CarParked struct {
+	Slot               int    `json:"slot"`
+	RegistrationNumber string `json:"registration"`
+	Colour             string `json:"colour"`
+}
+
+// CarLeft is published when a car leaves.
+type CarLeft struct {
+	Slot               int    `json:"slot"`
+	RegistrationNumber string `json:"registration"`
+	Colour             string `json:"colour"`
+}
+
+// LotFull is published when a car takes the last free slot.
+type LotFull struct {
+	Capacity int `json:"capacity"`
+}
+
+// ErrorOccurred is published when a statement fails.
+type ErrorOccurred struct {
+	Err error
+}
+
+// Kind returns KindLotCreated.
+func (LotCreated) Kind() Kind { return KindLotCreated }
+
+// Kind returns KindCarParked.
+func (CarParked) Kind() Kind { return KindCarParked }
+
+// Kind returns KindCarLeft.
+func (CarLeft) Kind() Kind { return KindCarLeft }
+
+// Kind returns KindLotFull.
+func (LotFull) Kind() Kind { return KindLotFull }
+
+// Kind returns KindErrorOccurred.
+func (ErrorOccurred) Kind() Kind { return KindErrorOccurred }
+
+// Code returns the code of the error, EXECUTION_FAILED if it has none.
+func (e ErrorOccurred) Code() errors.Code {
+	if code := errors.CodeOf(e.Err); code != "" {
+		return code
+	}
+	return errors.ExecutionFailed
+}
+
+// MarshalJSON encodes the error as its message and code.
+func (e ErrorOccurred) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error string      `json:"error"`
+		Code  errors.Code `json:"code"`
+	}{e.Err.Error(), e.Code()})
+}
@@ -0,0 +1,34 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	lerrors "parking_lot/errors"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	tests := []struct {
+		e    Event
+		want string
+	}{
+		{LotCreated{6}, `{"capacity":6}`},
+		{CarParked{1, "KA-01-HH-1234", "White"}, `{"slot":1,"registration":"KA-01-HH-1234","colour":"White"}`},
+		{CarLeft{4, "KA-01-HH-1234", "White"}, `{"slot":4,"registration":"KA-01-HH-1234","colour":"White"}`},
+		{LotFull{6}, `{"capacity":6}`},
+		{ErrorOccurred{lerrors.New(lerrors.LotFull, "sorry, parking lot is full")}, `{"error":"sorry, parking lot is full","code":"LOT_FULL"}`},
+		{ErrorOccurred{errors.New("undefined variable $n")}, `{"error":"undefined variable $n","code":"EXECUTION_FAILED"}`},
+	}
+
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.e)
+		if err != nil {
+			t.Errorf("%s marshal error: %s", tt.e.Kind(), err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("%s invalid JSON - want: %s, got: %s", tt.e.Kind(), tt.want, b)
+		}
+	}
+}
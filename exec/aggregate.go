@@ -0,0 +1,68 @@
+package exec
+
+import (
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+)
+
+func (e *Executor) execCountStatement(db *database.Database, stmt *ast.CountStatement) error {
+	if stmt.By == "" {
+		n, err := db.Count()
+		if err != nil {
+			return err
+		}
+		return e.emit(Count{n})
+	}
+
+	counts, err := db.CountByColor()
+	if err != nil {
+		return err
+	}
+
+	return e.emit(ColourCounts{counts})
+}
+
+func (e *Executor) execOccupancyStatement(db *database.Database) error {
+	o, err := db.Occupancy()
+	if err != nil {
+		return err
+	}
+	return e.emit(Occupancy{o})
+}
+
+func (e *Executor) execFreeSlotsStatement(db *database.Database) error {
+	n, err := db.FreeSlots()
+	if err != nil {
+		return err
+	}
+	return e.emit(FreeSlots{n})
+}
+
+// evalCountExpr evaluates count used as expression. Grouped count is
+// a list of [colour, count] lists.
+func (e *Executor) evalCountExpr(db *database.Database, stmt *ast.CountStatement) (Value, error) {
+	if stmt.By == "" {
+		return db.Count()
+	}
+
+	counts, err := db.CountByColor()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Value, len(counts))
+	for i, c := range counts {
+		list[i] = []Value{c.Color, c.Count}
+	}
+	return list, nil
+}
+
+// evalOccupancyExpr evaluates occupancy used as expression, the value is
+// the occupied part of the parking lot in percents.
+func (e *Executor) evalOccupancyExpr(db *database.Database) (Value, error) {
+	o, err := db.Occupancy()
+	if err != nil {
+		return nil, err
+	}
+	return o.Percent(), nil
+}
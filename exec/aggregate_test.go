@@ -0,0 +1,39 @@
+package exec
+
+import "testing"
+
+func TestExecuteAggregates(t *testing.T) {
+	const lot = "create_parking_lot 4 park KA-01-HH-1234 White park KA-01-HH-9999 Black park KA-01-HH-7777 White "
+
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{"count", "3\n", ""},
+		{
+			"count by colour",
+			"Colour    Count\n" +
+				"Black     1\n" +
+				"White     2\n",
+			"",
+		},
+		{"occupancy", "Occupied 3 of 4 slots (75%)\n", ""},
+		{"free_slots", "1\n", ""},
+		{"free_slots() leave 1 free_slots", "1\nSlot number 1 is free\n2\n", ""},
+		{"if count == 3 and occupancy > 50 { leave 2 }", "Slot number 2 is free\n", ""},
+		{"let c = first(count by colour) if contains($c, Black) { leave 2 }", "Slot number 2 is free\n", ""},
+		{"create_parking_lot 0 occupancy count by colour", "Created a parking lot with 0 slots\nOccupied 0 of 0 slots (0%)\nColour    Count\n", ""},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, lot+tt.src)
+		stdout = stdout[len("Created a parking lot with 4 slots\nAllocated slot number: 1\nAllocated slot number: 2\nAllocated slot number: 3\n"):]
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
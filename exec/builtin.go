@@ -0,0 +1,114 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+)
+
+// builtin is a function available in expressions.
+type builtin struct {
+	nargs int
+	fn    func(db *database.Database, args []Value) (Value, error)
+}
+
+var builtins = map[string]builtin{
+	"free_slots": {0, builtinFreeSlots},
+	"len":        {1, builtinLen},
+	"contains":   {2, builtinContains},
+	"first":      {1, builtinFirst},
+}
+
+// evalCallExpr calls built-in function or procedure. The returned value
+// is nil if procedure does not return a value.
+func (e *Executor) evalCallExpr(db *database.Database, x *ast.CallExpr) (Value, error) {
+	b, proc, err := e.lookupCall(x)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]Value, len(x.Args))
+	for i := range x.Args {
+		v, err := e.eval(db, x.Args[i])
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	if proc == nil {
+		return b.fn(db, args)
+	}
+	return e.callProcedure(db, proc, args)
+}
+
+// lookupCall returns built-in function or procedure (nil for built-in)
+// called by x and checks the number of arguments. Built-in functions
+// cannot be redefined, so they are looked up first.
+func (e *Executor) lookupCall(x *ast.CallExpr) (builtin, *procedure, error) {
+	if b, ok := builtins[x.Name]; ok {
+		if len(x.Args) != b.nargs {
+			return builtin{}, nil, fmt.Errorf("%s expects %d arguments, got %d", x.Name, b.nargs, len(x.Args))
+		}
+		return b, nil, nil
+	}
+
+	proc, ok := e.scope.lookupProc(x.Name)
+	if !ok {
+		return builtin{}, nil, fmt.Errorf("undefined function %s", x.Name)
+	}
+	if len(x.Args) != len(proc.def.Params) {
+		return builtin{}, nil, fmt.Errorf("%s expects %d arguments, got %d", x.Name, len(proc.def.Params), len(x.Args))
+	}
+	return builtin{}, proc, nil
+}
+
+// builtinFreeSlots returns the number of free slots.
+func builtinFreeSlots(db *database.Database, args []Value) (Value, error) {
+	return db.FreeSlots()
+}
+
+// builtinLen returns the length of list or string.
+func builtinLen(db *database.Database, args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case []Value:
+		return len(v), nil
+	case string:
+		return len(v), nil
+	}
+	return nil, fmt.Errorf("invalid argument %s (%s) for len", formatValue(args[0]), typeName(args[0]))
+}
+
+// builtinContains reports whether list contains the value or string
+// contains the substring.
+func builtinContains(db *database.Database, args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case []Value:
+		for i := range v {
+			if equal(v[i], args[1]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		if substr, ok := args[1].(string); ok {
+			return strings.Contains(v, substr), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid arguments %s (%s) and %s (%s) for contains",
+		formatValue(args[0]), typeName(args[0]), formatValue(args[1]), typeName(args[1]))
+}
+
+// builtinFirst returns the first element of list.
+func builtinFirst(db *database.Database, args []Value) (Value, error) {
+	list, ok := args[0].([]Value)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument %s (%s) for first", formatValue(args[0]), typeName(args[0]))
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("first of empty list")
+	}
+	return list[0], nil
+}
@@ -0,0 +1,88 @@
+package exec
+
+import "testing"
+
+func TestBuiltinFreeSlots(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{
+			"create_parking_lot free_slots() + 2",
+			"Created a parking lot with 2 slots\n",
+			"",
+		},
+		{
+			"create_parking_lot 2 park KA-01-HH-1234 White if free_slots() > 0 { park KA-01-HH-9999 White } else { status }",
+			"Created a parking lot with 2 slots\n" +
+				"Allocated slot number: 1\n" +
+				"Allocated slot number: 2\n",
+			"",
+		},
+		{
+			"let n = free_slots(1)",
+			"",
+			"free_slots expects 0 arguments, got 1\n",
+		},
+		{
+			"let n = missing()",
+			"",
+			"undefined function missing\n",
+		},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, tt.src)
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestBuiltinLists(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{
+			"create_parking_lot len(KA-01) + len(slot_numbers_for_cars_with_colour White)",
+			"Created a parking lot with 5 slots\n",
+			"",
+		},
+		{
+			"if contains(KA-01-HH-1234, HH) and not contains(KA-01, HH) { create_parking_lot 1 }",
+			"Created a parking lot with 1 slots\n",
+			"",
+		},
+		{
+			"let n = len(1)",
+			"",
+			"invalid argument 1 (int) for len\n",
+		},
+		{
+			"let n = contains(1, 1)",
+			"",
+			"invalid arguments 1 (int) and 1 (int) for contains\n",
+		},
+		{
+			"let n = first(White)",
+			"",
+			"invalid argument White (string) for first\n",
+		},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, tt.src)
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"fmt"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/command"
+)
+
+// execCommandStatement evaluates arguments of registered command by their
+// kinds and runs its handler.
+func (e *Executor) execCommandStatement(db *database.Database, stmt *ast.CommandStatement) error {
+	cmd := command.Lookup(stmt.Name)
+	if cmd == nil || cmd.Builtin {
+		return fmt.Errorf("unknown command %s", stmt.Name)
+	}
+	if len(stmt.Args) != len(cmd.Args) {
+		return fmt.Errorf("%s expects %d arguments, got %d", cmd.Name, len(cmd.Args), len(stmt.Args))
+	}
+
+	args := make([]interface{}, len(stmt.Args))
+	for i, x := range stmt.Args {
+		var err error
+		if cmd.Args[i].Kind == command.Int {
+			args[i], err = e.evalInt(db, x)
+		} else {
+			args[i], err = e.evalString(db, x)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err := cmd.Handler(db, args)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	value, ok := commandValue(v)
+	if !ok {
+		return fmt.Errorf("command %s returned invalid value %v (%T)", cmd.Name, v, v)
+	}
+	return e.emit(CommandResult{Name: cmd.Name, Value: value})
+}
+
+// commandValue converts result of command handler to a value, lists of
+// handlers are []interface{}.
+func commandValue(v interface{}) (Value, bool) {
+	switch v := v.(type) {
+	case int, string, bool:
+		return v, true
+	case []interface{}:
+		list := make([]Value, len(v))
+		for i := range v {
+			var ok bool
+			if list[i], ok = commandValue(v[i]); !ok {
+				return nil, false
+			}
+		}
+		return list, true
+	}
+	return nil, false
+}
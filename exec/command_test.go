@@ -0,0 +1,102 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/command"
+	"parking_lot/lot/parser"
+)
+
+// registerTestCommands registers commands used by tests and returns
+// function unregistering them.
+func registerTestCommands() func() {
+	command.Register(&command.Command{
+		Name: "park_many",
+		Args: []command.Arg{{Name: "N", Kind: command.Int}, {Name: "COLOUR", Kind: command.String}},
+		Handler: func(lot command.Lot, args []interface{}) (interface{}, error) {
+			db := lot.(*database.Database)
+			var slots []interface{}
+			for i := 0; i < args[0].(int); i++ {
+				n, err := db.Count()
+				if err != nil {
+					return nil, err
+				}
+				car, err := db.NewCar(fmt.Sprintf("KA-01-HH-%d000", n+1), args[1].(string))
+				if err != nil {
+					return nil, err
+				}
+				slot, err := db.Save(car)
+				if err != nil {
+					return nil, err
+				}
+				slots = append(slots, slot+1)
+			}
+			return slots, nil
+		},
+	})
+	command.Register(&command.Command{
+		Name:    "nothing",
+		Handler: func(lot command.Lot, args []interface{}) (interface{}, error) { return nil, nil },
+	})
+	command.Register(&command.Command{
+		Name:    "invalid",
+		Handler: func(lot command.Lot, args []interface{}) (interface{}, error) { return 1.5, nil },
+	})
+	return func() {
+		command.Unregister("park_many")
+		command.Unregister("nothing")
+		command.Unregister("invalid")
+	}
+}
+
+func TestExecuteCommand(t *testing.T) {
+	defer registerTestCommands()()
+
+	src := "create_parking_lot 3 park_many 2 White nothing park_many 1 + 1 Red park_many $n Red invalid status"
+	want := []Result{
+		Created{3},
+		CommandResult{"park_many", []Value{1, 2}},
+		Error{Err: database.ErrFull},
+		Error{Err: errors.New("undefined variable $n")},
+		Error{Err: errors.New("command invalid returned invalid value 1.5 (float64)")},
+		StatusRows{[]StatusRow{{1, "KA-01-HH-1000", "White"}, {2, "KA-01-HH-2000", "White"}, {3, "KA-01-HH-3000", "Red"}}},
+	}
+
+	program, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse %q error: %s", src, err)
+	}
+	for name, execute := range map[string]func(e *Executor, db *database.Database) []Result{
+		"execute": func(e *Executor, db *database.Database) []Result { return e.Execute(program, db) },
+		"run":     func(e *Executor, db *database.Database) []Result { return e.Run(Compile(program, db), db) },
+	} {
+		results := execute(&Executor{}, database.NewDatabase(database.NewMemoryWriter()))
+		if !equalResults(results, want) {
+			t.Errorf("%s invalid results - want: %v, got: %v", name, want, results)
+		}
+		if err := results[2].(Error).Err; !errors.Is(err, lerrors.LotFull) {
+			t.Errorf("%s error %v of handler is not %s", name, err, lerrors.LotFull)
+		}
+	}
+
+	// program parsed with command which is not registered anymore
+	command.Unregister("nothing")
+	results := (&Executor{}).Execute(program, database.NewDatabase(database.NewMemoryWriter()))
+	if want := "unknown command nothing"; !hasError(results, want) {
+		t.Errorf("invalid results - want error: %s, got: %v", want, results)
+	}
+}
+
+// hasError reports whether results has error with message msg.
+func hasError(results []Result, msg string) bool {
+	for _, r := range results {
+		if r, ok := r.(Error); ok && r.Err.Error() == msg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,313 @@
+package exec
+
+import (
+	"fmt"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// opcode is an operation of compiled code. Operands are taken from and
+// results pushed to the value stack.
+type opcode uint8
+
+const (
+	opStmt           opcode = iota // start statement at position arg ending at target, count a step
+	opEnd                          // end statement
+	opConst                        // push constant arg
+	opLoad                         // push variable of identifier arg
+	opStore                        // pop value and set variable named arg
+	opPop                          // pop value
+	opInt                          // check that value of expression arg is int
+	opString                       // check that value of expression arg is string
+	opBool                         // check that value of expression arg is bool
+	opNot                          // negate bool
+	opBinary                       // pop operands and push result of binary expression arg
+	opJump                         // jump to target
+	opJumpIfFalse                  // pop bool and jump to target if it is false
+	opJumpFalseOrPop               // jump to target if bool is false, pop it otherwise
+	opJumpTrueOrPop                // jump to target if bool is true, pop it otherwise
+	opLookupCall                   // check function called by call expression arg
+	opCall                         // pop arguments and push result of call expression arg
+	opResult                       // check that call expression arg returned a value
+	opQuery                        // push value of query expression arg
+	opFail                         // fail with error arg
+	opCreate                       // pop int and create parking lot
+	opPark                         // park car arg
+	opParkValues                   // pop registration number and colour and park car
+	opLeave                        // pop int and leave slot
+	opStatus                       // print status
+	opExec                         // execute statement arg by the tree-walking executor
+	opPushScope                    // enter block scope
+	opPopScope                     // leave block scope
+	opForInit                      // check range of for statement arg, push counter
+	opForNext                      // start next iteration of for statement arg or jump to target
+	opWhileNext                    // count iteration of while statement arg
+	opDef                          // define procedure of def statement arg
+	opReturn                       // return, with popped value if arg is 1
+)
+
+// instr is a single instruction of compiled code. Arg is an index of
+// constant, except for opReturn.
+type instr struct {
+	op     opcode
+	arg    int32
+	target int32 // jump target or end of statement
+}
+
+// Code is a program compiled by Compile and run by Executor.Run.
+type Code struct {
+	instrs []instr
+	consts []interface{}
+
+	// procs are compiled bodies of procedures, shared by the program and
+	// procedures code.
+	procs map[*ast.DefStatement]*Code
+	db    *database.Database
+}
+
+// compiler compiles statements into code.
+type compiler struct {
+	code *Code
+}
+
+// Compile compiles program into code run by Executor.Run. Cars parked
+// with literal registration number and colour are created and validated
+// once, with normalizer and validator of db, so the code must be run on
+// db or on a database configured the same way.
+func Compile(program *ast.Program, db *database.Database) *Code {
+	code := &Code{
+		procs: make(map[*ast.DefStatement]*Code),
+		db:    db,
+	}
+	c := &compiler{code}
+	c.stmts(program.Statements)
+	return code
+}
+
+// proc returns compiled body of procedure def, it is compiled on first use
+// if it was defined by Execute.
+func (code *Code) proc(def *ast.DefStatement) *Code {
+	if body, ok := code.procs[def]; ok {
+		return body
+	}
+	body := &Code{procs: code.procs, db: code.db}
+	c := &compiler{body}
+	c.stmts(def.Body.Statements)
+	code.procs[def] = body
+	return body
+}
+
+// emit appends instruction and returns its index.
+func (c *compiler) emit(op opcode, arg int) int {
+	c.code.instrs = append(c.code.instrs, instr{op: op, arg: int32(arg)})
+	return len(c.code.instrs) - 1
+}
+
+// emitJump appends jump instruction. Its target is set by setTarget if
+// it is not known yet.
+func (c *compiler) emitJump(op opcode, arg, target int) int {
+	i := c.emit(op, arg)
+	c.code.instrs[i].target = int32(target)
+	return i
+}
+
+// setTarget sets target of instruction i to the next instruction.
+func (c *compiler) setTarget(i int) {
+	c.code.instrs[i].target = int32(len(c.code.instrs))
+}
+
+// constant adds constant and returns its index.
+func (c *compiler) constant(v interface{}) int {
+	c.code.consts = append(c.code.consts, v)
+	return len(c.code.consts) - 1
+}
+
+func (c *compiler) stmts(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		c.stmt(stmt)
+	}
+}
+
+// stmt compiles statement. Error of statement is reported and the
+// execution continues with opEnd of the statement, the same way as in
+// the tree-walking executor.
+func (c *compiler) stmt(stmt ast.Statement) {
+	start := c.emit(opStmt, c.constant(stmt.Pos()))
+
+	switch stmt := stmt.(type) {
+	case *ast.CreateParkingLotStatement:
+		c.intExpr(stmt.Number)
+		c.emit(opCreate, 0)
+	case *ast.ParkStatement:
+		c.park(stmt)
+	case *ast.LeaveStatement:
+		c.intExpr(stmt.Number)
+		c.emit(opLeave, 0)
+	case *ast.StatusStatement:
+		c.emit(opStatus, 0)
+	case *ast.LetStatement:
+		c.expr(stmt.Value)
+		c.emit(opStore, c.constant(stmt.Name))
+	case *ast.BlockStatement:
+		c.block(stmt)
+	case *ast.IfStatement:
+		c.expr(stmt.Cond)
+		c.emit(opBool, c.constant(stmt.Cond))
+		jump := c.emit(opJumpIfFalse, 0)
+		c.block(stmt.Body)
+		if stmt.Else != nil {
+			end := c.emit(opJump, 0)
+			c.setTarget(jump)
+			c.stmt(stmt.Else)
+			jump = end
+		}
+		c.setTarget(jump)
+	case *ast.ForStatement:
+		c.intExpr(stmt.From)
+		c.intExpr(stmt.To)
+		k := c.constant(stmt)
+		c.emit(opForInit, k)
+		next := c.emit(opForNext, k)
+		// the body shares new scope of the iteration, which binds only
+		// the loop variable, instead of nesting another one
+		c.stmts(stmt.Body.Statements)
+		c.emitJump(opJump, 0, next)
+		c.setTarget(next)
+	case *ast.WhileStatement:
+		c.emit(opConst, c.constant(0)) // iteration counter
+		cond := len(c.code.instrs)
+		c.expr(stmt.Cond)
+		c.emit(opBool, c.constant(stmt.Cond))
+		jump := c.emit(opJumpIfFalse, 0)
+		c.emit(opWhileNext, c.constant(stmt))
+		c.block(stmt.Body)
+		c.emitJump(opJump, 0, cond)
+		c.setTarget(jump)
+		c.emit(opPop, 0)
+	case *ast.DefStatement:
+		c.code.proc(stmt)
+		c.emit(opDef, c.constant(stmt))
+	case *ast.ReturnStatement:
+		if stmt.Value == nil {
+			c.emit(opReturn, 0)
+		} else {
+			c.expr(stmt.Value)
+			c.emit(opReturn, 1)
+		}
+	case *ast.CallStatement:
+		c.call(stmt.Call)
+		c.emit(opPop, 0)
+	case *ast.IncludeStatement:
+		c.stmts(stmt.Program.Statements)
+	case *ast.RegistrationNumbersForCarsWithColourStatement,
+		*ast.SlotNumbersForCarsWithColourStatement,
+		*ast.SlotNumberForRegistrationNumberStatement,
+		*ast.CarsWithRegistrationMatchingStatement,
+		*ast.FindStatement,
+		*ast.CountStatement,
+		*ast.OccupancyStatement,
+		*ast.FreeSlotsStatement,
+		*ast.CommandStatement:
+		c.emit(opExec, c.constant(stmt))
+	}
+
+	c.setTarget(start)
+	c.emit(opEnd, 0)
+}
+
+// park compiles park statement. Car with literal arguments is created at
+// compile time, so the registration number is not normalized and
+// validated again by every run.
+func (c *compiler) park(stmt *ast.ParkStatement) {
+	registrationNumber, ok1 := stmt.RegistrationNumber.(*ast.StringLiteral)
+	color, ok2 := stmt.Color.(*ast.StringLiteral)
+	if ok1 && ok2 {
+		car, err := c.code.db.NewCar(registrationNumber.Value, color.Value)
+		if err != nil {
+			c.emit(opFail, c.constant(err))
+		} else {
+			c.emit(opPark, c.constant(car))
+		}
+		return
+	}
+
+	c.expr(stmt.RegistrationNumber)
+	c.emit(opString, c.constant(stmt.RegistrationNumber))
+	c.expr(stmt.Color)
+	c.emit(opString, c.constant(stmt.Color))
+	c.emit(opParkValues, 0)
+}
+
+func (c *compiler) block(stmt *ast.BlockStatement) {
+	c.emit(opPushScope, 0)
+	c.stmts(stmt.Statements)
+	c.emit(opPopScope, 0)
+}
+
+// intExpr compiles expression which must result in int.
+func (c *compiler) intExpr(x ast.Expr) {
+	c.expr(x)
+	c.emit(opInt, c.constant(x))
+}
+
+func (c *compiler) expr(x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.IntLiteral:
+		c.emit(opConst, c.constant(x.Value))
+	case *ast.StringLiteral:
+		c.emit(opConst, c.constant(x.Value))
+	case *ast.BoolLiteral:
+		c.emit(opConst, c.constant(x.Value))
+	case *ast.Ident:
+		c.emit(opLoad, c.constant(x))
+	case *ast.ParenExpr:
+		c.expr(x.X)
+	case *ast.UnaryExpr:
+		if x.Op != token.NOT {
+			c.emit(opFail, c.constant(fmt.Errorf("invalid operator %s", x.Op)))
+			return
+		}
+		c.expr(x.X)
+		c.emit(opBool, c.constant(x.X))
+		c.emit(opNot, 0)
+	case *ast.BinaryExpr:
+		if x.Op == token.AND || x.Op == token.OR {
+			c.expr(x.X)
+			c.emit(opBool, c.constant(x.X))
+			op := opJumpFalseOrPop
+			if x.Op == token.OR {
+				op = opJumpTrueOrPop
+			}
+			jump := c.emit(op, 0)
+			c.expr(x.Y)
+			c.emit(opBool, c.constant(x.Y))
+			c.setTarget(jump)
+			return
+		}
+		c.expr(x.X)
+		c.expr(x.Y)
+		c.emit(opBinary, c.constant(x))
+	case *ast.CallExpr:
+		k := c.call(x)
+		c.emit(opResult, k)
+	case *ast.QueryExpr:
+		c.emit(opQuery, c.constant(x))
+	default:
+		c.emit(opFail, c.constant(fmt.Errorf("invalid expression %s", x)))
+	}
+}
+
+// call compiles call and returns the constant index of x. The function
+// is looked up before arguments are evaluated, as by the tree-walking
+// executor.
+func (c *compiler) call(x *ast.CallExpr) int {
+	k := c.constant(x)
+	c.emit(opLookupCall, k)
+	for _, arg := range x.Args {
+		c.expr(arg)
+	}
+	c.emit(opCall, k)
+	return k
+}
@@ -0,0 +1,275 @@
+package exec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// Value is a result of expression evaluation. It holds int, string, bool
+// or list ([]Value).
+type Value interface{}
+
+// typeName returns the name of value type used in error messages.
+func typeName(v Value) string {
+	switch v.(type) {
+	case int:
+		return "int"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []Value:
+		return "list"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// formatValue formats v the way it is printed and concatenated.
+func formatValue(v Value) string {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case []Value:
+		s := make([]string, len(v))
+		for i := range v {
+			s[i] = formatValue(v[i])
+		}
+		return strings.Join(s, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// equal reports whether values are equal. Lists are equal if they have
+// equal elements.
+func equal(a, b Value) bool {
+	la, aok := a.([]Value)
+	lb, bok := b.([]Value)
+	if !aok || !bok {
+		return !aok && !bok && a == b
+	}
+
+	if len(la) != len(lb) {
+		return false
+	}
+	for i := range la {
+		if !equal(la[i], lb[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// eval evaluates expression in the current scope.
+func (e *Executor) eval(db *database.Database, x ast.Expr) (Value, error) {
+	switch x := x.(type) {
+	case *ast.IntLiteral:
+		return x.Value, nil
+	case *ast.StringLiteral:
+		return x.Value, nil
+	case *ast.BoolLiteral:
+		return x.Value, nil
+	case *ast.Ident:
+		v, ok := e.scope.lookup(x.Name)
+		if !ok {
+			return nil, fmt.Errorf("undefined variable %s", x)
+		}
+		return v, nil
+	case *ast.ParenExpr:
+		return e.eval(db, x.X)
+	case *ast.UnaryExpr:
+		return e.evalUnaryExpr(db, x)
+	case *ast.BinaryExpr:
+		return e.evalBinaryExpr(db, x)
+	case *ast.CallExpr:
+		v, err := e.evalCallExpr(db, x)
+		if err == nil && v == nil {
+			return nil, fmt.Errorf("%s does not return a value", x)
+		}
+		return v, err
+	case *ast.QueryExpr:
+		return e.evalQueryExpr(db, x)
+	}
+	return nil, fmt.Errorf("invalid expression %s", x)
+}
+
+func (e *Executor) evalUnaryExpr(db *database.Database, x *ast.UnaryExpr) (Value, error) {
+	if x.Op != token.NOT {
+		return nil, fmt.Errorf("invalid operator %s", x.Op)
+	}
+
+	ok, err := e.evalBool(db, x.X)
+	if err != nil {
+		return nil, err
+	}
+	return !ok, nil
+}
+
+func (e *Executor) evalBinaryExpr(db *database.Database, x *ast.BinaryExpr) (Value, error) {
+	if x.Op == token.AND || x.Op == token.OR {
+		return e.evalLogicalExpr(db, x)
+	}
+
+	l, err := e.eval(db, x.X)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.eval(db, x.Y)
+	if err != nil {
+		return nil, err
+	}
+	return evalBinaryOp(x, l, r)
+}
+
+// evalBinaryOp applies the operator of x, other than and/or, on evaluated
+// operands.
+func evalBinaryOp(x *ast.BinaryExpr, l, r Value) (Value, error) {
+	switch x.Op {
+	case token.EQL:
+		return equal(l, r), nil
+	case token.NEQ:
+		return !equal(l, r), nil
+	}
+
+	switch l := l.(type) {
+	case int:
+		if r, ok := r.(int); ok {
+			return evalIntOp(x, l, r)
+		}
+	case string:
+		if r, ok := r.(string); ok {
+			if v, ok := evalStringOp(x.Op, l, r); ok {
+				return v, nil
+			}
+		}
+	}
+
+	// concatenation of string with int or bool
+	if _, ok := l.(string); ok && x.Op == token.ADD {
+		return formatValue(l) + formatValue(r), nil
+	}
+	if _, ok := r.(string); ok && x.Op == token.ADD {
+		return formatValue(l) + formatValue(r), nil
+	}
+
+	return nil, fmt.Errorf("invalid operation %s %s %s (mismatched types %s and %s)",
+		formatValue(l), x.Op, formatValue(r), typeName(l), typeName(r))
+}
+
+// evalLogicalExpr evaluates and/or with short-circuit.
+func (e *Executor) evalLogicalExpr(db *database.Database, x *ast.BinaryExpr) (Value, error) {
+	l, err := e.evalBool(db, x.X)
+	if err != nil {
+		return nil, err
+	}
+	if x.Op == token.AND && !l || x.Op == token.OR && l {
+		return l, nil
+	}
+	return e.evalBool(db, x.Y)
+}
+
+func evalIntOp(x *ast.BinaryExpr, l, r int) (Value, error) {
+	switch x.Op {
+	case token.ADD:
+		return l + r, nil
+	case token.SUB:
+		return l - r, nil
+	case token.MUL:
+		return l * r, nil
+	case token.QUO, token.REM:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero in %s", x)
+		}
+		if x.Op == token.QUO {
+			return l / r, nil
+		}
+		return l % r, nil
+	case token.LSS:
+		return l < r, nil
+	case token.GTR:
+		return l > r, nil
+	case token.LEQ:
+		return l <= r, nil
+	case token.GEQ:
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("invalid operation %d %s %d (operator %s not defined on int)", l, x.Op, r, x.Op)
+}
+
+func evalStringOp(op token.Token, l, r string) (Value, bool) {
+	switch op {
+	case token.ADD:
+		return l + r, true
+	case token.LSS:
+		return l < r, true
+	case token.GTR:
+		return l > r, true
+	case token.LEQ:
+		return l <= r, true
+	case token.GEQ:
+		return l >= r, true
+	}
+	return nil, false
+}
+
+// evalInt evaluates expression which must result in int.
+func (e *Executor) evalInt(db *database.Database, x ast.Expr) (int, error) {
+	v, err := e.eval(db, x)
+	if err != nil {
+		return 0, err
+	}
+	return expectInt(x, v)
+}
+
+// evalString evaluates expression which must result in string.
+func (e *Executor) evalString(db *database.Database, x ast.Expr) (string, error) {
+	v, err := e.eval(db, x)
+	if err != nil {
+		return "", err
+	}
+	return expectString(x, v)
+}
+
+// evalBool evaluates expression which must result in bool.
+func (e *Executor) evalBool(db *database.Database, x ast.Expr) (bool, error) {
+	v, err := e.eval(db, x)
+	if err != nil {
+		return false, err
+	}
+	return expectBool(x, v)
+}
+
+// expectInt returns value v of expression x which must be int.
+func expectInt(x ast.Expr, v Value) (int, error) {
+	n, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("%s is %s, expecting int", x, typeName(v))
+	}
+	return n, nil
+}
+
+// expectString returns value v of expression x which must be string.
+func expectString(x ast.Expr, v Value) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s is %s, expecting string", x, typeName(v))
+	}
+	return s, nil
+}
+
+// expectBool returns value v of expression x which must be bool.
+func expectBool(x ast.Expr, v Value) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s is %s, expecting bool", x, typeName(v))
+	}
+	return b, nil
+}
@@ -0,0 +1,112 @@
+package exec
+
+import (
+	"bytes"
+	"testing"
+
+	"parking_lot/database"
+	"parking_lot/lot/parser"
+)
+
+func TestExecuteVariables(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{
+			"let n = 2 create_parking_lot $n * 3",
+			"Created a parking lot with 6 slots\n",
+			"",
+		},
+		{
+			`create_parking_lot 1 let reg = "KA-01-HH-" + (1000 + 234) let c = White park $reg $c status`,
+			"Created a parking lot with 1 slots\n" +
+				"Allocated slot number: 1\n" +
+				"Slot No.    Registration No    Colour\n" +
+				"1           KA-01-HH-1234      White\n",
+			"",
+		},
+		{
+			"let n = 7 % 4 let n = $n - 1 create_parking_lot ($n + 1) / 3",
+			"Created a parking lot with 1 slots\n",
+			"",
+		},
+		{
+			"create_parking_lot $missing",
+			"",
+			"undefined variable $missing\n",
+		},
+		{
+			"let n = 1 / 0",
+			"",
+			"division by zero in 1 / 0\n",
+		},
+		{
+			"let n = White - 1",
+			"",
+			"invalid operation White - 1 (mismatched types string and int)\n",
+		},
+		{
+			"let c = White create_parking_lot $c",
+			"",
+			"$c is string, expecting int\n",
+		},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, tt.src)
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestExecuteConditions(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{"let b = 1 < 2 and 2 <= 2 and 3 > 2 and 3 >= 3 and 1 != 2 and 1 == 1 let s = $b + \"\"", "", ""},
+		{"let b = White < Yellow and not White == Yellow create_parking_lot 1", "Created a parking lot with 1 slots\n", ""},
+		{"let b = false and $missing create_parking_lot 1", "Created a parking lot with 1 slots\n", ""},
+		{"let b = true or $missing create_parking_lot 1", "Created a parking lot with 1 slots\n", ""},
+		{"let b = true and 1", "", "1 is int, expecting bool\n"},
+		{"let b = not 1", "", "1 is int, expecting bool\n"},
+		{"let b = true < false", "", "invalid operation true < false (mismatched types bool and bool)\n"},
+		{"let b = 1 + true", "", "invalid operation 1 + true (mismatched types int and bool)\n"},
+		{"let b = slot_numbers_for_cars_with_colour White == slot_numbers_for_cars_with_colour Red create_parking_lot 1",
+			"Created a parking lot with 1 slots\n", ""},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, tt.src)
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestExecuteVariablesOutliveProgram(t *testing.T) {
+	var (
+		stdout = new(bytes.Buffer)
+		e      = Executor{Stdout: stdout, Stderr: stdout}
+		db     = database.NewDatabase(database.NewMemoryWriter())
+	)
+
+	for _, src := range []string{"let n = 3", "create_parking_lot $n"} {
+		program, _ := parser.Parse(src)
+		e.Execute(program, db)
+	}
+
+	if want := "Created a parking lot with 3 slots\n"; stdout.String() != want {
+		t.Errorf("invalid output - want: %q, got: %q", want, stdout.String())
+	}
+}
@@ -0,0 +1,27 @@
+package exec
+
+import (
+	"parking_lot/event"
+)
+
+// KindResult is the kind of ResultEmitted events.
+const KindResult event.Kind = "result"
+
+// ResultEmitted is published by Executor for every result of statement.
+type ResultEmitted struct {
+	Result Result
+}
+
+// Kind returns KindResult.
+func (ResultEmitted) Kind() event.Kind { return KindResult }
+
+// RenderHandler returns handler of ResultEmitted events rendering their
+// results with r, the printing subscriber of the executor events.
+func RenderHandler(r Renderer) event.Handler {
+	return func(e event.Event) error {
+		if e, ok := e.(ResultEmitted); ok {
+			return r.Render(e.Result)
+		}
+		return nil
+	}
+}
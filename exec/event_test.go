@@ -0,0 +1,118 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"parking_lot/database"
+	"parking_lot/event"
+	"parking_lot/lot/parser"
+)
+
+func TestExecuteEvents(t *testing.T) {
+	src := `create_parking_lot 1 park KA-01-HH-1234 White park KA-01-HH-9999 Red leave 1 leave 1`
+	wantEvents := []event.Event{
+		event.LotCreated{Capacity: 1},
+		event.CarParked{Slot: 1, RegistrationNumber: "KA-01-HH-1234", Colour: "White"},
+		event.LotFull{Capacity: 1},
+		event.ErrorOccurred{Err: database.ErrFull},
+		event.CarLeft{Slot: 1, RegistrationNumber: "KA-01-HH-1234", Colour: "White"},
+	}
+	wantOutput := "Created a parking lot with 1 slots\n" +
+		"Allocated slot number: 1\n" +
+		"Sorry, parking lot is full\n" +
+		"Slot number 1 is free\n" +
+		"Slot number 1 is free\n"
+
+	program, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse %q error: %s", src, err)
+	}
+	for name, execute := range map[string]func(e *Executor, db *database.Database) []Result{
+		"execute": func(e *Executor, db *database.Database) []Result { return e.Execute(program, db) },
+		"run":     func(e *Executor, db *database.Database) []Result { return e.Run(Compile(program, db), db) },
+	} {
+		var out bytes.Buffer
+		var events []event.Event
+		bus := event.NewBus()
+		bus.Subscribe(RenderHandler(&TextRenderer{Stdout: &out, Stderr: &out}), KindResult)
+		bus.Subscribe(func(e event.Event) error {
+			events = append(events, e)
+			return nil
+		}, event.Kinds...)
+
+		db := database.NewDatabase(database.NewMemoryWriter())
+		db.Events = bus
+		execute(&Executor{Events: bus}, db)
+
+		if !reflect.DeepEqual(events, wantEvents) {
+			t.Errorf("%s invalid events - want: %v, got: %v", name, wantEvents, events)
+		}
+		if out.String() != wantOutput {
+			t.Errorf("%s invalid output - want: %q, got: %q", name, wantOutput, out.String())
+		}
+	}
+}
+
+func TestExecuteEventsError(t *testing.T) {
+	src := `create_parking_lot 2 park KA-01-HH-1234 White status`
+
+	program, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse %q error: %s", src, err)
+	}
+	bus := event.NewBus()
+	bus.Subscribe(func(event.Event) error { return errors.New("barrier jammed") }, event.KindCarParked)
+	db := database.NewDatabase(database.NewMemoryWriter())
+	db.Events = bus
+
+	// the car is parked, error of the subscriber is returned by Close of
+	// the bus
+	results := (&Executor{Events: bus}).Execute(program, db)
+	want := []Result{
+		Created{2},
+		Allocated{1},
+		StatusRows{[]StatusRow{{1, "KA-01-HH-1234", "White"}}},
+	}
+	if !equalResults(results, want) {
+		t.Errorf("invalid results - want: %v, got: %v", want, results)
+	}
+	if err := bus.Close(); err == nil || err.Error() != "barrier jammed" {
+		t.Errorf("invalid close error - want: barrier jammed, got: %v", err)
+	}
+}
+
+func TestExecuteEventsSubscriberError(t *testing.T) {
+	src := `create_parking_lot 2 park KA-01-HH-1234 White status`
+
+	program, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse %q error: %s", src, err)
+	}
+	for name, execute := range map[string]func(e *Executor, db *database.Database) []Result{
+		"execute": func(e *Executor, db *database.Database) []Result { return e.Execute(program, db) },
+		"run":     func(e *Executor, db *database.Database) []Result { return e.Run(Compile(program, db), db) },
+	} {
+		bus := event.NewBus()
+		bus.Subscribe(func(event.Event) error { return errors.New("printer jammed") }, KindResult)
+		db := database.NewDatabase(database.NewMemoryWriter())
+		db.Events = bus
+
+		// failed subscriber of results does not abort the program
+		results := execute(&Executor{Events: bus}, db)
+		want := []Result{
+			Created{2},
+			Allocated{1},
+			StatusRows{[]StatusRow{{1, "KA-01-HH-1234", "White"}}},
+		}
+		if !equalResults(results, want) {
+			t.Errorf("%s invalid results - want: %v, got: %v", name, want, results)
+		}
+		msg := "printer jammed\nprinter jammed\nprinter jammed"
+		if err := bus.Close(); err == nil || err.Error() != msg {
+			t.Errorf("%s invalid close error - want: %q, got: %v", name, msg, err)
+		}
+	}
+}
@@ -1,21 +1,99 @@
 package exec
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strconv"
-	"strings"
-	"text/tabwriter"
 
 	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/event"
 	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// Limits of a program execution.
+const (
+	// DefaultMaxIterations is the default limit of iterations of a single loop.
+	DefaultMaxIterations = 100000
+
+	// DefaultMaxCallDepth is the default limit of nested procedure calls.
+	DefaultMaxCallDepth = 1000
 )
 
 // Executor handles program execution and output.
 type Executor struct {
 	Stdout io.Writer
 	Stderr io.Writer
+
+	// Renderer renders results of statements as they are executed. Nil
+	// means TextRenderer writing to Stdout and Stderr.
+	Renderer Renderer
+
+	// Events publishes every result as ResultEmitted event and every
+	// error as ErrorOccurred event. Results are rendered by subscribers,
+	// see RenderHandler, so Renderer is not used. Errors of subscribers
+	// do not abort the program, they are returned by Events.Close. Nil
+	// means results are rendered with Renderer.
+	Events *event.Bus
+
+	// MaxIterations limits iterations of a single loop, so runaway scripts
+	// are stopped. Zero means DefaultMaxIterations.
+	MaxIterations int
+
+	// MaxCallDepth limits nested procedure calls, so infinite recursion
+	// is stopped. Zero means DefaultMaxCallDepth.
+	MaxCallDepth int
+
+	// MaxSteps limits statements executed by a single Execute or
+	// ExecuteStatement call, so nested loops and recursion are stopped.
+	// Zero means no limit.
+	MaxSteps int
+
+	// FileSet locates failed statements, see Error.Pos and FileError.
+	// Nil means failed statements are not located.
+	FileSet *token.FileSet
+
+	// scope holds variables and procedures. It outlives single Execute call,
+	// so bindings made in one shell line are visible in the next ones.
+	scope *scope
+
+	// depth is the number of active procedure calls.
+	depth int
+
+	// steps is the number of statements executed by Execute or
+	// ExecuteStatement.
+	steps int
+
+	// results are results of statements executed by Execute or
+	// ExecuteStatement.
+	results []Result
+
+	// file is the file of the first statement executed by Execute or
+	// ExecuteStatement, statements of other files are included. It is
+	// set if started is true.
+	file    string
+	started bool
+}
+
+// fatalError stops the execution of whole program.
+type fatalError struct {
+	msg string
+}
+
+func (e *fatalError) Error() string {
+	return e.msg
+}
+
+// ErrorCode returns LIMIT_EXCEEDED, fatal errors are exceeded limits.
+func (e *fatalError) ErrorCode() lerrors.Code {
+	return lerrors.LimitExceeded
+}
+
+// Is reports whether target is LIMIT_EXCEEDED.
+func (e *fatalError) Is(target error) bool {
+	return target == lerrors.LimitExceeded
 }
 
 // NewExecutor createx new Executor with stdout and stderr set to os.Stdout
@@ -27,134 +105,379 @@ func NewExecutor() *Executor {
 	}
 }
 
-// Execute executes lot program on given database.
-func (e *Executor) Execute(program *ast.Program, db *database.Database) {
-	for _, stmt := range program.Statements {
-		switch stmt := stmt.(type) {
-		case *ast.CreateParkingLotStatement:
-			e.execCreateParkingLotStatement(db, stmt)
-		case *ast.ParkStatement:
-			e.execParkStatement(db, stmt)
-		case *ast.LeaveStatement:
-			e.execLeaveStatement(db, stmt)
-		case *ast.StatusStatement:
-			e.execStatusStatement(db)
-		case *ast.RegistrationNumbersForCarsWithColourStatement:
-			e.execRegistrationNumbersForCarsWithColourStatement(db, stmt)
-		case *ast.SlotNumbersForCarsWithColourStatement:
-			e.execSlotNumbersForCarsWithColourStatement(db, stmt)
-		case *ast.SlotNumberForRegistrationNumberStatement:
-			e.execSlotNumberForRegistrationNumberStatement(db, stmt)
+// Execute executes lot program on given database and returns results of
+// executed statements.
+func (e *Executor) Execute(program *ast.Program, db *database.Database) []Result {
+	e.start()
+	if err := e.execStatements(db, program.Statements); err != nil {
+		e.emit(Error{Err: err})
+	}
+	return e.results
+}
+
+// ExecuteStatement executes a single statement of a program read statement
+// by statement, see parser.Reader, and returns its results. Statements
+// share the scope as in Execute. Errors are Error results, the returned
+// error means that the program must be aborted, e.g. it exceeds a limit.
+func (e *Executor) ExecuteStatement(stmt ast.Statement, db *database.Database) ([]Result, error) {
+	e.start()
+	err := e.execStatement(db, stmt)
+	if err != nil {
+		e.emit(e.stmtError(stmt.Pos(), err))
+	}
+	return e.results, err
+}
+
+// Steps returns the number of statements executed by the last Execute,
+// ExecuteStatement or Run call, including statements of blocks, loop
+// iterations, procedure calls and included files.
+func (e *Executor) Steps() int {
+	return e.steps
+}
+
+// start prepares executor for execution of a program.
+func (e *Executor) start() {
+	if e.scope == nil {
+		e.scope = newScope(nil)
+	}
+	e.steps = 0
+	e.results = nil
+	e.started = false
+}
+
+// enter counts statement at pos as executed. It returns fatal error if the
+// program exceeds the step limit.
+func (e *Executor) enter(pos token.Pos) error {
+	if !e.started && e.FileSet != nil {
+		e.file = e.FileSet.Position(pos).Filename
+		e.started = true
+	}
+	e.steps++
+	if e.MaxSteps > 0 && e.steps > e.MaxSteps {
+		return &fatalError{fmt.Sprintf("program exceeds step limit %d", e.MaxSteps)}
+	}
+	return nil
+}
+
+// stmtError returns Error result of statement at pos failed with err.
+// Errors of statements of included files are FileError.
+func (e *Executor) stmtError(pos token.Pos, err error) Error {
+	if e.FileSet == nil || !pos.IsValid() {
+		return Error{Err: err}
+	}
+
+	position := e.FileSet.Position(pos)
+	var fe *FileError
+	if position.Filename != e.file && !errors.As(err, &fe) {
+		err = &FileError{Pos: position, Err: err}
+	}
+	return Error{Err: err, Pos: position}
+}
+
+// emit records result of a statement and publishes or renders it.
+func (e *Executor) emit(r Result) error {
+	e.results = append(e.results, r)
+	if e.Events != nil {
+		if r, ok := r.(Error); ok {
+			e.Events.Post(event.ErrorOccurred{Err: r.Err})
 		}
+		e.Events.Post(ResultEmitted{r})
+		return nil
 	}
+	if e.Renderer != nil {
+		return e.Renderer.Render(r)
+	}
+	return (&TextRenderer{Stdout: e.Stdout, Stderr: e.Stderr}).Render(r)
+}
+
+// execStatements executes statements in order. Errors of single statements
+// are Error results, the returned error means execution is aborted.
+func (e *Executor) execStatements(db *database.Database, stmts []ast.Statement) error {
+	for _, stmt := range stmts {
+		if err := e.execStatement(db, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execStatement executes single statement and reports its error as result.
+// Only errors which change the control flow (return, fatal errors) are
+// returned.
+func (e *Executor) execStatement(db *database.Database, stmt ast.Statement) error {
+	if err := e.enter(stmt.Pos()); err != nil {
+		return err
+	}
+
+	var err error
+	switch stmt := stmt.(type) {
+	case *ast.CreateParkingLotStatement:
+		err = e.execCreateParkingLotStatement(db, stmt)
+	case *ast.ParkStatement:
+		err = e.execParkStatement(db, stmt)
+	case *ast.LeaveStatement:
+		err = e.execLeaveStatement(db, stmt)
+	case *ast.StatusStatement:
+		err = e.execStatusStatement(db)
+	case *ast.RegistrationNumbersForCarsWithColourStatement:
+		err = e.execRegistrationNumbersForCarsWithColourStatement(db, stmt)
+	case *ast.SlotNumbersForCarsWithColourStatement:
+		err = e.execSlotNumbersForCarsWithColourStatement(db, stmt)
+	case *ast.SlotNumberForRegistrationNumberStatement:
+		err = e.execSlotNumberForRegistrationNumberStatement(db, stmt)
+	case *ast.LetStatement:
+		err = e.execLetStatement(db, stmt)
+	case *ast.BlockStatement:
+		err = e.execBlockStatement(db, stmt)
+	case *ast.IfStatement:
+		err = e.execIfStatement(db, stmt)
+	case *ast.ForStatement:
+		err = e.execForStatement(db, stmt)
+	case *ast.WhileStatement:
+		err = e.execWhileStatement(db, stmt)
+	case *ast.DefStatement:
+		err = e.execDefStatement(stmt)
+	case *ast.CallStatement:
+		_, err = e.evalCallExpr(db, stmt.Call)
+	case *ast.ReturnStatement:
+		err = e.execReturnStatement(db, stmt)
+	case *ast.IncludeStatement:
+		// included statements share the scope of including file, so
+		// procedures defined in libraries are visible after include
+		err = e.execStatements(db, stmt.Program.Statements)
+	case *ast.FindStatement:
+		err = e.execFindStatement(db, stmt)
+	case *ast.CarsWithRegistrationMatchingStatement:
+		err = e.execCarsWithRegistrationMatchingStatement(db, stmt)
+	case *ast.CountStatement:
+		err = e.execCountStatement(db, stmt)
+	case *ast.OccupancyStatement:
+		err = e.execOccupancyStatement(db)
+	case *ast.FreeSlotsStatement:
+		err = e.execFreeSlotsStatement(db)
+	case *ast.CommandStatement:
+		err = e.execCommandStatement(db, stmt)
+	}
+
+	switch err.(type) {
+	case nil:
+		return nil
+	case *fatalError, *returnValue:
+		return err
+	}
+	e.emit(e.stmtError(stmt.Pos(), err))
+	return nil
 }
 
-func (e *Executor) execCreateParkingLotStatement(db *database.Database, stmt *ast.CreateParkingLotStatement) {
-	if err := db.Init(stmt.Number); err != nil {
-		fmt.Fprintln(e.Stderr, err)
-	} else {
-		fmt.Fprintf(e.Stdout, "Created a parking lot with %d slots\n", stmt.Number)
+func (e *Executor) execCreateParkingLotStatement(db *database.Database, stmt *ast.CreateParkingLotStatement) error {
+	n, err := e.evalInt(db, stmt.Number)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Init(n); err != nil {
+		return err
 	}
+	return e.emit(Created{Capacity: n})
 }
 
-func (e *Executor) execParkStatement(db *database.Database, stmt *ast.ParkStatement) {
-	car, err := database.NewCar(stmt.RegistrationNumber, stmt.Color)
+func (e *Executor) execParkStatement(db *database.Database, stmt *ast.ParkStatement) error {
+	registrationNumber, err := e.evalString(db, stmt.RegistrationNumber)
+	if err != nil {
+		return err
+	}
+	color, err := e.evalString(db, stmt.Color)
+	if err != nil {
+		return err
+	}
+
+	car, err := db.NewCar(registrationNumber, color)
 	if err != nil {
-		fmt.Fprintln(e.Stderr, err)
-		return
+		return err
 	}
 
-	if i, err := db.Save(car); err != nil {
-		fmt.Fprintln(e.Stderr, err)
-	} else {
-		fmt.Fprintf(e.Stdout, "Allocated slot number: %d\n", i+1)
+	i, err := db.Save(car)
+	if err != nil {
+		return err
 	}
+	return e.emit(Allocated{Slot: i + 1})
 }
 
-func (e *Executor) execLeaveStatement(db *database.Database, stmt *ast.LeaveStatement) {
-	if err := db.Remove(stmt.Number - 1); err != nil {
-		fmt.Fprintln(e.Stderr, err)
-	} else {
-		fmt.Fprintf(e.Stdout, "Slot number %d is free\n", stmt.Number)
+func (e *Executor) execLeaveStatement(db *database.Database, stmt *ast.LeaveStatement) error {
+	n, err := e.evalInt(db, stmt.Number)
+	if err != nil {
+		return err
 	}
+
+	if err := db.Remove(n - 1); err != nil {
+		return err
+	}
+	return e.emit(Freed{Slot: n})
 }
 
-func (e *Executor) execStatusStatement(db *database.Database) {
+func (e *Executor) execStatusStatement(db *database.Database) error {
 	cars, err := db.GetAll()
 	if err != nil {
-		fmt.Fprintln(e.Stderr, err)
-		return
+		return err
 	}
 
-	w := tabwriter.NewWriter(e.Stdout, 0, 0, 4, ' ', 0)
-	fmt.Fprintf(w, "Slot No.\tRegistration No\tColour\n")
+	var rows []StatusRow
 	for i, car := range cars {
 		if car != nil {
-			fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, car.RegistrationNumber(), car.Color())
+			rows = append(rows, StatusRow{i + 1, car.RegistrationNumber(), car.Color()})
 		}
 	}
-	if err := w.Flush(); err != nil {
-		fmt.Fprintln(e.Stderr, err)
-	}
+	return e.emit(StatusRows{rows})
 }
 
-func (e *Executor) execRegistrationNumbersForCarsWithColourStatement(db *database.Database, stmt *ast.RegistrationNumbersForCarsWithColourStatement) {
-	cars, err := db.FilterCars(database.FilterByColor(stmt.Color))
+func (e *Executor) execRegistrationNumbersForCarsWithColourStatement(db *database.Database, stmt *ast.RegistrationNumbersForCarsWithColourStatement) error {
+	v, err := e.evalQuery(db, stmt)
 	if err != nil {
-		fmt.Fprintln(e.Stderr, err)
-		return
+		return err
 	}
-	if len(cars) == 0 {
-		fmt.Fprintf(e.Stderr, "Not found\n")
-	} else {
-		var s []string
-		for _, car := range cars {
-			s = append(s, car.RegistrationNumber())
-		}
-		fmt.Fprintln(e.Stdout, strings.Join(s, ", "))
+
+	list := v.([]Value)
+	if len(list) == 0 {
+		return e.emit(NotFound{})
+	}
+	s := make([]string, len(list))
+	for i := range list {
+		s[i] = list[i].(string)
 	}
+	return e.emit(RegistrationNumbers{s})
 }
 
-func (e *Executor) execSlotNumbersForCarsWithColourStatement(db *database.Database, stmt *ast.SlotNumbersForCarsWithColourStatement) {
-	slots, err := db.FilterSlotNumbers(database.FilterByColor(stmt.Color))
+func (e *Executor) execSlotNumbersForCarsWithColourStatement(db *database.Database, stmt *ast.SlotNumbersForCarsWithColourStatement) error {
+	v, err := e.evalQuery(db, stmt)
 	if err != nil {
-		fmt.Fprintln(e.Stderr, err)
-		return
+		return err
 	}
 
+	slots := intList(v.([]Value))
 	if len(slots) == 0 {
-		fmt.Fprintf(e.Stderr, "Not found\n")
-	} else {
-		fmt.Fprintln(e.Stdout, intSliceToString(slots))
+		return e.emit(NotFound{})
 	}
+	return e.emit(SlotList{slots})
 }
 
-func (e *Executor) execSlotNumberForRegistrationNumberStatement(db *database.Database, stmt *ast.SlotNumberForRegistrationNumberStatement) {
-	slots, err := db.FilterSlotNumbers(database.FilterByRegistrationNumber(stmt.RegistrationNumber))
+func (e *Executor) execSlotNumberForRegistrationNumberStatement(db *database.Database, stmt *ast.SlotNumberForRegistrationNumberStatement) error {
+	v, err := e.evalQuery(db, stmt)
+	if errors.Is(err, lerrors.NotFound) {
+		return e.emit(NotFound{})
+	}
 	if err != nil {
-		fmt.Fprintln(e.Stderr, err)
-		return
+		return err
+	}
+	return e.emit(SlotList{[]int{v.(int)}})
+}
+
+func (e *Executor) execCarsWithRegistrationMatchingStatement(db *database.Database, stmt *ast.CarsWithRegistrationMatchingStatement) error {
+	v, err := e.evalQuery(db, stmt)
+	if err != nil {
+		return err
 	}
 
+	slots := intList(v.([]Value))
 	if len(slots) == 0 {
-		fmt.Fprintf(e.Stderr, "Not found\n")
-	} else {
-		fmt.Fprintln(e.Stdout, intSliceToString(slots))
+		return e.emit(NotFound{})
+	}
+
+	cars, err := db.GetAll()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]StatusRow, len(slots))
+	for i, slot := range slots {
+		rows[i] = StatusRow{slot, cars[slot-1].RegistrationNumber(), cars[slot-1].Color()}
 	}
+	return e.emit(StatusRows{rows})
 }
 
-// intSliceToString join given int slice into string.
-// It uses ", " as separator.
-// IMPORATANT: it adds +1 to every int to keep program output consistent
-// with specification.
-func intSliceToString(a []int) string {
-	if len(a) == 0 {
-		return ""
+func (e *Executor) execLetStatement(db *database.Database, stmt *ast.LetStatement) error {
+	v, err := e.eval(db, stmt.Value)
+	if err != nil {
+		return err
 	}
+	e.scope.set(stmt.Name, v)
+	return nil
+}
+
+func (e *Executor) execBlockStatement(db *database.Database, stmt *ast.BlockStatement) error {
+	outer := e.scope
+	e.scope = newScope(outer)
+	defer func() { e.scope = outer }()
+
+	return e.execStatements(db, stmt.Statements)
+}
+
+func (e *Executor) execIfStatement(db *database.Database, stmt *ast.IfStatement) error {
+	ok, err := e.evalBool(db, stmt.Cond)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return e.execBlockStatement(db, stmt.Body)
+	}
+	if stmt.Else != nil {
+		return e.execStatement(db, stmt.Else)
+	}
+	return nil
+}
+
+func (e *Executor) execForStatement(db *database.Database, stmt *ast.ForStatement) error {
+	from, err := e.evalInt(db, stmt.From)
+	if err != nil {
+		return err
+	}
+	to, err := e.evalInt(db, stmt.To)
+	if err != nil {
+		return err
+	}
+
+	if n := to - from; to >= from && (n < 0 || n >= e.maxIterations()) {
+		return &fatalError{fmt.Sprintf("loop %s%s%s exceeds iteration limit %d",
+			stmt.From, token.RANGE, stmt.To, e.maxIterations())}
+	}
+
+	outer := e.scope
+	defer func() { e.scope = outer }()
+
+	for n := 0; n <= to-from; n++ {
+		e.scope = newScope(outer)
+		e.scope.vars[stmt.Var] = from + n
+		if err := e.execBlockStatement(db, stmt.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Executor) execWhileStatement(db *database.Database, stmt *ast.WhileStatement) error {
+	for i := 0; ; i++ {
+		ok, err := e.evalBool(db, stmt.Cond)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		if i == e.maxIterations() {
+			return &fatalError{fmt.Sprintf("while %s exceeds iteration limit %d",
+				stmt.Cond, e.maxIterations())}
+		}
+		if err := e.execBlockStatement(db, stmt.Body); err != nil {
+			return err
+		}
+	}
+}
 
-	s := strconv.FormatInt(int64(a[0]+1), 10)
-	for i := 1; i < len(a); i++ {
-		s += ", " + strconv.FormatInt(int64(a[i]+1), 10)
+// maxIterations returns the loop iteration limit.
+func (e *Executor) maxIterations() int {
+	if e.MaxIterations > 0 {
+		return e.MaxIterations
 	}
-	return s
+	return DefaultMaxIterations
 }
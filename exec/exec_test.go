@@ -2,10 +2,15 @@ package exec
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
 	"testing"
 
 	"parking_lot/database"
 	"parking_lot/lot/ast"
+	"parking_lot/lot/parser"
 )
 
 var testPrograms = []struct {
@@ -21,7 +26,7 @@ var testPrograms = []struct {
 		&ast.Program{
 			Statements: []ast.Statement{
 				&ast.CreateParkingLotStatement{
-					Number: 1,
+					Number: &ast.IntLiteral{Value: 1},
 				},
 			},
 		},
@@ -31,11 +36,11 @@ var testPrograms = []struct {
 		&ast.Program{
 			Statements: []ast.Statement{
 				&ast.CreateParkingLotStatement{
-					Number: 1,
+					Number: &ast.IntLiteral{Value: 1},
 				},
 				&ast.ParkStatement{
-					RegistrationNumber: "AA-00-AA-0000",
-					Color:              "White",
+					RegistrationNumber: &ast.StringLiteral{Value: "AA-00-AA-0000"},
+					Color:              &ast.StringLiteral{Value: "White"},
 				},
 			},
 		},
@@ -45,14 +50,14 @@ var testPrograms = []struct {
 		&ast.Program{
 			Statements: []ast.Statement{
 				&ast.CreateParkingLotStatement{
-					Number: 1,
+					Number: &ast.IntLiteral{Value: 1},
 				},
 				&ast.ParkStatement{
-					RegistrationNumber: "AA-00-AA-0000",
-					Color:              "White",
+					RegistrationNumber: &ast.StringLiteral{Value: "AA-00-AA-0000"},
+					Color:              &ast.StringLiteral{Value: "White"},
 				},
 				&ast.LeaveStatement{
-					Number: 1,
+					Number: &ast.IntLiteral{Value: 1},
 				},
 			},
 		},
@@ -144,3 +149,235 @@ func TestExecuteDatabase(t *testing.T) {
 		}
 	}
 }
+
+// executeSource parses and executes src on empty memory database.
+func executeSource(t *testing.T, src string) (stdout, stderr string) {
+	var (
+		outbuf = new(bytes.Buffer)
+		errbuf = new(bytes.Buffer)
+		e      = Executor{Stdout: outbuf, Stderr: errbuf, MaxIterations: 10, MaxCallDepth: 10, MaxSteps: 100}
+		db     = database.NewDatabase(database.NewMemoryWriter())
+	)
+
+	program, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse %q error: %s", src, err)
+	}
+	e.Execute(program, db)
+	return outbuf.String(), errbuf.String()
+}
+
+func TestExecuteControlFlow(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{
+			"create_parking_lot 3 for i in 1..3 { park \"KA-01-HH-100\" + $i White }",
+			"Created a parking lot with 3 slots\n" +
+				"Allocated slot number: 1\n" +
+				"Allocated slot number: 2\n" +
+				"Allocated slot number: 3\n",
+			"",
+		},
+		{
+			"create_parking_lot 1 for i in 2..1 { leave $i }",
+			"Created a parking lot with 1 slots\n",
+			"",
+		},
+		{
+			"create_parking_lot 1 if 1 > 0 { leave 1 } else { leave 2 }",
+			"Created a parking lot with 1 slots\n" +
+				"Slot number 1 is free\n",
+			"",
+		},
+		{
+			"create_parking_lot 2 if 1 > 2 { leave 1 } else if 1 > 1 { leave 2 } else { leave 3 }",
+			"Created a parking lot with 2 slots\n",
+			"slot number 2 out of range [1, 2]\n",
+		},
+		{
+			"create_parking_lot 2 let i = 0 while $i < 2 { let i = $i + 1 leave $i }",
+			"Created a parking lot with 2 slots\n" +
+				"Slot number 1 is free\n" +
+				"Slot number 2 is free\n",
+			"",
+		},
+		{
+			"let i = 0 for j in 1..3 { let i = $i + $j let k = 1 } create_parking_lot $i",
+			"Created a parking lot with 6 slots\n",
+			"",
+		},
+		{
+			"for j in 1..3 { let k = $j } create_parking_lot $k",
+			"",
+			"undefined variable $k\n",
+		},
+		{
+			"if 1 { status } create_parking_lot 1",
+			"Created a parking lot with 1 slots\n",
+			"1 is int, expecting bool\n",
+		},
+		{
+			"for i in 1..11 { status } create_parking_lot 1",
+			"",
+			"loop 1..11 exceeds iteration limit 10\n",
+		},
+		{
+			"while true { let x = 1 } create_parking_lot 1",
+			"",
+			"while true exceeds iteration limit 10\n",
+		},
+		{
+			"for i in 1..2 { while true { } } create_parking_lot 1",
+			"",
+			"while true exceeds iteration limit 10\n",
+		},
+		{
+			"for i in 1..10 { for j in 1..10 { let x = 1 } } create_parking_lot 1",
+			"",
+			"program exceeds step limit 100\n",
+		},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, tt.src)
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestExecuteStatement(t *testing.T) {
+	var (
+		stdout = new(bytes.Buffer)
+		e      = Executor{Stdout: stdout, Stderr: stdout, MaxSteps: 5}
+		db     = database.NewDatabase(database.NewMemoryWriter())
+	)
+
+	program, err := parser.Parse("let n = 2 create_parking_lot $n for i in 1..$n { park \"KA-01-HH-100\" + $i White } leave 3 status")
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+	var errs []string
+	for _, stmt := range program.Statements {
+		if _, err := e.ExecuteStatement(stmt, db); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	want := "Created a parking lot with 2 slots\n" +
+		"Allocated slot number: 1\n" +
+		"Allocated slot number: 2\n" +
+		"slot number 2 out of range [1, 2]\n" +
+		"Slot No.    Registration No    Colour\n" +
+		"1           KA-01-HH-1001      White\n" +
+		"2           KA-01-HH-1002      White\n"
+	if stdout.String() != want {
+		t.Errorf("invalid stdout:\n\twant: %q\n\t got: %q", want, stdout.String())
+	}
+	if len(errs) != 0 {
+		t.Errorf("invalid errors - want: none, got: %q", errs)
+	}
+
+	program, _ = parser.Parse("for i in 1..3 { status status }")
+	if _, err := e.ExecuteStatement(program.Statements[0], db); err == nil {
+		t.Errorf("statement exceeding step limit must return error")
+	}
+}
+
+func TestExecuteNormalization(t *testing.T) {
+	src := "Create_Parking_Lot 2 PARK ka-01-hh-1234 white Park KA-01-HH-9999 BLACK " +
+		"slot_numbers_for_cars_with_colour WHITE Slot_Number_For_Registration_Number Ka-01-Hh-9999 " +
+		"find cars where registration in (ka-01-hh-1234) and colour != black select slot " +
+		"cars_with_registration_matching ka-01 park KA-01-HH-1234 White"
+	stdout, stderr := executeSource(t, src)
+
+	want := "Created a parking lot with 2 slots\n" +
+		"Allocated slot number: 1\n" +
+		"Allocated slot number: 2\n" +
+		"1\n" +
+		"2\n" +
+		"Slot No.\n" +
+		"1\n" +
+		"Slot No.    Registration No    Colour\n" +
+		"1           KA-01-HH-1234      White\n" +
+		"2           KA-01-HH-9999      Black\n"
+	if stdout != want {
+		t.Errorf("invalid stdout:\n\twant: %q\n\t got: %q", want, stdout)
+	}
+	if want := "identity thieves are not welcome, calling police\n"; stderr != want {
+		t.Errorf("invalid stderr:\n\twant: %q\n\t got: %q", want, stderr)
+	}
+}
+
+// limitWriter is a memory writer refusing large parking lots, so fuzzed
+// programs do not run out of memory.
+type limitWriter struct {
+	*database.MemoryWriter
+}
+
+func (w limitWriter) Init(capacity int) error {
+	if capacity > 1000 {
+		return fmt.Errorf("capacity %d over fuzzing limit", capacity)
+	}
+	return w.MemoryWriter.Init(capacity)
+}
+
+func FuzzExecute(f *testing.F) {
+	for _, src := range []string{
+		"create_parking_lot 6\npark KA-01-HH-1234 White\npark KA-01-HH-9999 White\nleave 1\nstatus",
+		"create_parking_lot 2 park A White park B White leave 1 park B White",
+		"create_parking_lot 3 for i in 1..3 { park \"KA-01-HH-100\" + $i White } leave 2 park KA-01-HH-1003 Black",
+		"create_parking_lot 2 let i = 0 while $i < 2 { let i = $i + 1 leave $i }",
+		"proc fill(n) { for i in 1..$n { park \"R-\" + $i Red } } create_parking_lot 4 fill(5) leave 3 fill(4)",
+		"create_parking_lot 3 park A White park B Red for slot in find cars where colour == White select slot { leave $slot }",
+	} {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		if strings.Contains(strings.ToLower(src), "include") {
+			t.Skip("include reads files")
+		}
+		program, err := parser.Parse(src)
+		if err != nil {
+			return
+		}
+
+		var (
+			e  = Executor{Stdout: io.Discard, Stderr: io.Discard, MaxIterations: 10, MaxCallDepth: 5, MaxSteps: 1000}
+			db = database.NewDatabase(limitWriter{database.NewMemoryWriter()})
+		)
+		e.Execute(program, db)
+
+		cars, err := db.GetAll()
+		if err != nil {
+			t.Fatalf("get all error: %s", err)
+		}
+		var slots []int
+		seen := make(map[string]int)
+		for i, car := range cars {
+			if car == nil {
+				continue
+			}
+			if j, ok := seen[car.RegistrationNumber()]; ok {
+				t.Errorf("%q duplicate registration number %s - slots: %d, %d", src, car.RegistrationNumber(), j+1, i+1)
+			}
+			seen[car.RegistrationNumber()] = i
+			slots = append(slots, i)
+		}
+
+		indexed, err := db.SlotNumbersByRegistrationPattern("*")
+		if err != nil {
+			t.Fatalf("search error: %s", err)
+		}
+		if !slices.Equal(slots, indexed) {
+			t.Errorf("%q invalid registration index - want: %v, got: %v", src, slots, indexed)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package exec
+
+import (
+	"fmt"
+	"sort"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// findRow is a single car found by find statement.
+type findRow struct {
+	slot int // 1-based slot number
+	car  *database.Car
+}
+
+// field returns value of the named field.
+func (r findRow) field(name string) Value {
+	switch name {
+	case ast.FieldRegistration:
+		return r.car.RegistrationNumber()
+	case ast.FieldColour:
+		return r.car.Color()
+	}
+	return r.slot
+}
+
+// findHeaders are column headers of find output, same as status ones.
+var findHeaders = map[string]string{
+	ast.FieldSlot:         "Slot No.",
+	ast.FieldRegistration: "Registration No",
+	ast.FieldColour:       "Colour",
+}
+
+// findFields returns fields selected by stmt.
+func findFields(stmt *ast.FindStatement) []string {
+	if len(stmt.Select) > 0 {
+		return stmt.Select
+	}
+	return []string{ast.FieldSlot, ast.FieldRegistration, ast.FieldColour}
+}
+
+func (e *Executor) execFindStatement(db *database.Database, stmt *ast.FindStatement) error {
+	rows, err := e.find(db, stmt)
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return e.emit(NotFound{})
+	}
+
+	fields := findFields(stmt)
+	return e.emit(FoundRows{Fields: fields, Rows: findValues(rows, fields)})
+}
+
+// evalFindExpr evaluates find statement used as expression. A single
+// selected field gives list of its values, otherwise every car is a list
+// of selected fields.
+func (e *Executor) evalFindExpr(db *database.Database, stmt *ast.FindStatement) (Value, error) {
+	rows, err := e.find(db, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := findFields(stmt)
+	list := make([]Value, len(rows))
+	for i, values := range findValues(rows, fields) {
+		if len(fields) == 1 {
+			list[i] = values[0]
+		} else {
+			list[i] = values
+		}
+	}
+	return list, nil
+}
+
+// findValues returns values of fields of found cars.
+func findValues(rows []findRow, fields []string) [][]Value {
+	values := make([][]Value, len(rows))
+	for i, row := range rows {
+		values[i] = make([]Value, len(fields))
+		for j, field := range fields {
+			values[i][j] = row.field(field)
+		}
+	}
+	return values
+}
+
+// find returns cars matched by stmt in requested order.
+func (e *Executor) find(db *database.Database, stmt *ast.FindStatement) ([]findRow, error) {
+	var filter database.Filter
+	if stmt.Where != nil {
+		var err error
+		if filter, err = e.compilePredicate(db, stmt.Where); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := -1
+	if stmt.Limit != nil {
+		n, err := e.evalInt(db, stmt.Limit)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("invalid limit %d", n)
+		}
+		limit = n
+	}
+
+	cars, err := db.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []findRow
+	for i, car := range cars {
+		if car != nil && (filter == nil || filter(car)) {
+			rows = append(rows, findRow{slot: i + 1, car: car})
+		}
+	}
+
+	if stmt.OrderBy != "" {
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i].field(stmt.OrderBy), rows[j].field(stmt.OrderBy)
+			if stmt.Desc {
+				a, b = b, a
+			}
+			if a, ok := a.(int); ok {
+				return a < b.(int)
+			}
+			return a.(string) < b.(string)
+		})
+	}
+
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// compilePredicate compiles predicate into database filter. Values of
+// predicates are evaluated once.
+func (e *Executor) compilePredicate(db *database.Database, pred ast.Predicate) (database.Filter, error) {
+	switch pred := pred.(type) {
+	case *ast.FieldPredicate:
+		values := make([]string, len(pred.Values))
+		for i := range pred.Values {
+			v, err := e.evalString(db, pred.Values[i])
+			if err != nil {
+				return nil, err
+			}
+			if pred.Field == ast.FieldColour {
+				values[i] = db.NormalizeColor(v)
+			} else {
+				values[i] = db.NormalizeRegistrationNumber(v)
+			}
+		}
+
+		switch {
+		case pred.Op == token.TILDE && pred.Field == ast.FieldRegistration:
+			f, err := database.FilterByRegistrationPattern(values[0])
+			if err != nil {
+				return nil, lerrors.Errorf(lerrors.InvalidReg, "invalid pattern %q: %w", values[0], err)
+			}
+			return f, nil
+		case pred.Op == token.TILDE:
+			return nil, fmt.Errorf("%s does not support %s", pred.Field, pred.Op)
+		case pred.Field == ast.FieldRegistration:
+			filters := make([]database.Filter, len(values))
+			for i, v := range values {
+				filters[i] = database.FilterByRegistrationNumber(v)
+			}
+			f := database.Or(filters...)
+			if pred.Op == token.NEQ {
+				return database.Not(f), nil
+			}
+			return f, nil
+		default:
+			f := database.FilterByColors(values...)
+			if pred.Op == token.NEQ {
+				return database.Not(f), nil
+			}
+			return f, nil
+		}
+	case *ast.BinaryPredicate:
+		x, err := e.compilePredicate(db, pred.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := e.compilePredicate(db, pred.Y)
+		if err != nil {
+			return nil, err
+		}
+		if pred.Op == token.AND {
+			return database.And(x, y), nil
+		}
+		return database.Or(x, y), nil
+	case *ast.NotPredicate:
+		x, err := e.compilePredicate(db, pred.X)
+		if err != nil {
+			return nil, err
+		}
+		return database.Not(x), nil
+	case *ast.ParenPredicate:
+		return e.compilePredicate(db, pred.X)
+	}
+	return nil, fmt.Errorf("invalid predicate %s", pred)
+}
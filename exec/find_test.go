@@ -0,0 +1,112 @@
+package exec
+
+import "testing"
+
+const findTestLot = "create_parking_lot 5 park KA-01-HH-1234 White park KA-02-HH-9999 Black " +
+	"park KA-01-HH-7777 Red park KA-01-BB-0001 White "
+
+func TestExecuteFind(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{
+			"find cars",
+			"Slot No.    Registration No    Colour\n" +
+				"1           KA-01-HH-1234      White\n" +
+				"2           KA-02-HH-9999      Black\n" +
+				"3           KA-01-HH-7777      Red\n" +
+				"4           KA-01-BB-0001      White\n",
+			"",
+		},
+		{
+			`find cars where colour in (White, Red) and registration ~ "KA-01-HH-*" select slot, registration order by slot limit 10`,
+			"Slot No.    Registration No\n" +
+				"1           KA-01-HH-1234\n" +
+				"3           KA-01-HH-7777\n",
+			"",
+		},
+		{
+			"find cars where not colour == White select registration order by registration desc limit 1",
+			"Registration No\n" +
+				"KA-02-HH-9999\n",
+			"",
+		},
+		{
+			"find cars where colour != White and (registration == KA-02-HH-9999 or colour == Red) select colour, slot order by colour",
+			"Colour    Slot No.\n" +
+				"Black     2\n" +
+				"Red       3\n",
+			"",
+		},
+		{
+			"find cars where colour == Blue",
+			"",
+			"Not found\n",
+		},
+		{
+			"find cars limit 0",
+			"",
+			"Not found\n",
+		},
+		{
+			"find cars limit 0 - 1",
+			"",
+			"invalid limit -1\n",
+		},
+		{
+			"find cars where colour ~ White",
+			"",
+			"colour does not support ~\n",
+		},
+		{
+			// region prefix, as of cars_with_registration_matching
+			"find cars where registration ~ ka-01 select slot",
+			"Slot No.\n" +
+				"1\n" +
+				"3\n" +
+				"4\n",
+			"",
+		},
+		{
+			"find cars where registration ~ \"KA-[\"",
+			"",
+			"invalid pattern \"KA-[\": syntax error in pattern\n",
+		},
+		{
+			"let c = Red find cars where colour in ($c, White) select slot order by slot desc limit 2",
+			"Slot No.\n" +
+				"4\n" +
+				"3\n",
+			"",
+		},
+		{
+			"let slots = find cars where colour == White select slot leave first($slots)",
+			"Slot number 1 is free\n",
+			"",
+		},
+		{
+			"let cars = find cars where colour == Red if contains(first($cars), KA-01-HH-7777) { leave len($cars) }",
+			"Slot number 1 is free\n",
+			"",
+		},
+		{
+			"if len(find cars where registration ~ KA-01-*) == 3 { leave 2 }",
+			"Slot number 2 is free\n",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, findTestLot+tt.src)
+		stdout = stdout[len("Created a parking lot with 5 slots\nAllocated slot number: 1\nAllocated slot number: 2\n"+
+			"Allocated slot number: 3\nAllocated slot number: 4\n"):]
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package exec
+
+import (
+	"fmt"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// procedure is a procedure defined with def. It keeps the scope it was
+// defined in, so its body sees variables and procedures visible at the
+// point of definition (including itself), and the file set of its
+// definition, which may differ from the file set of the statement calling
+// it.
+type procedure struct {
+	def   *ast.DefStatement
+	scope *scope
+	fset  *token.FileSet
+}
+
+// returnValue carries the value of return statement up to the procedure call.
+type returnValue struct {
+	value Value
+}
+
+func (r *returnValue) Error() string {
+	return "return outside procedure"
+}
+
+func (e *Executor) execDefStatement(stmt *ast.DefStatement) error {
+	if _, ok := builtins[stmt.Name]; ok {
+		return fmt.Errorf("cannot redefine built-in function %s", stmt.Name)
+	}
+	e.scope.procs[stmt.Name] = &procedure{def: stmt, scope: e.scope, fset: e.FileSet}
+	return nil
+}
+
+func (e *Executor) execReturnStatement(db *database.Database, stmt *ast.ReturnStatement) error {
+	if stmt.Value == nil {
+		return &returnValue{}
+	}
+
+	v, err := e.eval(db, stmt.Value)
+	if err != nil {
+		return err
+	}
+	return &returnValue{v}
+}
+
+// callProcedure executes procedure body in a new scope with bound
+// parameters and returns the value of return statement (nil if none).
+func (e *Executor) callProcedure(db *database.Database, proc *procedure, args []Value) (Value, error) {
+	if e.depth == e.maxCallDepth() {
+		return nil, &fatalError{fmt.Sprintf("procedure %s exceeds call depth limit %d", proc.def.Name, e.maxCallDepth())}
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+
+	outer, fset := e.scope, e.FileSet
+	e.scope, e.FileSet = newScope(proc.scope), proc.fset
+	defer func() { e.scope, e.FileSet = outer, fset }()
+
+	for i, name := range proc.def.Params {
+		e.scope.vars[name] = args[i]
+	}
+
+	err := e.execStatements(db, proc.def.Body.Statements)
+	if r, ok := err.(*returnValue); ok {
+		return r.value, nil
+	}
+	return nil, err
+}
+
+// maxCallDepth returns the limit of nested procedure calls.
+func (e *Executor) maxCallDepth() int {
+	if e.MaxCallDepth > 0 {
+		return e.MaxCallDepth
+	}
+	return DefaultMaxCallDepth
+}
@@ -0,0 +1,226 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/parser"
+	"parking_lot/lot/token"
+)
+
+func TestExecuteProcedures(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{
+			"def fill(n, color) { for i in 1..$n { park \"KA-01-HH-100\" + $i $color } } " +
+				"create_parking_lot 2 fill(2, White)",
+			"Created a parking lot with 2 slots\n" +
+				"Allocated slot number: 1\n" +
+				"Allocated slot number: 2\n",
+			"",
+		},
+		{
+			"def double(n) { return $n * 2 } create_parking_lot double(double(1))",
+			"Created a parking lot with 4 slots\n",
+			"",
+		},
+		{
+			"def fact(n) { if $n <= 1 { return 1 } return $n * fact($n - 1) } create_parking_lot fact(3)",
+			"Created a parking lot with 6 slots\n",
+			"",
+		},
+		{
+			// procedure sees variables of the scope it was defined in
+			"let base = 2 def add(n) { return $base + $n } let base = 3 create_parking_lot add(1)",
+			"Created a parking lot with 4 slots\n",
+			"",
+		},
+		{
+			// parameters are not visible outside
+			"def f(n) { return $n } let x = f(1) create_parking_lot $n",
+			"",
+			"undefined variable $n\n",
+		},
+		{
+			// procedure defined in block is not visible outside
+			"if true { def f() { return 1 } } create_parking_lot f()",
+			"",
+			"undefined function f\n",
+		},
+		{
+			"def f() { let x = 1 } create_parking_lot f()",
+			"",
+			"f() does not return a value\n",
+		},
+		{
+			"def f(a) { status } f()",
+			"",
+			"f expects 1 arguments, got 0\n",
+		},
+		{
+			"def free_slots() { status }",
+			"",
+			"cannot redefine built-in function free_slots\n",
+		},
+		{
+			"def f() { return 1 create_parking_lot 1 } f() create_parking_lot 2",
+			"Created a parking lot with 2 slots\n",
+			"",
+		},
+		{
+			"def f() { for i in 1..2 { return $i } } create_parking_lot f()",
+			"Created a parking lot with 1 slots\n",
+			"",
+		},
+		{
+			"def f(n) { return f($n + 1) } let n = f(1) create_parking_lot 1",
+			"",
+			"procedure f exceeds call depth limit 10\n",
+		},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, tt.src)
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestExecuteInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib := "let colour = White def fill(n) { for i in 1..$n { park \"KA-01-HH-100\" + $i $colour } }"
+	if err := ioutil.WriteFile(filepath.Join(dir, "lib.lot"), []byte(lib), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	main := filepath.Join(dir, "main.lot")
+	program, err := parser.ParseFile(token.NewFileSet(), main, []byte(`include "lib.lot" create_parking_lot 1 fill(1)`))
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	var (
+		stdout = new(bytes.Buffer)
+		e      = Executor{Stdout: stdout, Stderr: stdout}
+	)
+	e.Execute(program, database.NewDatabase(database.NewMemoryWriter()))
+
+	want := "Created a parking lot with 1 slots\nAllocated slot number: 1\n"
+	if stdout.String() != want {
+		t.Errorf("invalid output - want: %q, got: %q", want, stdout.String())
+	}
+}
+
+func TestExecuteErrorPositions(t *testing.T) {
+	dir := t.TempDir()
+	lib := "def fill(n) {\n  for i in 1..$n {\n    park \"KA-01-HH-100\" + $i White\n  }\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "lib.lot"), []byte(lib), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "main.lot")
+	src := "include \"lib.lot\"\ncreate_parking_lot 1\nfill(2)\nfor i in 1..1 {\n  leave $slot\n}\n"
+
+	libPos := token.Position{Filename: filepath.Join(dir, "lib.lot"), Offset: 37, Line: 3, Column: 5}
+	mainPos := token.Position{Filename: main, Offset: 65, Line: 5, Column: 3}
+	want := []Result{
+		Created{1},
+		Allocated{1},
+		Error{Err: &FileError{libPos, database.ErrFull}, Pos: libPos},
+		Error{Err: errors.New("undefined variable $slot"), Pos: mainPos},
+	}
+	wantStdout := "Created a parking lot with 1 slots\n" +
+		"Allocated slot number: 1\n" +
+		libPos.String() + ": Sorry, parking lot is full\n" +
+		"undefined variable $slot\n"
+
+	fset := token.NewFileSet()
+	program, err := parser.ParseFile(fset, main, []byte(src))
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+	for name, execute := range map[string]func(e *Executor, db *database.Database) []Result{
+		"execute": func(e *Executor, db *database.Database) []Result { return e.Execute(program, db) },
+		"run":     func(e *Executor, db *database.Database) []Result { return e.Run(Compile(program, db), db) },
+	} {
+		stdout := new(bytes.Buffer)
+		e := &Executor{Stdout: stdout, Stderr: stdout, FileSet: fset}
+		results := execute(e, database.NewDatabase(database.NewMemoryWriter()))
+
+		if !equalResults(results, want) {
+			t.Errorf("%s invalid results - want: %v, got: %v", name, want, results)
+			continue
+		}
+		for i := 2; i < len(want); i++ {
+			if got := results[i].(Error).Pos; got != want[i].(Error).Pos {
+				t.Errorf("%s invalid position of %s - want: %s, got: %s", name, results[i].(Error).Err, want[i].(Error).Pos, got)
+			}
+		}
+		if !errors.Is(results[2].(Error).Err, lerrors.LotFull) {
+			t.Errorf("%s error of included file is not %s", name, lerrors.LotFull)
+		}
+		if stdout.String() != wantStdout {
+			t.Errorf("%s invalid stdout - want: %q, got: %q", name, wantStdout, stdout.String())
+		}
+	}
+}
+
+func TestExecuteErrorPositionsOfEarlierStatements(t *testing.T) {
+	dir := t.TempDir()
+	lib := "def fill(n) {\n  for i in 1..$n {\n    park \"KA-01-HH-100\" + $i White\n  }\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "lib.lot"), []byte(lib), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "main.lot")
+	libPos := token.Position{Filename: filepath.Join(dir, "lib.lot"), Offset: 37, Line: 3, Column: 5}
+
+	for name, execute := range map[string]func(e *Executor, program *ast.Program, db *database.Database) []Result{
+		"execute": func(e *Executor, program *ast.Program, db *database.Database) []Result { return e.Execute(program, db) },
+		"run": func(e *Executor, program *ast.Program, db *database.Database) []Result {
+			return e.Run(Compile(program, db), db)
+		},
+	} {
+		e := &Executor{Stdout: ioutil.Discard, Stderr: ioutil.Discard}
+		db := database.NewDatabase(database.NewMemoryWriter())
+
+		// every statement is parsed to its own file set, like statements
+		// read by parser.Reader
+		var results []Result
+		for _, src := range []string{"include \"lib.lot\"\ncreate_parking_lot 1\n", "\n\nfill(2)\n"} {
+			fset := token.NewFileSet()
+			program, err := parser.ParseFile(fset, main, []byte(src))
+			if err != nil {
+				t.Fatalf("parse error: %s", err)
+			}
+			e.FileSet = fset
+			results = execute(e, program, db)
+		}
+
+		want := []Result{Allocated{1}, Error{Err: &FileError{libPos, database.ErrFull}, Pos: libPos}}
+		if !equalResults(results, want) {
+			t.Errorf("%s invalid results - want: %v, got: %v", name, want, results)
+			continue
+		}
+		if got := results[1].(Error).Pos; got != libPos {
+			t.Errorf("%s invalid position - want: %s, got: %s", name, libPos, got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package exec
+
+import (
+	"fmt"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/ast"
+)
+
+// evalQueryExpr evaluates query used as expression, see evalQuery.
+func (e *Executor) evalQueryExpr(db *database.Database, x *ast.QueryExpr) (Value, error) {
+	return e.evalQuery(db, x.Query)
+}
+
+// evalQuery evaluates query, the statements of queries emit its value.
+// Registration numbers are returned as list of strings, slot numbers (also
+// of cars matching registration pattern) as list of ints and single slot
+// as int.
+func (e *Executor) evalQuery(db *database.Database, query ast.Statement) (Value, error) {
+	switch q := query.(type) {
+	case *ast.RegistrationNumbersForCarsWithColourStatement:
+		color, err := e.evalString(db, q.Color)
+		if err != nil {
+			return nil, err
+		}
+
+		cars, err := db.FilterCars(database.FilterByColor(db.NormalizeColor(color)))
+		if err != nil {
+			return nil, err
+		}
+
+		list := make([]Value, len(cars))
+		for i, car := range cars {
+			list[i] = car.RegistrationNumber()
+		}
+		return list, nil
+	case *ast.SlotNumbersForCarsWithColourStatement:
+		color, err := e.evalString(db, q.Color)
+		if err != nil {
+			return nil, err
+		}
+
+		slots, err := db.FilterSlotNumbers(database.FilterByColor(db.NormalizeColor(color)))
+		if err != nil {
+			return nil, err
+		}
+		return slotList(slots), nil
+	case *ast.SlotNumberForRegistrationNumberStatement:
+		registrationNumber, err := e.evalString(db, q.RegistrationNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		slots, err := db.FilterSlotNumbers(database.FilterByRegistrationNumber(db.NormalizeRegistrationNumber(registrationNumber)))
+		if err != nil {
+			return nil, err
+		}
+		if len(slots) == 0 {
+			return nil, lerrors.Errorf(lerrors.NotFound, "car %s not found", registrationNumber)
+		}
+		return slots[0] + 1, nil
+	case *ast.CarsWithRegistrationMatchingStatement:
+		pattern, err := e.evalString(db, q.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		slots, err := db.SlotNumbersByRegistrationPattern(db.NormalizeRegistrationNumber(pattern))
+		if err != nil {
+			return nil, lerrors.Errorf(lerrors.InvalidReg, "invalid pattern %q: %w", pattern, err)
+		}
+		return slotList(slots), nil
+	case *ast.FindStatement:
+		return e.evalFindExpr(db, q)
+	case *ast.CountStatement:
+		return e.evalCountExpr(db, q)
+	case *ast.OccupancyStatement:
+		return e.evalOccupancyExpr(db)
+	}
+	return nil, fmt.Errorf("invalid query %s", query)
+}
+
+// slotList converts database positions to list of slot numbers.
+func slotList(slots []int) []Value {
+	list := make([]Value, len(slots))
+	for i := range slots {
+		list[i] = slots[i] + 1
+	}
+	return list
+}
+
+// intList converts list of slot numbers to ints.
+func intList(list []Value) []int {
+	slots := make([]int, len(list))
+	for i := range list {
+		slots[i] = list[i].(int)
+	}
+	return slots
+}
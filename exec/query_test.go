@@ -0,0 +1,123 @@
+package exec
+
+import (
+	"bytes"
+	"testing"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/parser"
+)
+
+const queryTestLot = "create_parking_lot 4 park KA-01-HH-1234 White park KA-01-HH-9999 Black park KA-01-HH-7777 White "
+
+func TestEvalQueryExpr(t *testing.T) {
+	tests := []struct {
+		src    string
+		stdout string
+		stderr string
+	}{
+		{
+			"let slots = slot_numbers_for_cars_with_colour White leave first($slots)",
+			"Slot number 1 is free\n",
+			"",
+		},
+		{
+			"leave slot_number_for_registration_number KA-01-HH-9999 + 1",
+			"Slot number 3 is free\n",
+			"",
+		},
+		{
+			"if slot_number_for_registration_number KA-01-HH-9999 == 2 { leave 2 }",
+			"Slot number 2 is free\n",
+			"",
+		},
+		{
+			"let regs = registration_numbers_for_cars_with_colour White " +
+				"if len($regs) == 2 and contains($regs, KA-01-HH-7777) { leave 3 }",
+			"Slot number 3 is free\n",
+			"",
+		},
+		{
+			"let regs = registration_numbers_for_cars_with_colour Red create_parking_lot len($regs)",
+			"Created a parking lot with 0 slots\n",
+			"",
+		},
+		{
+			"let n = slot_number_for_registration_number KA-01-HH-0000",
+			"",
+			"car KA-01-HH-0000 not found\n",
+		},
+		{
+			"let n = first(slot_numbers_for_cars_with_colour Red)",
+			"",
+			"first of empty list\n",
+		},
+		{
+			"cars_with_registration_matching \"KA-01-HH-?7??\" cars_with_registration_matching KA-01",
+			"Slot No.    Registration No    Colour\n" +
+				"3           KA-01-HH-7777      White\n" +
+				"Slot No.    Registration No    Colour\n" +
+				"1           KA-01-HH-1234      White\n" +
+				"2           KA-01-HH-9999      Black\n" +
+				"3           KA-01-HH-7777      White\n",
+			"",
+		},
+		{
+			"cars_with_registration_matching KA-01-H cars_with_registration_matching \"KA-[\"",
+			"",
+			"Not found\ninvalid pattern \"KA-[\": syntax error in pattern\n",
+		},
+		{
+			"leave first(cars_with_registration_matching \"*-9999\")",
+			"Slot number 2 is free\n",
+			"",
+		},
+		{
+			"park \"KA-01-HH-\" + len(slot_numbers_for_cars_with_colour White) + 100 Red status",
+			"Allocated slot number: 4\n" +
+				"Slot No.    Registration No    Colour\n" +
+				"1           KA-01-HH-1234      White\n" +
+				"2           KA-01-HH-9999      Black\n" +
+				"3           KA-01-HH-7777      White\n" +
+				"4           KA-01-HH-2100      Red\n",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := executeSource(t, queryTestLot+tt.src)
+		stdout = stdout[len("Created a parking lot with 4 slots\nAllocated slot number: 1\nAllocated slot number: 2\nAllocated slot number: 3\n"):]
+		if tt.stderr != stderr {
+			t.Errorf("test %q invalid stderr:\n\twant: %q\n\t got: %q", tt.src, tt.stderr, stderr)
+		}
+		if tt.stdout != stdout {
+			t.Errorf("test %q invalid stdout:\n\twant: %q\n\t got: %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestInvalidPatternCode(t *testing.T) {
+	tests := []string{
+		"cars_with_registration_matching \"KA-[\"",
+		"let slots = cars_with_registration_matching \"KA-[\"",
+		"find cars where registration ~ \"KA-[\"",
+	}
+
+	for _, src := range tests {
+		program, err := parser.Parse("create_parking_lot 1 " + src)
+		if err != nil {
+			t.Fatalf("parse %q error: %s", src, err)
+		}
+		e := Executor{Stdout: new(bytes.Buffer), Stderr: new(bytes.Buffer)}
+		results := e.Execute(program, database.NewDatabase(database.NewMemoryWriter()))
+		r, ok := results[len(results)-1].(Error)
+		if !ok {
+			t.Errorf("test %q invalid result - want: %s, got: %v", src, "Error", results[len(results)-1])
+			continue
+		}
+		if code := lerrors.CodeOf(r.Err); code != lerrors.InvalidReg {
+			t.Errorf("test %q invalid error code - want: %s, got: %s", src, lerrors.InvalidReg, code)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/ast"
+)
+
+// Output formats of NewRenderer.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatTable = "table"
+)
+
+// Formats are the output formats of NewRenderer.
+var Formats = []string{FormatText, FormatJSON, FormatJSONL, FormatCSV, FormatTable}
+
+// NewRenderer creates renderer of the given output format. Results are
+// written to stdout and errors to stderr.
+func NewRenderer(format string, stdout, stderr io.Writer) (Renderer, error) {
+	switch format {
+	case FormatText:
+		return &TextRenderer{Stdout: stdout, Stderr: stderr}, nil
+	case FormatJSON:
+		return &JSONRenderer{Stdout: stdout, Stderr: stderr, Indent: "  "}, nil
+	case FormatJSONL:
+		return &JSONRenderer{Stdout: stdout, Stderr: stderr}, nil
+	case FormatCSV:
+		return &CSVRenderer{Stdout: stdout, Stderr: stderr}, nil
+	case FormatTable:
+		return &TableRenderer{Stdout: stdout, Stderr: stderr}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q: use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// errorCode returns the code of error reported by executor. Errors
+// without code have code EXECUTION_FAILED.
+func errorCode(err error) lerrors.Code {
+	if code := lerrors.CodeOf(err); code != "" {
+		return code
+	}
+	return lerrors.ExecutionFailed
+}
+
+// JSONRenderer renders each result as a JSON value: status and queries
+// as lists, other results as objects with the result name, and errors as
+// objects with the error code and message. NotFound is an empty list.
+type JSONRenderer struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Indent indents values. Empty indent writes each value on a single
+	// line, as JSON Lines.
+	Indent string
+}
+
+// slotJSON is a result of park or leave in JSON.
+type slotJSON struct {
+	Result string `json:"result"`
+	Slot   int    `json:"slot"`
+}
+
+// countJSON is a result of count or free_slots in JSON.
+type countJSON struct {
+	Result string `json:"result"`
+	Count  int    `json:"count"`
+}
+
+// statusJSON is a slot of status in JSON.
+type statusJSON struct {
+	Slot         int    `json:"slot"`
+	Registration string `json:"registration"`
+	Colour       string `json:"colour"`
+}
+
+// colourCountJSON is a colour count in JSON.
+type colourCountJSON struct {
+	Colour string `json:"colour"`
+	Count  int    `json:"count"`
+}
+
+// errorJSON is an error in JSON.
+type errorJSON struct {
+	Error string       `json:"error"`
+	Code  lerrors.Code `json:"code"`
+}
+
+// Render writes r as JSON value followed by new line.
+func (j *JSONRenderer) Render(r Result) error {
+	w := j.Stdout
+	if _, ok := r.(Error); ok {
+		w = j.Stderr
+	}
+	if w == nil {
+		return nil
+	}
+
+	var v interface{}
+	switch r := r.(type) {
+	case Created:
+		v = struct {
+			Result   string `json:"result"`
+			Capacity int    `json:"capacity"`
+		}{"created", r.Capacity}
+	case Allocated:
+		v = slotJSON{"allocated", r.Slot}
+	case Freed:
+		v = slotJSON{"freed", r.Slot}
+	case StatusRows:
+		slots := make([]statusJSON, len(r.Rows))
+		for i, row := range r.Rows {
+			slots[i] = statusJSON{row.Slot, row.RegistrationNumber, row.Colour}
+		}
+		v = slots
+	case RegistrationNumbers:
+		v = nonNil(r.RegistrationNumbers)
+	case SlotList:
+		v = nonNil(r.Slots)
+	case FoundRows:
+		rows := make([]foundRowJSON, len(r.Rows))
+		for i, row := range r.Rows {
+			rows[i] = foundRowJSON{r.Fields, row}
+		}
+		v = rows
+	case Count:
+		v = countJSON{"count", r.Count}
+	case ColourCounts:
+		counts := make([]colourCountJSON, len(r.Counts))
+		for i, c := range r.Counts {
+			counts[i] = colourCountJSON{c.Color, c.Count}
+		}
+		v = counts
+	case Occupancy:
+		v = struct {
+			Result   string `json:"result"`
+			Capacity int    `json:"capacity"`
+			Occupied int    `json:"occupied"`
+			Percent  int    `json:"percent"`
+		}{"occupancy", r.Capacity, r.Occupied, r.Percent()}
+	case FreeSlots:
+		v = countJSON{"free_slots", r.Count}
+	case CommandResult:
+		v = struct {
+			Result string `json:"result"`
+			Value  Value  `json:"value"`
+		}{r.Name, r.Value}
+	case NotFound:
+		v = []interface{}{}
+	case Error:
+		v = errorJSON{r.Err.Error(), errorCode(r.Err)}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", j.Indent)
+	return enc.Encode(v)
+}
+
+// foundRowJSON is a found car encoded as object with selected fields in
+// order of selection.
+type foundRowJSON struct {
+	fields []string
+	values []Value
+}
+
+// MarshalJSON encodes the row as object.
+func (r foundRowJSON) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// nonNil returns s or empty slice if s is nil, so it is encoded as empty
+// JSON list.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// CSVRenderer renders each result as CSV records. Header is written before
+// the first result and whenever the columns change, so results of the same
+// query form a single table. NotFound and Error results are written to
+// Stderr as code and message records.
+type CSVRenderer struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// header and errHeader are the last headers written to Stdout and
+	// Stderr.
+	header    []string
+	errHeader []string
+}
+
+// Render writes r as CSV.
+func (c *CSVRenderer) Render(r Result) error {
+	headers, rows, isErr := records(r)
+	w, last := c.Stdout, &c.header
+	if isErr {
+		w, last = c.Stderr, &c.errHeader
+	}
+	if w == nil {
+		return nil
+	}
+
+	cw := csv.NewWriter(w)
+	if *last == nil || !slices.Equal(*last, headers) {
+		cw.Write(headers)
+		*last = headers
+	}
+	cw.WriteAll(rows)
+	return cw.Error()
+}
+
+// TableRenderer renders each result as a table with header. NotFound and
+// Error results are written to Stderr as code and message tables.
+type TableRenderer struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Render writes r as table.
+func (t *TableRenderer) Render(r Result) error {
+	headers, rows, isErr := records(r)
+	w := t.Stdout
+	if isErr {
+		w = t.Stderr
+	}
+	if w == nil {
+		return nil
+	}
+	return writeTable(w, headers, rows)
+}
+
+// records returns r as header and rows of CSV and table output, and
+// whether it is an error.
+func records(r Result) (headers []string, rows [][]string, isErr bool) {
+	switch r := r.(type) {
+	case Created:
+		return []string{"capacity"}, [][]string{{strconv.Itoa(r.Capacity)}}, false
+	case Allocated:
+		return []string{"allocated"}, [][]string{{strconv.Itoa(r.Slot)}}, false
+	case Freed:
+		return []string{"freed"}, [][]string{{strconv.Itoa(r.Slot)}}, false
+	case StatusRows:
+		rows = make([][]string, len(r.Rows))
+		for i, row := range r.Rows {
+			rows[i] = []string{strconv.Itoa(row.Slot), row.RegistrationNumber, row.Colour}
+		}
+		return []string{ast.FieldSlot, ast.FieldRegistration, ast.FieldColour}, rows, false
+	case RegistrationNumbers:
+		rows = make([][]string, len(r.RegistrationNumbers))
+		for i, registrationNumber := range r.RegistrationNumbers {
+			rows[i] = []string{registrationNumber}
+		}
+		return []string{ast.FieldRegistration}, rows, false
+	case SlotList:
+		rows = make([][]string, len(r.Slots))
+		for i, slot := range r.Slots {
+			rows[i] = []string{strconv.Itoa(slot)}
+		}
+		return []string{ast.FieldSlot}, rows, false
+	case FoundRows:
+		rows = make([][]string, len(r.Rows))
+		for i, row := range r.Rows {
+			rows[i] = make([]string, len(row))
+			for j := range row {
+				rows[i][j] = formatValue(row[j])
+			}
+		}
+		return r.Fields, rows, false
+	case Count:
+		return []string{"count"}, [][]string{{strconv.Itoa(r.Count)}}, false
+	case ColourCounts:
+		rows = make([][]string, len(r.Counts))
+		for i, c := range r.Counts {
+			rows[i] = []string{c.Color, strconv.Itoa(c.Count)}
+		}
+		return []string{ast.FieldColour, "count"}, rows, false
+	case Occupancy:
+		return []string{"capacity", "occupied", "percent"},
+			[][]string{{strconv.Itoa(r.Capacity), strconv.Itoa(r.Occupied), strconv.Itoa(r.Percent())}}, false
+	case FreeSlots:
+		return []string{"free_slots"}, [][]string{{strconv.Itoa(r.Count)}}, false
+	case CommandResult:
+		return []string{r.Name}, [][]string{{formatValue(r.Value)}}, false
+	case NotFound:
+		return errorRecords(ErrNotFound)
+	case Error:
+		return errorRecords(r.Err)
+	}
+	return nil, nil, false
+}
+
+// errorRecords returns err as code and message records.
+func errorRecords(err error) (headers []string, rows [][]string, isErr bool) {
+	return []string{"code", "error"}, [][]string{{string(errorCode(err)), err.Error()}}, true
+}
@@ -0,0 +1,219 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+)
+
+// renderTests are results rendered by every output format.
+var renderTests = []Result{
+	Created{6},
+	Allocated{1},
+	Freed{4},
+	StatusRows{[]StatusRow{{1, "KA-01-HH-1234", "White"}, {10, "KA-01-BB-0001", "Black"}}},
+	StatusRows{},
+	RegistrationNumbers{[]string{"KA-01-HH-1234", "KA-01-HH-9999"}},
+	SlotList{[]int{1, 2, 4}},
+	FoundRows{[]string{"registration", "slot"}, [][]Value{{"KA-01-HH-1234", 1}}},
+	Count{3},
+	ColourCounts{[]database.ColorCount{{Color: "White", Count: 2}}},
+	Occupancy{database.Occupancy{Capacity: 6, Occupied: 4}},
+	FreeSlots{2},
+	NotFound{},
+	Error{Err: database.ErrFull},
+	Error{Err: lerrors.New(lerrors.InvalidColour, "car colour \"Pink\" is invalid")},
+}
+
+func TestRenderers(t *testing.T) {
+	tests := []struct {
+		format string
+		stdout []string
+		stderr []string
+	}{
+		{
+			FormatJSONL,
+			[]string{
+				`{"result":"created","capacity":6}` + "\n",
+				`{"result":"allocated","slot":1}` + "\n",
+				`{"result":"freed","slot":4}` + "\n",
+				`[{"slot":1,"registration":"KA-01-HH-1234","colour":"White"},{"slot":10,"registration":"KA-01-BB-0001","colour":"Black"}]` + "\n",
+				"[]\n",
+				`["KA-01-HH-1234","KA-01-HH-9999"]` + "\n",
+				"[1,2,4]\n",
+				`[{"registration":"KA-01-HH-1234","slot":1}]` + "\n",
+				`{"result":"count","count":3}` + "\n",
+				`[{"colour":"White","count":2}]` + "\n",
+				`{"result":"occupancy","capacity":6,"occupied":4,"percent":66}` + "\n",
+				`{"result":"free_slots","count":2}` + "\n",
+				"[]\n",
+				"",
+				"",
+			},
+			[]string{
+				"", "", "", "", "", "", "", "", "", "", "", "", "",
+				`{"error":"sorry, parking lot is full","code":"LOT_FULL"}` + "\n",
+				`{"error":"car colour \"Pink\" is invalid","code":"INVALID_COLOUR"}` + "\n",
+			},
+		},
+		{
+			FormatCSV,
+			[]string{
+				"capacity\n6\n",
+				"allocated\n1\n",
+				"freed\n4\n",
+				"slot,registration,colour\n1,KA-01-HH-1234,White\n10,KA-01-BB-0001,Black\n",
+				"slot,registration,colour\n",
+				"registration\nKA-01-HH-1234\nKA-01-HH-9999\n",
+				"slot\n1\n2\n4\n",
+				"registration,slot\nKA-01-HH-1234,1\n",
+				"count\n3\n",
+				"colour,count\nWhite,2\n",
+				"capacity,occupied,percent\n6,4,66\n",
+				"free_slots\n2\n",
+				"",
+				"",
+				"",
+			},
+			[]string{
+				"", "", "", "", "", "", "", "", "", "", "", "",
+				"code,error\nNOT_FOUND,not found\n",
+				"code,error\nLOT_FULL,\"sorry, parking lot is full\"\n",
+				"code,error\nINVALID_COLOUR,\"car colour \"\"Pink\"\" is invalid\"\n",
+			},
+		},
+		{
+			FormatTable,
+			[]string{
+				"capacity\n6\n",
+				"allocated\n1\n",
+				"freed\n4\n",
+				"slot    registration     colour\n" +
+					"1       KA-01-HH-1234    White\n" +
+					"10      KA-01-BB-0001    Black\n",
+				"slot    registration    colour\n",
+				"registration\nKA-01-HH-1234\nKA-01-HH-9999\n",
+				"slot\n1\n2\n4\n",
+				"registration     slot\nKA-01-HH-1234    1\n",
+				"count\n3\n",
+				"colour    count\nWhite     2\n",
+				"capacity    occupied    percent\n6           4           66\n",
+				"free_slots\n2\n",
+				"",
+				"",
+				"",
+			},
+			[]string{
+				"", "", "", "", "", "", "", "", "", "", "", "",
+				"code         error\nNOT_FOUND    not found\n",
+				"code        error\nLOT_FULL    sorry, parking lot is full\n",
+				"code              error\nINVALID_COLOUR    car colour \"Pink\" is invalid\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for i, result := range renderTests {
+			var stdout, stderr bytes.Buffer
+			r, err := NewRenderer(tt.format, &stdout, &stderr)
+			if err != nil {
+				t.Fatalf("new %s renderer error: %s", tt.format, err)
+			}
+			if err := r.Render(result); err != nil {
+				t.Errorf("%s render %#v error: %s", tt.format, result, err)
+			}
+			if stdout.String() != tt.stdout[i] {
+				t.Errorf("%s render %#v invalid stdout:\n\twant: %q\n\t got: %q", tt.format, result, tt.stdout[i], stdout.String())
+			}
+			if stderr.String() != tt.stderr[i] {
+				t.Errorf("%s render %#v invalid stderr:\n\twant: %q\n\t got: %q", tt.format, result, tt.stderr[i], stderr.String())
+			}
+
+			// nil writers discard the output
+			r, _ = NewRenderer(tt.format, nil, nil)
+			if err := r.Render(result); err != nil {
+				t.Errorf("%s render %#v without writer error: %s", tt.format, result, err)
+			}
+		}
+	}
+}
+
+func TestJSONRendererIndent(t *testing.T) {
+	var stdout bytes.Buffer
+	r, _ := NewRenderer(FormatJSON, &stdout, nil)
+	r.Render(SlotList{[]int{1, 2}})
+	r.Render(Allocated{3})
+
+	want := "[\n  1,\n  2\n]\n{\n  \"result\": \"allocated\",\n  \"slot\": 3\n}\n"
+	if stdout.String() != want {
+		t.Errorf("invalid output:\n\twant: %q\n\t got: %q", want, stdout.String())
+	}
+}
+
+func TestJSONRendererFieldOrder(t *testing.T) {
+	var stdout bytes.Buffer
+	r, _ := NewRenderer(FormatJSONL, &stdout, nil)
+	r.Render(FoundRows{[]string{"slot", "colour", "registration"}, [][]Value{{1, "White", "KA-01-HH-1234"}}})
+
+	want := `[{"slot":1,"colour":"White","registration":"KA-01-HH-1234"}]` + "\n"
+	if stdout.String() != want {
+		t.Errorf("invalid output:\n\twant: %q\n\t got: %q", want, stdout.String())
+	}
+}
+
+func TestCSVRendererHeader(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	r, _ := NewRenderer(FormatCSV, &stdout, &stderr)
+	r.Render(Allocated{1})
+	r.Render(Allocated{2})
+	r.Render(NotFound{})
+	r.Render(Freed{1})
+	r.Render(Allocated{1})
+	r.Render(Error{Err: database.ErrFull})
+
+	want := "allocated\n1\n2\nfreed\n1\nallocated\n1\n"
+	if stdout.String() != want {
+		t.Errorf("invalid stdout:\n\twant: %q\n\t got: %q", want, stdout.String())
+	}
+	want = "code,error\nNOT_FOUND,not found\nLOT_FULL,\"sorry, parking lot is full\"\n"
+	if stderr.String() != want {
+		t.Errorf("invalid stderr:\n\twant: %q\n\t got: %q", want, stderr.String())
+	}
+}
+
+func TestNewRenderer(t *testing.T) {
+	for _, format := range Formats {
+		if _, err := NewRenderer(format, nil, nil); err != nil {
+			t.Errorf("new %s renderer error: %s", format, err)
+		}
+	}
+
+	want := `unknown output format "xml": use one of text, json, jsonl, csv, table`
+	if _, err := NewRenderer("xml", nil, nil); err == nil || err.Error() != want {
+		t.Errorf("invalid error - want: %s, got: %v", want, err)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code lerrors.Code
+	}{
+		{database.ErrFull, lerrors.LotFull},
+		{fmt.Errorf("park: %w", database.ErrIdentity), lerrors.DuplicateReg},
+		{database.NewDatabase(database.NewMemoryWriter()).Remove(0), lerrors.SlotOutOfRange},
+		{&fatalError{"program exceeds step limit 1"}, lerrors.LimitExceeded},
+		{ErrNotFound, lerrors.NotFound},
+		{errors.New("undefined variable $x"), lerrors.ExecutionFailed},
+	}
+
+	for _, tt := range tests {
+		if code := errorCode(tt.err); code != tt.code {
+			t.Errorf("error %q invalid code - want: %s, got: %s", tt.err, tt.code, code)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/token"
+)
+
+// Result is an outcome of a single executed statement. Slots of results
+// are numbered from 1.
+type Result interface {
+	result()
+}
+
+// Created is the result of create_parking_lot.
+type Created struct {
+	Capacity int
+}
+
+// Allocated is the result of park.
+type Allocated struct {
+	Slot int
+}
+
+// Freed is the result of leave.
+type Freed struct {
+	Slot int
+}
+
+// StatusRow is a parked car listed by status.
+type StatusRow struct {
+	Slot               int
+	RegistrationNumber string
+	Colour             string
+}
+
+// StatusRows is the result of status and cars_with_registration_matching.
+type StatusRows struct {
+	Rows []StatusRow
+}
+
+// RegistrationNumbers is the result of
+// registration_numbers_for_cars_with_colour.
+type RegistrationNumbers struct {
+	RegistrationNumbers []string
+}
+
+// SlotList is the result of slot_numbers_for_cars_with_colour and
+// slot_number_for_registration_number.
+type SlotList struct {
+	Slots []int
+}
+
+// FoundRows is the result of find. Values of each row are in order of
+// fields.
+type FoundRows struct {
+	Fields []string
+	Rows   [][]Value
+}
+
+// Count is the result of count.
+type Count struct {
+	Count int
+}
+
+// ColourCounts is the result of count by colour, sorted by colour.
+type ColourCounts struct {
+	Counts []database.ColorCount
+}
+
+// Occupancy is the result of occupancy.
+type Occupancy struct {
+	database.Occupancy
+}
+
+// FreeSlots is the result of free_slots.
+type FreeSlots struct {
+	Count int
+}
+
+// CommandResult is the result of registered command, see package command.
+type CommandResult struct {
+	Name  string
+	Value Value
+}
+
+// NotFound is the result of query which found no car.
+type NotFound struct{}
+
+// ErrNotFound is the error of NotFound result, it has code NOT_FOUND.
+var ErrNotFound = lerrors.New(lerrors.NotFound, "not found")
+
+// Error is the result of failed statement. Error of a statement which
+// aborts the program is the last result.
+type Error struct {
+	Err error
+
+	// Pos is the position of the failed statement, the innermost one
+	// in blocks, loops, procedures and included files. It is invalid if
+	// Executor has no FileSet.
+	Pos token.Position
+}
+
+// FileError is an error of statement of included file. Its message starts
+// with the position of the statement, as the statement is not in the
+// executed file.
+type FileError struct {
+	Pos token.Position
+	Err error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Err)
+}
+
+// Unwrap returns the error of the statement.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+func (Created) result()             {}
+func (Allocated) result()           {}
+func (Freed) result()               {}
+func (StatusRows) result()          {}
+func (RegistrationNumbers) result() {}
+func (SlotList) result()            {}
+func (FoundRows) result()           {}
+func (Count) result()               {}
+func (ColourCounts) result()        {}
+func (Occupancy) result()           {}
+func (FreeSlots) result()           {}
+func (CommandResult) result()       {}
+func (NotFound) result()            {}
+func (Error) result()               {}
+
+// Renderer renders results of statements as they are executed.
+type Renderer interface {
+	Render(r Result) error
+}
+
+// TextRenderer renders results as the text of parking lot specification.
+// NotFound and Error results are written to Stderr. Nil writer discards
+// its output.
+type TextRenderer struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Render writes r as text.
+func (t *TextRenderer) Render(r Result) error {
+	w := t.Stdout
+	switch r.(type) {
+	case NotFound, Error:
+		w = t.Stderr
+	}
+	if w == nil {
+		return nil
+	}
+
+	var err error
+	switch r := r.(type) {
+	case Created:
+		_, err = fmt.Fprintf(w, "Created a parking lot with %d slots\n", r.Capacity)
+	case Allocated:
+		_, err = fmt.Fprintf(w, "Allocated slot number: %d\n", r.Slot)
+	case Freed:
+		_, err = fmt.Fprintf(w, "Slot number %d is free\n", r.Slot)
+	case StatusRows:
+		rows := make([][]string, len(r.Rows))
+		for i, row := range r.Rows {
+			rows[i] = []string{strconv.Itoa(row.Slot), row.RegistrationNumber, row.Colour}
+		}
+		err = writeTable(w, []string{"Slot No.", "Registration No", "Colour"}, rows)
+	case RegistrationNumbers:
+		_, err = fmt.Fprintln(w, strings.Join(r.RegistrationNumbers, ", "))
+	case SlotList:
+		s := make([]string, len(r.Slots))
+		for i := range r.Slots {
+			s[i] = strconv.Itoa(r.Slots[i])
+		}
+		_, err = fmt.Fprintln(w, strings.Join(s, ", "))
+	case FoundRows:
+		headers := make([]string, len(r.Fields))
+		for i, field := range r.Fields {
+			headers[i] = findHeaders[field]
+		}
+		rows := make([][]string, len(r.Rows))
+		for i, row := range r.Rows {
+			rows[i] = make([]string, len(row))
+			for j := range row {
+				rows[i][j] = formatValue(row[j])
+			}
+		}
+		err = writeTable(w, headers, rows)
+	case Count:
+		_, err = fmt.Fprintln(w, r.Count)
+	case ColourCounts:
+		rows := make([][]string, len(r.Counts))
+		for i, c := range r.Counts {
+			rows[i] = []string{c.Color, strconv.Itoa(c.Count)}
+		}
+		err = writeTable(w, []string{"Colour", "Count"}, rows)
+	case Occupancy:
+		_, err = fmt.Fprintf(w, "Occupied %d of %d slots (%d%%)\n", r.Occupied, r.Capacity, r.Percent())
+	case FreeSlots:
+		_, err = fmt.Fprintln(w, r.Count)
+	case CommandResult:
+		_, err = fmt.Fprintln(w, formatValue(r.Value))
+	case NotFound:
+		_, err = fmt.Fprintln(w, errorText(ErrNotFound))
+	case Error:
+		_, err = fmt.Fprintln(w, errorText(r.Err))
+	}
+	return err
+}
+
+// specMessages are messages of errors which differ from the error text in
+// the parking lot specification.
+var specMessages = map[error]string{
+	database.ErrFull: "Sorry, parking lot is full",
+	ErrNotFound:      "Not found",
+}
+
+// errorText returns the message of err as written by TextRenderer.
+func errorText(err error) string {
+	var fe *FileError
+	if errors.As(err, &fe) {
+		return fmt.Sprintf("%s: %s", fe.Pos, errorText(fe.Err))
+	}
+	for target, msg := range specMessages {
+		if errors.Is(err, target) {
+			return msg
+		}
+	}
+	return err.Error()
+}
+
+// writeTable writes rows aligned in columns under headers.
+func writeTable(w io.Writer, headers []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 4, ' ', 0)
+	fmt.Fprintf(tw, "%s\n", strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\n", strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,169 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"parking_lot/database"
+	"parking_lot/lot/parser"
+)
+
+func TestExecuteResults(t *testing.T) {
+	tests := []struct {
+		src     string
+		results []Result
+	}{
+		{"", nil},
+		{
+			"create_parking_lot 3 park KA-01-HH-1234 White park KA-01-HH-9999 Red leave 1 status",
+			[]Result{
+				Created{3},
+				Allocated{1},
+				Allocated{2},
+				Freed{1},
+				StatusRows{[]StatusRow{{2, "KA-01-HH-9999", "Red"}}},
+			},
+		},
+		{
+			"create_parking_lot 2 park KA-01-HH-1234 White park KA-01-HH-1234 Red leave 3 " +
+				"registration_numbers_for_cars_with_colour White slot_numbers_for_cars_with_colour Red " +
+				"slot_number_for_registration_number KA-01-HH-1234 cars_with_registration_matching KA-*",
+			[]Result{
+				Created{2},
+				Allocated{1},
+				Error{Err: database.ErrIdentity},
+				Error{Err: errors.New("slot number 2 out of range [1, 2]")},
+				RegistrationNumbers{[]string{"KA-01-HH-1234"}},
+				NotFound{},
+				SlotList{[]int{1}},
+				StatusRows{[]StatusRow{{1, "KA-01-HH-1234", "White"}}},
+			},
+		},
+		{
+			"create_parking_lot 4 park KA-01-HH-0001 White park KA-01-HH-0002 Red park KA-01-HH-0003 White " +
+				"find cars where colour == White select slot, registration order by slot desc " +
+				"find cars where colour == Blue count count by colour occupancy free_slots",
+			[]Result{
+				Created{4},
+				Allocated{1},
+				Allocated{2},
+				Allocated{3},
+				FoundRows{[]string{"slot", "registration"}, [][]Value{{3, "KA-01-HH-0003"}, {1, "KA-01-HH-0001"}}},
+				NotFound{},
+				Count{3},
+				ColourCounts{[]database.ColorCount{{Color: "Red", Count: 1}, {Color: "White", Count: 2}}},
+				Occupancy{database.Occupancy{Capacity: 4, Occupied: 3}},
+				FreeSlots{1},
+			},
+		},
+		{
+			"def f(n) { leave $n } create_parking_lot 1 f(1) while true { }",
+			[]Result{
+				Created{1},
+				Freed{1},
+				Error{Err: errors.New("while true exceeds iteration limit 10")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		program, err := parser.Parse(tt.src)
+		if err != nil {
+			t.Fatalf("parse %q error: %s", tt.src, err)
+		}
+
+		for name, execute := range map[string]func(e *Executor, db *database.Database) []Result{
+			"execute": func(e *Executor, db *database.Database) []Result { return e.Execute(program, db) },
+			"run":     func(e *Executor, db *database.Database) []Result { return e.Run(Compile(program, db), db) },
+		} {
+			e := &Executor{MaxIterations: 10}
+			results := execute(e, database.NewDatabase(database.NewMemoryWriter()))
+			if !equalResults(results, tt.results) {
+				t.Errorf("%s %q invalid results - want: %v, got: %v", name, tt.src, tt.results, results)
+			}
+		}
+	}
+}
+
+// equalResults reports whether results are equal, errors are compared by
+// message.
+func equalResults(a, b []Result) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		ea, aok := a[i].(Error)
+		eb, bok := b[i].(Error)
+		if aok && bok {
+			if ea.Err.Error() != eb.Err.Error() {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTextRenderer(t *testing.T) {
+	tests := []struct {
+		result Result
+		stdout string
+		stderr string
+	}{
+		{Created{6}, "Created a parking lot with 6 slots\n", ""},
+		{Allocated{1}, "Allocated slot number: 1\n", ""},
+		{Freed{4}, "Slot number 4 is free\n", ""},
+		{
+			StatusRows{[]StatusRow{{1, "KA-01-HH-1234", "White"}, {10, "KA-01-BB-0001", "Black"}}},
+			"Slot No.    Registration No    Colour\n" +
+				"1           KA-01-HH-1234      White\n" +
+				"10          KA-01-BB-0001      Black\n",
+			"",
+		},
+		{StatusRows{}, "Slot No.    Registration No    Colour\n", ""},
+		{RegistrationNumbers{[]string{"KA-01-HH-1234", "KA-01-HH-9999"}}, "KA-01-HH-1234, KA-01-HH-9999\n", ""},
+		{SlotList{[]int{1, 2, 4}}, "1, 2, 4\n", ""},
+		{
+			FoundRows{[]string{"registration", "slot"}, [][]Value{{"KA-01-HH-1234", 1}}},
+			"Registration No    Slot No.\n" +
+				"KA-01-HH-1234      1\n",
+			"",
+		},
+		{Count{3}, "3\n", ""},
+		{
+			ColourCounts{[]database.ColorCount{{Color: "White", Count: 2}}},
+			"Colour    Count\n" +
+				"White     2\n",
+			"",
+		},
+		{Occupancy{database.Occupancy{Capacity: 6, Occupied: 4}}, "Occupied 4 of 6 slots (66%)\n", ""},
+		{FreeSlots{2}, "2\n", ""},
+		{NotFound{}, "", "Not found\n"},
+		{Error{Err: database.ErrFull}, "", "Sorry, parking lot is full\n"},
+		{Error{Err: database.ErrIdentity}, "", "identity thieves are not welcome, calling police\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		r := &TextRenderer{Stdout: &stdout, Stderr: &stderr}
+		if err := r.Render(tt.result); err != nil {
+			t.Errorf("render %#v error: %s", tt.result, err)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("render %#v invalid stdout:\n\twant: %q\n\t got: %q", tt.result, tt.stdout, stdout.String())
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("render %#v invalid stderr:\n\twant: %q\n\t got: %q", tt.result, tt.stderr, stderr.String())
+		}
+	}
+
+	// nil writers discard the output
+	if err := (&TextRenderer{}).Render(Created{1}); err != nil {
+		t.Errorf("render without writer error: %s", err)
+	}
+}
@@ -0,0 +1,51 @@
+package exec
+
+// scope holds variables bound with let and defined procedures.
+type scope struct {
+	vars  map[string]Value
+	procs map[string]*procedure
+	outer *scope
+}
+
+// newScope creates a scope nested in outer (nil for the global scope).
+func newScope(outer *scope) *scope {
+	return &scope{
+		vars:  make(map[string]Value),
+		procs: make(map[string]*procedure),
+		outer: outer,
+	}
+}
+
+// lookup returns the value of the variable with given name, searching
+// outer scopes when the name is not bound in s.
+func (s *scope) lookup(name string) (Value, bool) {
+	for ; s != nil; s = s.outer {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// set assigns the variable in the nearest scope which binds it or defines
+// it in s if the name is not bound yet.
+func (s *scope) set(name string, v Value) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if _, ok := sc.vars[name]; ok {
+			sc.vars[name] = v
+			return
+		}
+	}
+	s.vars[name] = v
+}
+
+// lookupProc returns the procedure with given name, searching outer scopes
+// when the name is not defined in s.
+func (s *scope) lookupProc(name string) (*procedure, bool) {
+	for ; s != nil; s = s.outer {
+		if p, ok := s.procs[name]; ok {
+			return p, true
+		}
+	}
+	return nil, false
+}
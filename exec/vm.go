@@ -0,0 +1,272 @@
+package exec
+
+import (
+	"fmt"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// vm runs compiled code.
+type vm struct {
+	e    *Executor
+	db   *database.Database
+	code *Code
+}
+
+// frame is a statement being run. Error of the statement is reported and
+// the stack and scope are restored, so the run continues after it.
+type frame struct {
+	pos   token.Pos // position of the statement
+	end   int
+	sp    int
+	scope *scope
+}
+
+// Run runs code compiled by Compile on db and returns results of executed
+// statements. It works as Execute of the compiled program: the results,
+// limits and scope are the same.
+func (e *Executor) Run(code *Code, db *database.Database) []Result {
+	e.start()
+	global := e.scope
+	m := &vm{e: e, db: db, code: code}
+	if _, err := m.run(code); err != nil {
+		e.scope = global
+		e.emit(Error{Err: err})
+	}
+	return e.results
+}
+
+// run runs code and returns the value of return statement. The returned
+// error is fatal.
+func (m *vm) run(code *Code) (Value, error) {
+	var (
+		e      = m.e
+		stack  []Value
+		frames []frame
+	)
+
+	for pc := 0; pc < len(code.instrs); {
+		in := code.instrs[pc]
+		pc++
+
+		var err error
+		switch in.op {
+		case opStmt:
+			pos := code.consts[in.arg].(token.Pos)
+			if err := e.enter(pos); err != nil {
+				return nil, err
+			}
+			frames = append(frames, frame{pos: pos, end: int(in.target), sp: len(stack), scope: e.scope})
+		case opEnd:
+			frames = frames[:len(frames)-1]
+		case opConst:
+			stack = append(stack, code.consts[in.arg])
+		case opLoad:
+			x := code.consts[in.arg].(*ast.Ident)
+			v, ok := e.scope.lookup(x.Name)
+			if !ok {
+				err = fmt.Errorf("undefined variable %s", x)
+				break
+			}
+			stack = append(stack, v)
+		case opStore:
+			e.scope.set(code.consts[in.arg].(string), stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+		case opPop:
+			stack = stack[:len(stack)-1]
+		case opInt:
+			_, err = expectInt(code.consts[in.arg].(ast.Expr), stack[len(stack)-1])
+		case opString:
+			_, err = expectString(code.consts[in.arg].(ast.Expr), stack[len(stack)-1])
+		case opBool:
+			_, err = expectBool(code.consts[in.arg].(ast.Expr), stack[len(stack)-1])
+		case opNot:
+			stack[len(stack)-1] = !stack[len(stack)-1].(bool)
+		case opBinary:
+			n := len(stack)
+			var v Value
+			if v, err = evalBinaryOp(code.consts[in.arg].(*ast.BinaryExpr), stack[n-2], stack[n-1]); err == nil {
+				stack = append(stack[:n-2], v)
+			}
+		case opJump:
+			pc = int(in.target)
+		case opJumpIfFalse:
+			ok := stack[len(stack)-1].(bool)
+			stack = stack[:len(stack)-1]
+			if !ok {
+				pc = int(in.target)
+			}
+		case opJumpFalseOrPop, opJumpTrueOrPop:
+			if stack[len(stack)-1].(bool) == (in.op == opJumpTrueOrPop) {
+				pc = int(in.target)
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+		case opLookupCall:
+			_, _, err = e.lookupCall(code.consts[in.arg].(*ast.CallExpr))
+		case opCall:
+			x := code.consts[in.arg].(*ast.CallExpr)
+			n := len(stack) - len(x.Args)
+			args := append([]Value(nil), stack[n:]...)
+			var v Value
+			if v, err = m.call(x, args); err == nil {
+				stack = append(stack[:n], v)
+			}
+		case opResult:
+			if stack[len(stack)-1] == nil {
+				err = fmt.Errorf("%s does not return a value", code.consts[in.arg])
+			}
+		case opQuery:
+			var v Value
+			if v, err = e.evalQueryExpr(m.db, code.consts[in.arg].(*ast.QueryExpr)); err == nil {
+				stack = append(stack, v)
+			}
+		case opFail:
+			err = code.consts[in.arg].(error)
+		case opCreate:
+			n := stack[len(stack)-1].(int)
+			stack = stack[:len(stack)-1]
+			if err = m.db.Init(n); err == nil {
+				err = e.emit(Created{Capacity: n})
+			}
+		case opPark:
+			err = m.park(code.consts[in.arg].(*database.Car))
+		case opParkValues:
+			n := len(stack)
+			registrationNumber, color := stack[n-2].(string), stack[n-1].(string)
+			stack = stack[:n-2]
+			var car *database.Car
+			if car, err = m.db.NewCar(registrationNumber, color); err == nil {
+				err = m.park(car)
+			}
+		case opLeave:
+			n := stack[len(stack)-1].(int)
+			stack = stack[:len(stack)-1]
+			if err = m.db.Remove(n - 1); err == nil {
+				err = e.emit(Freed{Slot: n})
+			}
+		case opStatus:
+			err = e.execStatusStatement(m.db)
+		case opExec:
+			err = e.execQuery(m.db, code.consts[in.arg].(ast.Statement))
+		case opPushScope:
+			e.scope = newScope(e.scope)
+		case opPopScope:
+			e.scope = e.scope.outer
+		case opForInit:
+			stmt := code.consts[in.arg].(*ast.ForStatement)
+			from, to := stack[len(stack)-2].(int), stack[len(stack)-1].(int)
+			if n := to - from; to >= from && (n < 0 || n >= e.maxIterations()) {
+				return nil, &fatalError{fmt.Sprintf("loop %s%s%s exceeds iteration limit %d",
+					stmt.From, token.RANGE, stmt.To, e.maxIterations())}
+			}
+			stack = append(stack, 0)
+		case opForNext:
+			n := len(stack)
+			from, to, i := stack[n-3].(int), stack[n-2].(int), stack[n-1].(int)
+			if i > 0 {
+				e.scope = e.scope.outer // scope of previous iteration
+			}
+			if i > to-from {
+				stack = stack[:n-3]
+				pc = int(in.target)
+				break
+			}
+			e.scope = newScope(e.scope)
+			e.scope.vars[code.consts[in.arg].(*ast.ForStatement).Var] = from + i
+			stack[n-1] = i + 1
+		case opWhileNext:
+			i := stack[len(stack)-1].(int)
+			if i == e.maxIterations() {
+				return nil, &fatalError{fmt.Sprintf("while %s exceeds iteration limit %d",
+					code.consts[in.arg].(*ast.WhileStatement).Cond, e.maxIterations())}
+			}
+			stack[len(stack)-1] = i + 1
+		case opDef:
+			err = e.execDefStatement(code.consts[in.arg].(*ast.DefStatement))
+		case opReturn:
+			if in.arg == 0 {
+				return nil, nil
+			}
+			return stack[len(stack)-1], nil
+		}
+
+		if err != nil {
+			if _, ok := err.(*fatalError); ok {
+				return nil, err
+			}
+			f := frames[len(frames)-1]
+			e.emit(e.stmtError(f.pos, err))
+			stack = stack[:f.sp]
+			e.scope = f.scope
+			pc = f.end
+		}
+	}
+	return nil, nil
+}
+
+// park saves car.
+func (m *vm) park(car *database.Car) error {
+	i, err := m.db.Save(car)
+	if err != nil {
+		return err
+	}
+	return m.e.emit(Allocated{Slot: i + 1})
+}
+
+// call calls built-in function or procedure of x with evaluated
+// arguments. The returned value is nil if procedure does not return
+// a value.
+func (m *vm) call(x *ast.CallExpr, args []Value) (Value, error) {
+	e := m.e
+	b, proc, err := e.lookupCall(x)
+	if err != nil {
+		return nil, err
+	}
+	if proc == nil {
+		return b.fn(m.db, args)
+	}
+
+	if e.depth == e.maxCallDepth() {
+		return nil, &fatalError{fmt.Sprintf("procedure %s exceeds call depth limit %d", proc.def.Name, e.maxCallDepth())}
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+
+	outer, fset := e.scope, e.FileSet
+	e.scope, e.FileSet = newScope(proc.scope), proc.fset
+	defer func() { e.scope, e.FileSet = outer, fset }()
+
+	for i, name := range proc.def.Params {
+		e.scope.vars[name] = args[i]
+	}
+	return m.run(m.code.proc(proc.def))
+}
+
+// execQuery executes query or command statement. Queries spend their time
+// in the database, so compiled code runs them by the tree-walking executor.
+func (e *Executor) execQuery(db *database.Database, stmt ast.Statement) error {
+	switch stmt := stmt.(type) {
+	case *ast.RegistrationNumbersForCarsWithColourStatement:
+		return e.execRegistrationNumbersForCarsWithColourStatement(db, stmt)
+	case *ast.SlotNumbersForCarsWithColourStatement:
+		return e.execSlotNumbersForCarsWithColourStatement(db, stmt)
+	case *ast.SlotNumberForRegistrationNumberStatement:
+		return e.execSlotNumberForRegistrationNumberStatement(db, stmt)
+	case *ast.CarsWithRegistrationMatchingStatement:
+		return e.execCarsWithRegistrationMatchingStatement(db, stmt)
+	case *ast.FindStatement:
+		return e.execFindStatement(db, stmt)
+	case *ast.CountStatement:
+		return e.execCountStatement(db, stmt)
+	case *ast.OccupancyStatement:
+		return e.execOccupancyStatement(db)
+	case *ast.FreeSlotsStatement:
+		return e.execFreeSlotsStatement(db)
+	case *ast.CommandStatement:
+		return e.execCommandStatement(db, stmt)
+	}
+	return nil
+}
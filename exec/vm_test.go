@@ -0,0 +1,202 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/parser"
+)
+
+var vmTests = []string{
+	"",
+	"create_parking_lot 6\npark KA-01-HH-1234 White\npark KA-01-HH-9999 White\npark KA-01-BB-0001 Black\n" +
+		"leave 2\nstatus\npark KA-01-HH-9999 White\npark KA-01-HH-1234 Red\npark invalid White\n" +
+		"registration_numbers_for_cars_with_colour White\nslot_numbers_for_cars_with_colour Black\n" +
+		"slot_number_for_registration_number KA-01-HH-9999\nslot_number_for_registration_number MH-04-AY-1111\n" +
+		"cars_with_registration_matching KA-01-HH-*\ncount\ncount by colour\noccupancy\nfree_slots",
+	"create_parking_lot 3 for i in 1..3 { park \"KA-01-HH-100\" + $i White } leave 4 leave $x let s = \"1\" leave $s status",
+	"create_parking_lot 2 if 1 > 2 { leave 1 } else if 1 > 1 { leave 2 } else { leave 3 }",
+	"create_parking_lot 2 let i = 0 while $i < 2 { let i = $i + 1 leave $i }",
+	"let i = 0 for j in 1..3 { let i = $i + $j let k = 1 } create_parking_lot $i",
+	"for j in 1..3 { let k = $j } create_parking_lot $k",
+	"if 1 { status } create_parking_lot 1 if not 1 { status } if true and 1 { status } if false or 1 { status }",
+	"if false and 1 { status } if true or 1 { status } let x = 1 / 0 let y = 1 + true let z = \"a\" + 1 + true create_parking_lot len($z)",
+	"for i in 1..11 { status } create_parking_lot 1",
+	"while true { let x = 1 } create_parking_lot 1",
+	"for i in 1..2 { while true { } } create_parking_lot 1",
+	"for i in 1..10 { for j in 1..10 { let x = 1 } } create_parking_lot 1",
+	"def fill(n, colour) { for i in 1..$n { if free_slots() == 0 { return $i - 1 } park \"KA-01-HH-100\" + $i $colour } return $n }\n" +
+		"create_parking_lot 3 let n = fill(5, White) create_parking_lot $n fill(1) fill(1, 2) undefined() let x = status_of()",
+	"def f() { status } def g(n) { if $n > 0 { return g($n - 1) } return 0 } create_parking_lot f() let x = g(3) create_parking_lot $x g(20)",
+	"def fact(n) { if $n <= 1 { return 1 } return $n * fact($n - 1) } create_parking_lot fact(4) def len(x) { return 1 }",
+	"create_parking_lot 4 park KA-01-HH-1 White park KA-01-HH-2 Red park KA-01-HH-3 White\n" +
+		"let slots = find cars where colour == White select slot leave first($slots)\n" +
+		"let regs = registration_numbers_for_cars_with_colour Red let n = len($regs) create_parking_lot $n + contains($regs, first($regs))\n" +
+		"find cars order by registration desc let c = count by colour let o = occupancy let s = slot_number_for_registration_number KA-01-HH-1",
+	"def outer() { def inner() { return 2 } return inner() } create_parking_lot outer() inner()",
+	"create_parking_lot 1 park $r White park KA-01-HH-1 $c let r = 1 park $r White let c = 1 park KA-01-HH-1 $c",
+}
+
+// runBoth runs src by Execute and Run and returns both outputs.
+func runBoth(t testing.TB, src string, limits Executor) (executed, run string) {
+	program, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse %q error: %s", src, err)
+	}
+
+	output := func(execute func(e *Executor, db *database.Database)) string {
+		out := new(bytes.Buffer)
+		e := limits
+		e.Stdout, e.Stderr = out, out
+		db := database.NewDatabase(limitWriter{database.NewMemoryWriter()})
+		execute(&e, db)
+		// scope must stay usable after the program
+		e.Execute(&ast.Program{Statements: []ast.Statement{&ast.StatusStatement{}}}, db)
+		return out.String()
+	}
+	executed = output(func(e *Executor, db *database.Database) { e.Execute(program, db) })
+	run = output(func(e *Executor, db *database.Database) { e.Run(Compile(program, db), db) })
+	return executed, run
+}
+
+func TestRun(t *testing.T) {
+	limits := Executor{MaxIterations: 10, MaxCallDepth: 10, MaxSteps: 100}
+	for _, src := range vmTests {
+		executed, run := runBoth(t, src, limits)
+		if run != executed {
+			t.Errorf("run %q invalid output:\n\twant: %q\n\t got: %q", src, executed, run)
+		}
+	}
+}
+
+func TestRunScope(t *testing.T) {
+	var (
+		out = new(bytes.Buffer)
+		e   = Executor{Stdout: out, Stderr: out}
+		db  = database.NewDatabase(database.NewMemoryWriter())
+	)
+
+	for _, src := range []string{
+		"def size() { return 2 } let n = size()",
+		"create_parking_lot $n",
+		"def park_white(r) { park $r White }",
+		"park_white(KA-01-HH-1234) status",
+	} {
+		program, err := parser.Parse(src)
+		if err != nil {
+			t.Fatalf("parse %q error: %s", src, err)
+		}
+		if strings.HasPrefix(src, "create") {
+			e.Execute(program, db)
+		} else {
+			e.Run(Compile(program, db), db)
+		}
+	}
+
+	want := "Created a parking lot with 2 slots\n" +
+		"Allocated slot number: 1\n" +
+		"Slot No.    Registration No    Colour\n" +
+		"1           KA-01-HH-1234      White\n"
+	if out.String() != want {
+		t.Errorf("invalid output:\n\twant: %q\n\t got: %q", want, out.String())
+	}
+}
+
+func FuzzRun(f *testing.F) {
+	for _, src := range vmTests {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		if strings.Contains(strings.ToLower(src), "include") {
+			t.Skip("include reads files")
+		}
+		if _, err := parser.Parse(src); err != nil {
+			return
+		}
+
+		limits := Executor{MaxIterations: 10, MaxCallDepth: 5, MaxSteps: 1000}
+		executed, run := runBoth(t, src, limits)
+		if run != executed {
+			t.Errorf("run %q invalid output:\n\twant: %q\n\t got: %q", src, executed, run)
+		}
+	})
+}
+
+// benchmarkPrograms are simulation runs: replay of a logged day with
+// literal arguments and a generated day with loops.
+var benchmarkPrograms = []struct {
+	name string
+	src  func() string
+}{
+	{"replay", func() string {
+		var b strings.Builder
+		b.WriteString("create_parking_lot 100\n")
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(&b, "park KA-%02d-HH-%04d White\n", i%100, i)
+			if i >= 50 {
+				fmt.Fprintf(&b, "leave %d\n", (i*7)%100+1)
+			}
+		}
+		b.WriteString("status\n")
+		return b.String()
+	}},
+	{"loops", func() string {
+		return `
+create_parking_lot 100
+for i in 1..100 {
+	park "KA-01-HH-" + (1000 + $i) White
+}
+for i in 1..50 {
+	leave $i * 2
+}
+let n = 0
+while $n < 100 {
+	let n = $n + 1
+	if $n % 2 == 0 and $n > 10 {
+		leave $n
+		leave $n - 1
+	}
+}
+free_slots
+`
+	}},
+}
+
+func benchmarkExecutor(b *testing.B, run func(b *testing.B, e *Executor, program *ast.Program, db *database.Database)) {
+	for _, bm := range benchmarkPrograms {
+		program, err := parser.Parse(bm.src())
+		if err != nil {
+			b.Fatalf("parse %s error: %s", bm.name, err)
+		}
+
+		b.Run(bm.name, func(b *testing.B) {
+			db := database.NewDatabase(database.NewMemoryWriter())
+			e := &Executor{Stdout: io.Discard, Stderr: io.Discard}
+			run(b, e, program, db)
+		})
+	}
+}
+
+func BenchmarkExecute(b *testing.B) {
+	benchmarkExecutor(b, func(b *testing.B, e *Executor, program *ast.Program, db *database.Database) {
+		for i := 0; i < b.N; i++ {
+			e.Execute(program, db)
+		}
+	})
+}
+
+func BenchmarkRun(b *testing.B) {
+	benchmarkExecutor(b, func(b *testing.B, e *Executor, program *ast.Program, db *database.Database) {
+		code := Compile(program, db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			e.Run(code, db)
+		}
+	})
+}
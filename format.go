@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"parking_lot/diff"
+	"parking_lot/lot/format"
+)
+
+// runFmt runs fmt subcommand, which formats lot source files:
+//
+//	parking_lot fmt [-d] [-l] FILE...
+//
+// Files are rewritten unless -d or -l is given.
+func runFmt(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	showDiff := fs.Bool("d", false, "display diffs instead of rewriting files")
+	list := fs.Bool("l", false, "list files whose formatting differs")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: parking_lot fmt [-d] [-l] FILE...\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no source files")
+	}
+
+	failed := false
+	for _, file := range fs.Args() {
+		if err := formatFile(file, *showDiff, *list, stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("formatting failed")
+	}
+	return nil
+}
+
+// formatFile formats single file.
+func formatFile(file string, showDiff, list bool, stdout io.Writer) error {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	res, err := format.Source(file, src)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(src, res) {
+		return nil
+	}
+
+	if list {
+		fmt.Fprintln(stdout, file)
+	}
+	if showDiff {
+		stdout.Write(diff.Diff(file+".orig", src, file, res))
+	}
+	if list || showDiff {
+		return nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, res, info.Mode().Perm())
+}
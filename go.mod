@@ -1 +1,3 @@
 module parking_lot
+
+go 1.21
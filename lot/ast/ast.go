@@ -2,6 +2,8 @@ package ast
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"parking_lot/lot/token"
 )
@@ -9,6 +11,7 @@ import (
 // Node represents an AST node.
 type Node interface {
 	String() string
+	Pos() token.Pos // position of first character belonging to the node
 }
 
 // Statement represents a statement.
@@ -17,26 +20,162 @@ type Statement interface {
 	statementNode()
 }
 
+// Expr represents an expression.
+type Expr interface {
+	Node
+	exprNode()
+}
+
 // Program is a top-level AST node of a program.
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // comments of the file in source order
+}
+
+// Comment represents a single # comment.
+type Comment struct {
+	Hash token.Pos // position of "#"
+	Text string    // comment text including "#", without trailing white space
+}
+
+func (c *Comment) String() string {
+	return c.Text
+}
+
+// Pos returns position of the comment.
+func (c *Comment) Pos() token.Pos { return c.Hash }
+
+// IntLiteral represents an integer literal.
+type IntLiteral struct {
+	ValuePos token.Pos
+	Value    int
+}
+
+func (e *IntLiteral) String() string {
+	return strconv.Itoa(e.Value)
+}
+
+// StringLiteral represents a bare word (KA-01-HH-1234) or a quoted string.
+type StringLiteral struct {
+	ValuePos token.Pos
+	Value    string
+	Quoted   bool // written as quoted string, so it is printed quoted
+}
+
+func (e *StringLiteral) String() string {
+	if !e.Quoted && token.IsWord(e.Value) && !isClauseWord(e.Value) {
+		return e.Value
+	}
+	return strconv.Quote(e.Value)
+}
+
+// isClauseWord reports whether s starts a clause of find or count. Such
+// words are quoted, so they are not taken as a clause of preceding query.
+func isClauseWord(s string) bool {
+	for _, w := range []string{"where", "select", "order", "limit", "by"} {
+		if strings.EqualFold(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ident represents a variable reference ($name).
+type Ident struct {
+	NamePos token.Pos
+	Name    string
+}
+
+func (e *Ident) String() string {
+	return "$" + e.Name
+}
+
+// BinaryExpr represents a binary expression.
+type BinaryExpr struct {
+	X  Expr
+	Op token.Token
+	Y  Expr
+}
+
+func (e *BinaryExpr) String() string {
+	return fmt.Sprintf("%s %s %s", e.X, e.Op, e.Y)
+}
+
+// BoolLiteral represents true or false.
+type BoolLiteral struct {
+	ValuePos token.Pos
+	Value    bool
+}
+
+func (e *BoolLiteral) String() string {
+	if e.Value {
+		return token.TRUE.String()
+	}
+	return token.FALSE.String()
+}
+
+// UnaryExpr represents an unary expression.
+type UnaryExpr struct {
+	OpPos token.Pos
+	Op    token.Token
+	X     Expr
+}
+
+func (e *UnaryExpr) String() string {
+	return fmt.Sprintf("%s %s", e.Op, e.X)
+}
+
+// CallExpr represents a function call.
+type CallExpr struct {
+	NamePos token.Pos
+	Name    string
+	Args    []Expr
+}
+
+func (e *CallExpr) String() string {
+	args := make([]string, len(e.Args))
+	for i := range e.Args {
+		args[i] = e.Args[i].String()
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+}
+
+// QueryExpr represents a query statement used as an expression.
+type QueryExpr struct {
+	Query Statement
+}
+
+func (e *QueryExpr) String() string {
+	return e.Query.String()
+}
+
+// ParenExpr represents a parenthesized expression.
+type ParenExpr struct {
+	Lparen token.Pos
+	X      Expr
+}
+
+func (e *ParenExpr) String() string {
+	return fmt.Sprintf("(%s)", e.X)
 }
 
 // CreateParkingLotStatement represents a create parking lot statemant.
 type CreateParkingLotStatement struct {
 	Token  token.Token
-	Number int
+	TokPos token.Pos // position of Token
+	Number Expr
 }
 
 func (s *CreateParkingLotStatement) String() string {
-	return fmt.Sprintf("%s %d", s.Token, s.Number)
+	return fmt.Sprintf("%s %s", s.Token, s.Number)
 }
 
 // ParkStatement represents a park statemant.
 type ParkStatement struct {
 	Token              token.Token
-	RegistrationNumber string
-	Color              string
+	TokPos             token.Pos // position of Token
+	RegistrationNumber Expr
+	Color              Expr
 }
 
 func (s *ParkStatement) String() string {
@@ -46,16 +185,18 @@ func (s *ParkStatement) String() string {
 // LeaveStatement represents a leave statemant.
 type LeaveStatement struct {
 	Token  token.Token
-	Number int
+	TokPos token.Pos // position of Token
+	Number Expr
 }
 
 func (s *LeaveStatement) String() string {
-	return fmt.Sprintf("%s %d", s.Token, s.Number)
+	return fmt.Sprintf("%s %s", s.Token, s.Number)
 }
 
 // StatusStatement represents a status statement.
 type StatusStatement struct {
-	Token token.Token
+	Token  token.Token
+	TokPos token.Pos // position of Token
 }
 
 func (s *StatusStatement) String() string {
@@ -64,8 +205,9 @@ func (s *StatusStatement) String() string {
 
 // RegistrationNumbersForCarsWithColourStatement represents a registration statemant.
 type RegistrationNumbersForCarsWithColourStatement struct {
-	Token token.Token
-	Color string
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Color  Expr
 }
 
 func (s *RegistrationNumbersForCarsWithColourStatement) String() string {
@@ -74,8 +216,9 @@ func (s *RegistrationNumbersForCarsWithColourStatement) String() string {
 
 // SlotNumbersForCarsWithColourStatement represents a slot by color statement.
 type SlotNumbersForCarsWithColourStatement struct {
-	Token token.Token
-	Color string
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Color  Expr
 }
 
 func (s *SlotNumbersForCarsWithColourStatement) String() string {
@@ -85,13 +228,373 @@ func (s *SlotNumbersForCarsWithColourStatement) String() string {
 // SlotNumberForRegistrationNumberStatement represents a slot by number statement.
 type SlotNumberForRegistrationNumberStatement struct {
 	Token              token.Token
-	RegistrationNumber string
+	TokPos             token.Pos // position of Token
+	RegistrationNumber Expr
 }
 
 func (s *SlotNumberForRegistrationNumberStatement) String() string {
 	return fmt.Sprintf("%s %s", s.Token, s.RegistrationNumber)
 }
 
+// LetStatement represents a variable binding statement.
+type LetStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Name   string
+	Value  Expr
+}
+
+func (s *LetStatement) String() string {
+	return fmt.Sprintf("%s %s %s %s", s.Token, s.Name, token.ASSIGN, s.Value)
+}
+
+// BlockStatement represents a list of statements in braces.
+type BlockStatement struct {
+	Lbrace     token.Pos
+	Statements []Statement
+	Rbrace     token.Pos
+}
+
+func (s *BlockStatement) String() string {
+	if len(s.Statements) == 0 {
+		return "{}"
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, stmt := range s.Statements {
+		for _, line := range strings.Split(stmt.String(), "\n") {
+			b.WriteString("\t" + line + "\n")
+		}
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// IfStatement represents an if statement. Else is nil, *BlockStatement
+// or *IfStatement.
+type IfStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Cond   Expr
+	Body   *BlockStatement
+	Else   Statement
+}
+
+func (s *IfStatement) String() string {
+	if s.Else == nil {
+		return fmt.Sprintf("%s %s %s", s.Token, s.Cond, s.Body)
+	}
+	return fmt.Sprintf("%s %s %s %s %s", s.Token, s.Cond, s.Body, token.ELSE, s.Else)
+}
+
+// ForStatement represents a loop over inclusive integer range.
+type ForStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Var    string
+	From   Expr
+	To     Expr
+	Body   *BlockStatement
+}
+
+func (s *ForStatement) String() string {
+	return fmt.Sprintf("%s %s %s %s%s%s %s", s.Token, s.Var, token.IN, s.From, token.RANGE, s.To, s.Body)
+}
+
+// WhileStatement represents a while loop.
+type WhileStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Cond   Expr
+	Body   *BlockStatement
+}
+
+func (s *WhileStatement) String() string {
+	return fmt.Sprintf("%s %s %s", s.Token, s.Cond, s.Body)
+}
+
+// DefStatement represents a procedure definition.
+type DefStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Name   string
+	Params []string
+	Body   *BlockStatement
+}
+
+func (s *DefStatement) String() string {
+	return fmt.Sprintf("%s %s(%s) %s", s.Token, s.Name, strings.Join(s.Params, ", "), s.Body)
+}
+
+// CallStatement represents a procedure call used as a statement.
+type CallStatement struct {
+	Call *CallExpr
+}
+
+func (s *CallStatement) String() string {
+	return s.Call.String()
+}
+
+// ReturnStatement represents a return statement. Value is nil if the
+// procedure does not return a value.
+type ReturnStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	Value  Expr
+}
+
+func (s *ReturnStatement) String() string {
+	if s.Value == nil {
+		return s.Token.String()
+	}
+	return fmt.Sprintf("%s %s", s.Token, s.Value)
+}
+
+// IncludeStatement represents an include of other source file. Program
+// holds statements of included file.
+type IncludeStatement struct {
+	Token   token.Token
+	TokPos  token.Pos // position of Token
+	Path    string
+	Program *Program
+}
+
+func (s *IncludeStatement) String() string {
+	return fmt.Sprintf("%s %s", s.Token, strconv.Quote(s.Path))
+}
+
+// Fields of cars used in find statement.
+const (
+	FieldSlot         = "slot"
+	FieldRegistration = "registration"
+	FieldColour       = "colour"
+)
+
+// CarsWithRegistrationMatchingStatement represents a registration pattern
+// query statement.
+type CarsWithRegistrationMatchingStatement struct {
+	Token   token.Token
+	TokPos  token.Pos // position of Token
+	Pattern Expr
+}
+
+func (s *CarsWithRegistrationMatchingStatement) String() string {
+	return fmt.Sprintf("%s %s", s.Token, s.Pattern)
+}
+
+// FindStatement represents a generic query of parked cars:
+//
+//	find cars [where PREDICATE] [select FIELD, ...] [order by FIELD [asc|desc]] [limit EXPR]
+type FindStatement struct {
+	Token   token.Token
+	TokPos  token.Pos // position of Token
+	Where   Predicate // nil selects all cars
+	Select  []string  // selected fields, empty selects all fields
+	OrderBy string    // "" orders by slot
+	Desc    bool
+	Limit   Expr // nil if not limited
+}
+
+func (s *FindStatement) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s cars", s.Token)
+	if s.Where != nil {
+		fmt.Fprintf(&b, " where %s", s.Where)
+	}
+	if len(s.Select) > 0 {
+		fmt.Fprintf(&b, " select %s", strings.Join(s.Select, ", "))
+	}
+	if s.OrderBy != "" {
+		// direction is always printed, so a following asc or desc word
+		// is not taken as the direction
+		fmt.Fprintf(&b, " order by %s", s.OrderBy)
+		if s.Desc {
+			b.WriteString(" desc")
+		} else {
+			b.WriteString(" asc")
+		}
+	}
+	if s.Limit != nil {
+		fmt.Fprintf(&b, " limit %s", s.Limit)
+	}
+	return b.String()
+}
+
+// CountStatement represents the number of parked cars, optionally grouped
+// by a field:
+//
+//	count [by colour]
+type CountStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+	By     string    // "" if not grouped
+}
+
+func (s *CountStatement) String() string {
+	if s.By != "" {
+		return fmt.Sprintf("%s by %s", s.Token, s.By)
+	}
+	return s.Token.String()
+}
+
+// OccupancyStatement represents an occupancy statement.
+type OccupancyStatement struct {
+	Token  token.Token
+	TokPos token.Pos // position of Token
+}
+
+func (s *OccupancyStatement) String() string {
+	return s.Token.String()
+}
+
+// FreeSlotsStatement represents a free_slots statement. The free_slots is not
+// a keyword, so the free_slots() function stays available in expressions.
+type FreeSlotsStatement struct {
+	NamePos token.Pos
+}
+
+func (s *FreeSlotsStatement) String() string {
+	return FreeSlots
+}
+
+// FreeSlots is the name of free_slots statement.
+const FreeSlots = "free_slots"
+
+// CommandStatement represents a statement of registered command, see
+// package command. Name is the name of the command as registered.
+type CommandStatement struct {
+	NamePos token.Pos
+	Name    string
+	Args    []Expr
+}
+
+func (s *CommandStatement) String() string {
+	var b strings.Builder
+	b.WriteString(s.Name)
+	for _, arg := range s.Args {
+		b.WriteString(" ")
+		b.WriteString(arg.String())
+	}
+	return b.String()
+}
+
+// Predicate represents a condition of find statement.
+type Predicate interface {
+	Node
+	predicateNode()
+}
+
+// FieldPredicate compares car field with values. Op is one of EQL, NEQ,
+// TILDE (pattern match) or IN (Values holds all accepted values).
+type FieldPredicate struct {
+	FieldPos token.Pos
+	Field    string
+	Op       token.Token
+	Values   []Expr
+}
+
+func (p *FieldPredicate) String() string {
+	if p.Op != token.IN {
+		return fmt.Sprintf("%s %s %s", p.Field, p.Op, p.Values[0])
+	}
+
+	values := make([]string, len(p.Values))
+	for i := range p.Values {
+		values[i] = p.Values[i].String()
+	}
+	return fmt.Sprintf("%s %s (%s)", p.Field, p.Op, strings.Join(values, ", "))
+}
+
+// BinaryPredicate represents predicates joined with AND or OR.
+type BinaryPredicate struct {
+	X  Predicate
+	Op token.Token
+	Y  Predicate
+}
+
+func (p *BinaryPredicate) String() string {
+	return fmt.Sprintf("%s %s %s", p.X, p.Op, p.Y)
+}
+
+// NotPredicate represents negated predicate.
+type NotPredicate struct {
+	OpPos token.Pos
+	X     Predicate
+}
+
+func (p *NotPredicate) String() string {
+	return fmt.Sprintf("%s %s", token.NOT, p.X)
+}
+
+// ParenPredicate represents parenthesized predicate.
+type ParenPredicate struct {
+	Lparen token.Pos
+	X      Predicate
+}
+
+func (p *ParenPredicate) String() string {
+	return fmt.Sprintf("(%s)", p.X)
+}
+
+// Pos implementations for predicate nodes.
+func (p *FieldPredicate) Pos() token.Pos  { return p.FieldPos }
+func (p *BinaryPredicate) Pos() token.Pos { return p.X.Pos() }
+func (p *NotPredicate) Pos() token.Pos    { return p.OpPos }
+func (p *ParenPredicate) Pos() token.Pos  { return p.Lparen }
+
+// predicateNode() ensures that only predicate nodes can be assigned to a Predicate.
+func (*FieldPredicate) predicateNode()  {}
+func (*BinaryPredicate) predicateNode() {}
+func (*NotPredicate) predicateNode()    {}
+func (*ParenPredicate) predicateNode()  {}
+
+// Pos implementations for expression nodes.
+func (e *IntLiteral) Pos() token.Pos    { return e.ValuePos }
+func (e *StringLiteral) Pos() token.Pos { return e.ValuePos }
+func (e *BoolLiteral) Pos() token.Pos   { return e.ValuePos }
+func (e *Ident) Pos() token.Pos         { return e.NamePos }
+func (e *BinaryExpr) Pos() token.Pos    { return e.X.Pos() }
+func (e *UnaryExpr) Pos() token.Pos     { return e.OpPos }
+func (e *CallExpr) Pos() token.Pos      { return e.NamePos }
+func (e *ParenExpr) Pos() token.Pos     { return e.Lparen }
+func (e *QueryExpr) Pos() token.Pos     { return e.Query.Pos() }
+
+// Pos implementations for statement nodes.
+func (s *CreateParkingLotStatement) Pos() token.Pos                     { return s.TokPos }
+func (s *ParkStatement) Pos() token.Pos                                 { return s.TokPos }
+func (s *LeaveStatement) Pos() token.Pos                                { return s.TokPos }
+func (s *StatusStatement) Pos() token.Pos                               { return s.TokPos }
+func (s *RegistrationNumbersForCarsWithColourStatement) Pos() token.Pos { return s.TokPos }
+func (s *SlotNumbersForCarsWithColourStatement) Pos() token.Pos         { return s.TokPos }
+func (s *SlotNumberForRegistrationNumberStatement) Pos() token.Pos      { return s.TokPos }
+func (s *LetStatement) Pos() token.Pos                                  { return s.TokPos }
+func (s *BlockStatement) Pos() token.Pos                                { return s.Lbrace }
+func (s *IfStatement) Pos() token.Pos                                   { return s.TokPos }
+func (s *ForStatement) Pos() token.Pos                                  { return s.TokPos }
+func (s *WhileStatement) Pos() token.Pos                                { return s.TokPos }
+func (s *DefStatement) Pos() token.Pos                                  { return s.TokPos }
+func (s *CallStatement) Pos() token.Pos                                 { return s.Call.Pos() }
+func (s *ReturnStatement) Pos() token.Pos                               { return s.TokPos }
+func (s *IncludeStatement) Pos() token.Pos                              { return s.TokPos }
+func (s *FindStatement) Pos() token.Pos                                 { return s.TokPos }
+func (s *CarsWithRegistrationMatchingStatement) Pos() token.Pos         { return s.TokPos }
+func (s *CountStatement) Pos() token.Pos                                { return s.TokPos }
+func (s *OccupancyStatement) Pos() token.Pos                            { return s.TokPos }
+func (s *FreeSlotsStatement) Pos() token.Pos                            { return s.NamePos }
+func (s *CommandStatement) Pos() token.Pos                              { return s.NamePos }
+
+// exprNode() ensures that only expression nodes can be assigned to an Expr.
+func (*IntLiteral) exprNode()    {}
+func (*StringLiteral) exprNode() {}
+func (*Ident) exprNode()         {}
+func (*BinaryExpr) exprNode()    {}
+func (*ParenExpr) exprNode()     {}
+func (*BoolLiteral) exprNode()   {}
+func (*UnaryExpr) exprNode()     {}
+func (*CallExpr) exprNode()      {}
+func (*QueryExpr) exprNode()     {}
+
 // statementNode() ensures that only statement nodes can be assigned to a Statement.
 func (*CreateParkingLotStatement) statementNode()                     {}
 func (*ParkStatement) statementNode()                                 {}
@@ -100,3 +603,18 @@ func (*StatusStatement) statementNode()                               {}
 func (*RegistrationNumbersForCarsWithColourStatement) statementNode() {}
 func (*SlotNumbersForCarsWithColourStatement) statementNode()         {}
 func (*SlotNumberForRegistrationNumberStatement) statementNode()      {}
+func (*LetStatement) statementNode()                                  {}
+func (*BlockStatement) statementNode()                                {}
+func (*IfStatement) statementNode()                                   {}
+func (*ForStatement) statementNode()                                  {}
+func (*WhileStatement) statementNode()                                {}
+func (*DefStatement) statementNode()                                  {}
+func (*CallStatement) statementNode()                                 {}
+func (*ReturnStatement) statementNode()                               {}
+func (*IncludeStatement) statementNode()                              {}
+func (*FindStatement) statementNode()                                 {}
+func (*CarsWithRegistrationMatchingStatement) statementNode()         {}
+func (*CountStatement) statementNode()                                {}
+func (*OccupancyStatement) statementNode()                            {}
+func (*FreeSlotsStatement) statementNode()                            {}
+func (*CommandStatement) statementNode()                              {}
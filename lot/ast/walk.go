@@ -0,0 +1,144 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil). Statements of included files are children of the
+// include statement.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Expressions
+	case *IntLiteral, *StringLiteral, *BoolLiteral, *Ident:
+		// nothing to do
+	case *BinaryExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+	case *UnaryExpr:
+		Walk(v, n.X)
+	case *CallExpr:
+		walkExprList(v, n.Args)
+	case *ParenExpr:
+		Walk(v, n.X)
+	case *QueryExpr:
+		Walk(v, n.Query)
+
+	// Statements
+	case *CreateParkingLotStatement:
+		Walk(v, n.Number)
+	case *ParkStatement:
+		Walk(v, n.RegistrationNumber)
+		Walk(v, n.Color)
+	case *LeaveStatement:
+		Walk(v, n.Number)
+	case *StatusStatement, *CountStatement, *OccupancyStatement, *FreeSlotsStatement:
+		// nothing to do
+	case *RegistrationNumbersForCarsWithColourStatement:
+		Walk(v, n.Color)
+	case *SlotNumbersForCarsWithColourStatement:
+		Walk(v, n.Color)
+	case *SlotNumberForRegistrationNumberStatement:
+		Walk(v, n.RegistrationNumber)
+	case *CarsWithRegistrationMatchingStatement:
+		Walk(v, n.Pattern)
+	case *LetStatement:
+		Walk(v, n.Value)
+	case *BlockStatement:
+		walkStmtList(v, n.Statements)
+	case *IfStatement:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *ForStatement:
+		Walk(v, n.From)
+		Walk(v, n.To)
+		Walk(v, n.Body)
+	case *WhileStatement:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+	case *DefStatement:
+		Walk(v, n.Body)
+	case *CallStatement:
+		Walk(v, n.Call)
+	case *ReturnStatement:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *IncludeStatement:
+		if n.Program != nil {
+			walkStmtList(v, n.Program.Statements)
+		}
+	case *CommandStatement:
+		walkExprList(v, n.Args)
+	case *FindStatement:
+		if n.Where != nil {
+			Walk(v, n.Where)
+		}
+		if n.Limit != nil {
+			Walk(v, n.Limit)
+		}
+
+	// Predicates
+	case *FieldPredicate:
+		walkExprList(v, n.Values)
+	case *BinaryPredicate:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+	case *NotPredicate:
+		Walk(v, n.X)
+	case *ParenPredicate:
+		Walk(v, n.X)
+
+	case *Comment:
+		// nothing to do
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkExprList(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkStmtList(v Visitor, list []Statement) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
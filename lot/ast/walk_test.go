@@ -0,0 +1,59 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+
+	"parking_lot/lot/token"
+)
+
+func TestInspect(t *testing.T) {
+	// if $a == 1 { park KA-01-HH-1234 White } else { leave f(2) }
+	stmt := &IfStatement{
+		Token: token.IF,
+		Cond:  &BinaryExpr{X: &Ident{Name: "a"}, Op: token.EQL, Y: &IntLiteral{Value: 1}},
+		Body: &BlockStatement{Statements: []Statement{
+			&ParkStatement{
+				Token:              token.PARK,
+				RegistrationNumber: &StringLiteral{Value: "KA-01-HH-1234"},
+				Color:              &StringLiteral{Value: "White"},
+			},
+		}},
+		Else: &BlockStatement{Statements: []Statement{
+			&LeaveStatement{
+				Token:  token.LEAVE,
+				Number: &CallExpr{Name: "f", Args: []Expr{&IntLiteral{Value: 2}}},
+			},
+		}},
+	}
+
+	var got []string
+	Inspect(stmt, func(n Node) bool {
+		if n != nil {
+			got = append(got, reflect.TypeOf(n).Elem().Name())
+		}
+		return true
+	})
+
+	want := []string{
+		"IfStatement", "BinaryExpr", "Ident", "IntLiteral",
+		"BlockStatement", "ParkStatement", "StringLiteral", "StringLiteral",
+		"BlockStatement", "LeaveStatement", "CallExpr", "IntLiteral",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inspect order - want: %v, got: %v", want, got)
+	}
+
+	// children are skipped when f returns false
+	n := 0
+	Inspect(stmt, func(node Node) bool {
+		if node != nil {
+			n++
+		}
+		_, isBlock := node.(*BlockStatement)
+		return !isBlock
+	})
+	if n != 6 {
+		t.Errorf("inspect visited nodes - want: %d, got: %d", 6, n)
+	}
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"parking_lot/lot/ast"
+)
+
+// builtins are statements of the language. Their names are keywords,
+// except free_slots.
+var builtins = []*Command{
+	{Name: "create_parking_lot", Args: []Arg{{"CAPACITY", Int}}, Help: "Creates parking lot with given number of slots, existing cars are removed."},
+	{Name: "park", Args: []Arg{{"REGISTRATION", String}, {"COLOUR", String}}, Help: "Parks car in the nearest free slot."},
+	{Name: "leave", Args: []Arg{{"SLOT", Int}}, Help: "Frees given slot."},
+	{Name: "status", Help: "Prints slots with parked cars."},
+	{Name: "registration_numbers_for_cars_with_colour", Args: []Arg{{"COLOUR", String}}, Help: "Prints registration numbers of cars with given colour. As a value it is a list of strings."},
+	{Name: "slot_numbers_for_cars_with_colour", Args: []Arg{{"COLOUR", String}}, Help: "Prints slot numbers of cars with given colour. As a value it is a list of slot numbers."},
+	{Name: "slot_number_for_registration_number", Args: []Arg{{"REGISTRATION", String}}, Help: "Prints slot number of car with given registration number. As a value it fails when the car is not parked."},
+	{Name: "let", Usage: "let NAME = EXPR", Help: "Binds variable, `let` of a variable of an outer scope updates it."},
+	{Name: "if", Usage: "if EXPR { ... } else { ... }", Help: "Executes the first block when the condition is true, the `else` block otherwise."},
+	{Name: "for", Usage: "for NAME in EXPR..EXPR { ... }", Help: "Loops over an inclusive range of integers."},
+	{Name: "while", Usage: "while EXPR { ... }", Help: "Executes block while the condition is true."},
+	{Name: "def", Usage: "def NAME(PARAM, ...) { ... }", Help: "Defines procedure. Procedures see variables of the scope they are defined in."},
+	{Name: "return", Usage: "return [EXPR]", Help: "Returns from procedure, optionally with a value."},
+	{Name: "include", Usage: "include \"PATH\"", Help: "Executes statements of other file in place. Relative paths are resolved against the directory of the including file."},
+	{Name: "find", Usage: "find cars [where COND] [select FIELD, ...] [order by FIELD [asc|desc]] [limit INT]", Help: "Prints parked cars matching the condition. Fields are `slot`, `registration` and `colour`."},
+	{Name: "count", Usage: "count [by colour]", Help: "Prints number of parked cars, or a table of cars per colour."},
+	{Name: "occupancy", Help: "Prints number of occupied slots and the occupancy rate. As a value it is the occupied part in percents."},
+	{Name: "cars_with_registration_matching", Args: []Arg{{"PATTERN", String}}, Help: "Prints cars with registration number matching glob pattern (`*`, `?`, `[...]`) or region prefix (`KA-01`). As a value it is a list of slot numbers."},
+	{Name: ast.FreeSlots, Help: "Prints number of free slots."},
+}
+
+func init() {
+	for _, c := range builtins {
+		c.Builtin = true
+		register(c)
+	}
+}
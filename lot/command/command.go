@@ -0,0 +1,158 @@
+// Package command is the registry of commands, statements defined by
+// a name, arguments, help text and a handler:
+//
+//	command.Register(&command.Command{
+//		Name:    "open_barrier",
+//		Args:    []command.Arg{{Name: "GATE", Kind: command.Int}},
+//		Help:    "Opens barrier of the gate.",
+//		Handler: openBarrier,
+//	})
+//
+// The parser parses registered commands as statements, the executor runs
+// their handlers, the shell help and the language server list them, so
+// a plugin adds a statement by a single Register call, usually in init.
+//
+// Built-in statements of the language are registered too, so they are
+// listed with the commands, but the parser and the executor handle them
+// themselves.
+//
+// Command names are not keywords, like free_slots, so a name stays usable
+// as a word argument, e.g. a colour, and in expressions.
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// Kind is the kind of command argument.
+type Kind int
+
+// Kinds of arguments.
+const (
+	Int    Kind = iota // integer expression, e.g. slot number
+	String             // string expression, e.g. registration number
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Int:
+		return "int"
+	case String:
+		return "string"
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Arg is an argument of command.
+type Arg struct {
+	Name string // name in syntax, e.g. SLOT
+	Kind Kind
+}
+
+// Lot is the parking lot commands run on. The executor passes its
+// *database.Database, handlers needing more assert it.
+type Lot interface {
+	Count() (int, error)
+	FreeSlots() (int, error)
+	SlotNumbersByRegistrationPattern(pattern string) ([]int, error)
+}
+
+// Handler executes command on lot with evaluated arguments, int or string
+// by kind of the argument, and returns its result: int, string, bool, list
+// ([]interface{}) or nil when the command has no result.
+type Handler func(lot Lot, args []interface{}) (interface{}, error)
+
+// Command is a statement with arguments of fixed kinds.
+type Command struct {
+	Name    string
+	Args    []Arg
+	Help    string
+	Handler Handler
+
+	// Builtin reports whether the command is a built-in statement,
+	// parsed and executed without Handler.
+	Builtin bool
+
+	// Usage is the syntax of built-in statement which does not fit Name
+	// followed by Args, e.g. "for NAME in EXPR..EXPR { ... }".
+	Usage string
+}
+
+// Syntax returns the syntax of command, e.g. "leave SLOT".
+func (c *Command) Syntax() string {
+	if c.Usage != "" {
+		return c.Usage
+	}
+	s := c.Name
+	for _, arg := range c.Args {
+		s += " " + arg.Name
+	}
+	return s
+}
+
+var (
+	mu       sync.RWMutex
+	commands = make(map[string]*Command)
+)
+
+// Register registers command. Names are case-insensitive. It panics if
+// the name is not a word, is a keyword or free_slots, if the command is
+// registered twice or if it has no handler.
+func Register(c *Command) {
+	if !token.IsWord(c.Name) || strings.EqualFold(c.Name, ast.FreeSlots) {
+		panic(fmt.Sprintf("command: invalid name %q", c.Name))
+	}
+	if c.Handler == nil {
+		panic("command: Register handler is nil for " + c.Name)
+	}
+
+	if c.Builtin {
+		panic("command: Register of built-in statement " + c.Name)
+	}
+	register(c)
+}
+
+// register adds command to the registry. It panics if the command is
+// registered twice.
+func register(c *Command) {
+	mu.Lock()
+	defer mu.Unlock()
+	name := strings.ToLower(c.Name)
+	if _, dup := commands[name]; dup {
+		panic("command: Register called twice for " + name)
+	}
+	commands[name] = c
+}
+
+// Unregister removes command of the name. It is meant for tests.
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(commands, strings.ToLower(name))
+}
+
+// Lookup returns the command of the name, nil if it is not registered.
+func Lookup(name string) *Command {
+	mu.RLock()
+	defer mu.RUnlock()
+	return commands[strings.ToLower(name)]
+}
+
+// Commands returns registered commands, including built-in statements,
+// sorted by name.
+func Commands() []*Command {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]*Command, 0, len(commands))
+	for _, c := range commands {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+	return list
+}
@@ -0,0 +1,106 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+func nop(lot Lot, args []interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func TestRegister(t *testing.T) {
+	Register(&Command{Name: "open_barrier", Args: []Arg{{"GATE", Int}}, Help: "Opens barrier.", Handler: nop})
+	Register(&Command{Name: "Notify", Args: []Arg{{"REGISTRATION", String}, {"MESSAGE", String}}, Handler: nop})
+	defer Unregister("open_barrier")
+	defer Unregister("notify")
+
+	for _, name := range []string{"open_barrier", "OPEN_BARRIER", "notify"} {
+		if Lookup(name) == nil {
+			t.Errorf("command %s not found", name)
+		}
+	}
+	if c := Lookup("close_barrier"); c != nil {
+		t.Errorf("invalid lookup - want: nil, got: %v", c)
+	}
+
+	var syntax []string
+	for _, c := range Commands() {
+		if !c.Builtin {
+			syntax = append(syntax, c.Syntax())
+		}
+	}
+	if want := []string{"Notify REGISTRATION MESSAGE", "open_barrier GATE"}; !reflect.DeepEqual(syntax, want) {
+		t.Errorf("invalid commands - want: %q, got: %q", want, syntax)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	Register(&Command{Name: "open_barrier", Handler: nop})
+	defer Unregister("open_barrier")
+
+	tests := []struct {
+		c    *Command
+		want string
+	}{
+		{&Command{Name: "", Handler: nop}, `command: invalid name ""`},
+		{&Command{Name: "park", Handler: nop}, `command: invalid name "park"`},
+		{&Command{Name: "FREE_SLOTS", Handler: nop}, `command: invalid name "FREE_SLOTS"`},
+		{&Command{Name: "open-barrier(", Handler: nop}, `command: invalid name "open-barrier("`},
+		{&Command{Name: "close_barrier"}, "command: Register handler is nil for close_barrier"},
+		{&Command{Name: "close_barrier", Handler: nop, Builtin: true}, "command: Register of built-in statement close_barrier"},
+		{&Command{Name: "Open_Barrier", Handler: nop}, "command: Register called twice for open_barrier"},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != tt.want {
+					t.Errorf("register %q invalid panic - want: %s, got: %v", tt.c.Name, tt.want, r)
+				}
+			}()
+			Register(tt.c)
+		}()
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		name   string
+		syntax string
+	}{
+		{"create_parking_lot", "create_parking_lot CAPACITY"},
+		{"PARK", "park REGISTRATION COLOUR"},
+		{"status", "status"},
+		{"for", "for NAME in EXPR..EXPR { ... }"},
+		{"free_slots", "free_slots"},
+	}
+
+	for _, tt := range tests {
+		c := Lookup(tt.name)
+		if c == nil || !c.Builtin || c.Handler != nil {
+			t.Errorf("built-in statement %s - want: registered without handler, got: %+v", tt.name, c)
+			continue
+		}
+		if c.Syntax() != tt.syntax {
+			t.Errorf("invalid syntax of %s - want: %s, got: %s", tt.name, tt.syntax, c.Syntax())
+		}
+	}
+
+	for _, c := range Commands() {
+		if c.Builtin && token.Lookup(c.Name) == token.STRING && c.Name != ast.FreeSlots {
+			t.Errorf("built-in statement %s is not a keyword", c.Name)
+		}
+	}
+}
+
+func TestKindString(t *testing.T) {
+	for k, want := range map[Kind]string{Int: "int", String: "string", 5: "kind(5)"} {
+		if k.String() != want {
+			t.Errorf("invalid kind - want: %s, got: %s", want, k)
+		}
+	}
+}
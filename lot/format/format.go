@@ -0,0 +1,189 @@
+// Package format implements canonical formatting of LoT source code.
+//
+// Every statement is printed on its own line with keywords in lower case,
+// blocks are indented with tabs and sequences of blank lines are reduced
+// to a single one. Comments are kept in their place between statements or
+// behind them, quoted strings stay quoted.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"parking_lot/lot/ast"
+	"parking_lot/lot/parser"
+	"parking_lot/lot/token"
+)
+
+// Source formats src of the file with given name. Included files are
+// resolved relative to the file, they must exist, but are not formatted.
+func Source(filename string, src []byte) ([]byte, error) {
+	if src == nil {
+		src = []byte{} // do not let parser read the file
+	}
+
+	fset := token.NewFileSet()
+	program, err := parser.ParseFile(fset, filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := Node(&buf, fset, program); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Node writes canonical source of the program to w. The fset must be the
+// file set used to parse the program, so comments can be placed.
+func Node(w io.Writer, fset *token.FileSet, program *ast.Program) error {
+	p := &printer{fset: fset, comments: program.Comments}
+	p.statements(program.Statements, token.NoPos)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// printer holds the state of program printing.
+type printer struct {
+	fset     *token.FileSet
+	comments []*ast.Comment // comments not printed yet
+
+	buf    bytes.Buffer
+	indent int
+
+	// line is the source line of the end of the last printed item,
+	// zero at the beginning of file or block.
+	line int
+}
+
+// sourceLine returns source line of the position.
+func (p *printer) sourceLine(pos token.Pos) int {
+	return p.fset.Position(pos).Line
+}
+
+// writeLine writes indented line.
+func (p *printer) writeLine(s string) {
+	if s != "" {
+		p.buf.WriteString(strings.Repeat("\t", p.indent))
+	}
+	p.buf.WriteString(s)
+	p.buf.WriteByte('\n')
+}
+
+// separate writes blank line if the item starting at pos was separated
+// by blank lines in the source.
+func (p *printer) separate(pos token.Pos) {
+	line := p.sourceLine(pos)
+	if p.line > 0 && line > p.line+1 {
+		p.writeLine("")
+	}
+	p.line = line
+}
+
+// flushComments prints comments before pos on their own lines. All
+// comments are printed if pos is NoPos.
+func (p *printer) flushComments(pos token.Pos) {
+	for len(p.comments) > 0 && (pos == token.NoPos || p.comments[0].Pos() < pos) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.separate(c.Pos())
+		p.writeLine(c.Text)
+	}
+}
+
+// statements prints statements of a file or block ending at end.
+func (p *printer) statements(stmts []ast.Statement, end token.Pos) {
+	for i, stmt := range stmts {
+		next := end
+		if i+1 < len(stmts) {
+			next = stmts[i+1].Pos()
+		}
+
+		p.flushComments(stmt.Pos())
+		p.separate(stmt.Pos())
+		s := p.statement("", stmt)
+		p.line = p.endLine(stmt)
+
+		// comment on the last line of statement stays behind it
+		if len(p.comments) > 0 {
+			c := p.comments[0]
+			if (next == token.NoPos || c.Pos() < next) && p.sourceLine(c.Pos()) == p.line {
+				p.comments = p.comments[1:]
+				s += " " + c.Text
+			}
+		}
+		p.writeLine(s)
+	}
+	p.flushComments(end)
+}
+
+// statement prints statement after prefix, except the new line at its
+// end. Statements with blocks are printed directly, the returned string
+// is the rest of the last line.
+func (p *printer) statement(prefix string, stmt ast.Statement) string {
+	switch s := stmt.(type) {
+	case *ast.BlockStatement:
+		return p.block(prefix, s)
+	case *ast.IfStatement:
+		last := p.block(fmt.Sprintf("%s%s %s ", prefix, token.IF, s.Cond), s.Body)
+		if s.Else != nil {
+			return p.statement(fmt.Sprintf("%s %s ", last, token.ELSE), s.Else)
+		}
+		return last
+	case *ast.ForStatement:
+		return p.block(fmt.Sprintf("%s%s %s %s %s%s%s ", prefix, token.FOR, s.Var, token.IN, s.From, token.RANGE, s.To), s.Body)
+	case *ast.WhileStatement:
+		return p.block(fmt.Sprintf("%s%s %s ", prefix, token.WHILE, s.Cond), s.Body)
+	case *ast.DefStatement:
+		return p.block(fmt.Sprintf("%s%s %s(%s) ", prefix, token.DEF, s.Name, strings.Join(s.Params, ", ")), s.Body)
+	}
+	return prefix + stmt.String()
+}
+
+// block prints header followed by block. It returns the closing brace.
+func (p *printer) block(header string, b *ast.BlockStatement) string {
+	if len(b.Statements) == 0 && (len(p.comments) == 0 || p.comments[0].Pos() > b.Rbrace) {
+		return header + "{}"
+	}
+
+	// comment behind the opening brace stays on its line
+	header += "{"
+	first := b.Rbrace
+	if len(b.Statements) > 0 {
+		first = b.Statements[0].Pos()
+	}
+	if len(p.comments) > 0 && p.comments[0].Pos() < first && p.sourceLine(p.comments[0].Pos()) == p.sourceLine(b.Lbrace) {
+		header += " " + p.comments[0].Text
+		p.comments = p.comments[1:]
+	}
+	p.writeLine(header)
+	p.indent++
+	p.line = 0
+	p.statements(b.Statements, b.Rbrace)
+	p.indent--
+	return "}"
+}
+
+// endLine returns source line where the statement ends. Only the start of
+// statements without block is known, so they are assumed to be single line.
+func (p *printer) endLine(stmt ast.Statement) int {
+	switch s := stmt.(type) {
+	case *ast.BlockStatement:
+		return p.sourceLine(s.Rbrace)
+	case *ast.IfStatement:
+		if s.Else != nil {
+			return p.endLine(s.Else)
+		}
+		return p.sourceLine(s.Body.Rbrace)
+	case *ast.ForStatement:
+		return p.sourceLine(s.Body.Rbrace)
+	case *ast.WhileStatement:
+		return p.sourceLine(s.Body.Rbrace)
+	case *ast.DefStatement:
+		return p.sourceLine(s.Body.Rbrace)
+	}
+	return p.sourceLine(stmt.Pos())
+}
@@ -0,0 +1,65 @@
+package format
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		src string
+		out string
+	}{
+		{"", ""},
+		{"STATUS", "status\n"},
+		{"Create_Parking_Lot   6   Park KA-01-HH-1234 \"White\"\n", "create_parking_lot 6\npark KA-01-HH-1234 \"White\"\n"},
+		{"status\n\n\n\nstatus\n", "status\n\nstatus\n"},
+		{"# header\n\nstatus # trailing\n# footer", "# header\n\nstatus # trailing\n# footer\n"},
+		{"status status # second\n", "status\nstatus # second\n"},
+		{
+			"for i in 1 .. 3 {\n\n  # inside\n  leave $i\n\n  status }   # after\n",
+			"for i in 1..3 {\n\t# inside\n\tleave $i\n\n\tstatus\n} # after\n",
+		},
+		{
+			"if $a { status } ELSE IF $b {} else { # only comment\n}",
+			"if $a {\n\tstatus\n} else if $b {} else { # only comment\n}\n",
+		},
+		{
+			"def f(a,b) {\nwhile $a<$b { let a=$a+1 }\nreturn $a }",
+			"def f(a, b) {\n\twhile $a < $b {\n\t\tlet a = $a + 1\n\t}\n\treturn $a\n}\n",
+		},
+		{
+			"park \"KA-01-HH-\" + $n \"White\"\nlet reg = KA-01-HH- + $n",
+			"park \"KA-01-HH-\" + $n \"White\"\nlet reg = KA-01-HH- + $n\n",
+		},
+		{
+			"if $a { # check\n  status\n} else {   # other\nleave 1 }\nfor i in 1..2 { # empty\n}\nif $b { status # inside\n}",
+			"if $a { # check\n\tstatus\n} else { # other\n\tleave 1\n}\nfor i in 1..2 { # empty\n}\nif $b {\n\tstatus # inside\n}\n",
+		},
+		{
+			"find cars WHERE colour in (White,Red) Select slot Order By slot Desc Limit 1",
+			"find cars where colour in (White, Red) select slot order by slot desc limit 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := Source("", []byte(tt.src))
+		if err != nil {
+			t.Fatalf("format %q error: %s", tt.src, err)
+		}
+		if string(out) != tt.out {
+			t.Errorf("format %q - want: %q, got: %q", tt.src, tt.out, out)
+		}
+
+		again, err := Source("", out)
+		if err != nil {
+			t.Fatalf("format %q error: %s", out, err)
+		}
+		if string(again) != string(out) {
+			t.Errorf("format is not idempotent - want: %q, got: %q", out, again)
+		}
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	if _, err := Source("", []byte("park")); err == nil {
+		t.Errorf("format of invalid source expected error")
+	}
+}
@@ -0,0 +1,244 @@
+package grammar
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Generator generates random sentences of grammar. Sentences are lists of
+// tokens, each lexical production and each token of other productions is a
+// single token.
+type Generator struct {
+	Grammar Grammar
+	Rand    *rand.Rand
+
+	// MaxDepth limits nesting of productions. Deeper productions take the
+	// shortest alternatives and skip options and repetitions.
+	MaxDepth int
+
+	// MaxRepeat limits number of repetitions.
+	MaxRepeat int
+
+	// Terminals replace productions with one of the given tokens, e.g.
+	// identifiers which are not keywords.
+	Terminals map[string][]string
+
+	// Exclude holds names of productions which are never generated.
+	Exclude map[string]bool
+
+	depths map[string]int // minimal depth of productions
+}
+
+// NewGenerator returns generator of sentences of g seeded with seed.
+func NewGenerator(g Grammar, seed int64) *Generator {
+	return &Generator{Grammar: g, Rand: rand.New(rand.NewSource(seed)), MaxDepth: 12, MaxRepeat: 3}
+}
+
+// Generate returns random sentence of production start.
+func (g *Generator) Generate(start string) (tokens []string, err error) {
+	g.initDepths()
+	if g.depths[start] == math.MaxInt {
+		return nil, fmt.Errorf("production %s cannot be generated", start)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(generateError); ok {
+				tokens, err = nil, e
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	s := &sentence{}
+	g.production(s, start, 0)
+	return s.tokens, nil
+}
+
+// generateError is a panic of generator which is returned as an error.
+type generateError error
+
+// sentence is a generated sentence. Lexical productions are generated into
+// the current token.
+type sentence struct {
+	tokens  []string
+	lexical int // nesting of lexical productions
+	token   strings.Builder
+}
+
+func (s *sentence) write(tok string) {
+	if s.lexical > 0 {
+		s.token.WriteString(tok)
+		return
+	}
+	s.tokens = append(s.tokens, tok)
+}
+
+func (g *Generator) production(s *sentence, name string, depth int) {
+	if terminals := g.Terminals[name]; len(terminals) > 0 {
+		s.write(terminals[g.Rand.Intn(len(terminals))])
+		return
+	}
+
+	p := g.Grammar[name]
+	if p == nil {
+		panic(generateError(fmt.Errorf("undefined production %s", name)))
+	}
+	if !p.Lexical() || s.lexical > 0 {
+		g.expr(s, p.Expr, depth+1)
+		return
+	}
+
+	s.lexical++
+	g.expr(s, p.Expr, depth+1)
+	s.lexical--
+	s.write(s.token.String())
+	s.token.Reset()
+}
+
+func (g *Generator) expr(s *sentence, x Expression, depth int) {
+	switch x := x.(type) {
+	case nil:
+	case Name:
+		g.production(s, string(x), depth)
+	case Token:
+		s.write(string(x))
+	case *Range:
+		begin, end := []rune(string(x.Begin)), []rune(string(x.End))
+		if len(begin) != 1 || len(end) != 1 || begin[0] > end[0] {
+			panic(generateError(fmt.Errorf("invalid range %s", x)))
+		}
+		s.write(string(begin[0] + rune(g.Rand.Intn(int(end[0]-begin[0])+1))))
+	case Sequence:
+		for _, y := range x {
+			g.expr(s, y, depth)
+		}
+	case Alternative:
+		g.expr(s, g.choose(x, depth), depth)
+	case *Group:
+		g.expr(s, x.Body, depth)
+	case *Option:
+		if g.optional(x.Body, depth) && g.Rand.Intn(2) == 0 {
+			g.expr(s, x.Body, depth)
+		}
+	case *Repetition:
+		for i := 0; i < g.MaxRepeat && g.optional(x.Body, depth) && g.Rand.Intn(2) == 0; i++ {
+			g.expr(s, x.Body, depth)
+		}
+	default:
+		panic(generateError(fmt.Errorf("unknown expression %T", x)))
+	}
+}
+
+// choose returns random alternative. Below MaxDepth it chooses among the
+// shallowest alternatives.
+func (g *Generator) choose(list Alternative, depth int) Expression {
+	var choices []Expression
+	shallowest := math.MaxInt
+	for _, x := range list {
+		d := g.depth(x)
+		if d == math.MaxInt {
+			continue
+		}
+		if depth >= g.MaxDepth {
+			if d > shallowest {
+				continue
+			}
+			if d < shallowest {
+				shallowest, choices = d, nil
+			}
+		}
+		choices = append(choices, x)
+	}
+	if len(choices) == 0 {
+		panic(generateError(fmt.Errorf("no alternative of %s can be generated", list)))
+	}
+	return choices[g.Rand.Intn(len(choices))]
+}
+
+// optional reports whether optional expression x may be generated.
+func (g *Generator) optional(x Expression, depth int) bool {
+	return depth < g.MaxDepth && g.depth(x) != math.MaxInt
+}
+
+// initDepths computes minimal depths of productions.
+func (g *Generator) initDepths() {
+	g.depths = make(map[string]int, len(g.Grammar))
+	for name := range g.Grammar {
+		g.depths[name] = math.MaxInt
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, p := range g.Grammar {
+			d := 0
+			switch {
+			case g.Exclude[name]:
+				continue
+			case len(g.Terminals[name]) == 0:
+				if d = g.depth(p.Expr); d != math.MaxInt {
+					d++
+				}
+			}
+			if d < g.depths[name] {
+				g.depths[name] = d
+				changed = true
+			}
+		}
+	}
+}
+
+// depth returns minimal depth of productions of x, math.MaxInt if it cannot
+// be generated.
+func (g *Generator) depth(x Expression) int {
+	switch x := x.(type) {
+	case Name:
+		if d, ok := g.depths[string(x)]; ok {
+			return d
+		}
+		return math.MaxInt
+	case Sequence:
+		d := 0
+		for _, y := range x {
+			d = max(d, g.depth(y))
+		}
+		return d
+	case Alternative:
+		d := math.MaxInt
+		for _, y := range x {
+			d = min(d, g.depth(y))
+		}
+		return d
+	case *Group:
+		return g.depth(x.Body)
+	}
+	return 0
+}
+
+// Mutate returns a copy of tokens with a random token removed, duplicated
+// or replaced or with a random token of grammar inserted. Mutated sentences
+// are mostly invalid.
+func (g *Generator) Mutate(tokens []string) []string {
+	grammarTokens := g.Grammar.Tokens()
+	randomToken := func() string { return grammarTokens[g.Rand.Intn(len(grammarTokens))] }
+
+	out := append([]string{}, tokens...)
+	if len(out) == 0 {
+		return append(out, randomToken())
+	}
+
+	i := g.Rand.Intn(len(out))
+	switch g.Rand.Intn(4) {
+	case 0:
+		out = append(out[:i], out[i+1:]...)
+	case 1:
+		out = append(out[:i+1:i+1], out[i:]...)
+	case 2:
+		out[i] = randomToken()
+	default:
+		out = append(out[:i], append([]string{randomToken()}, out[i:]...)...)
+	}
+	return out
+}
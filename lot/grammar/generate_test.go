@@ -0,0 +1,106 @@
+package grammar
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	g, err := Parse(`
+List   = "(" { Item } ")" .
+Item   = List | number | Name .
+Name   = "x" | "y" .
+number = digit { digit } .
+digit  = "0" … "9" .
+`)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	for seed := int64(0); seed < 100; seed++ {
+		gen := NewGenerator(g, seed)
+		gen.MaxDepth = 6
+		tokens, err := gen.Generate("List")
+		if err != nil {
+			t.Fatalf("generate error: %s", err)
+		}
+
+		depth, maxDepth := 0, 0
+		for _, tok := range tokens {
+			switch {
+			case tok == "(":
+				depth++
+				maxDepth = max(maxDepth, depth)
+			case tok == ")":
+				depth--
+			case tok == "x" || tok == "y":
+			case strings.Trim(tok, "0123456789") != "" || tok == "":
+				t.Fatalf("seed %d invalid token - want: number, got: %q", seed, tok)
+			}
+		}
+		// a list nests two productions, at MaxDepth an empty list is as
+		// shallow as a name, so lists nest at most 4 times
+		if depth != 0 || maxDepth > 4 {
+			t.Errorf("seed %d invalid nesting - want: balanced at most 4, got: %d %d in %q", seed, depth, maxDepth, tokens)
+		}
+	}
+}
+
+func TestGenerateTerminalsAndExclude(t *testing.T) {
+	g, err := Parse(`
+List = "(" Item { Item } ")" .
+Item = List | Name | Bad .
+Name = "x" .
+Bad  = "!" .
+`)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	gen := NewGenerator(g, 1)
+	gen.Terminals = map[string][]string{"Name": {"a", "b"}}
+	gen.Exclude = map[string]bool{"Bad": true, "List": false}
+	for i := 0; i < 50; i++ {
+		tokens, err := gen.Generate("List")
+		if err != nil {
+			t.Fatalf("generate error: %s", err)
+		}
+		for _, tok := range tokens {
+			if tok == "x" || tok == "!" {
+				t.Fatalf("invalid token - want: one of ( ) a b, got: %q in %q", tok, tokens)
+			}
+		}
+	}
+
+	gen.Exclude = map[string]bool{"Name": true, "Bad": true}
+	if _, err := gen.Generate("List"); err == nil || err.Error() != "production List cannot be generated" {
+		t.Errorf("generate - want: production List cannot be generated, got: %v", err)
+	}
+}
+
+func TestMutate(t *testing.T) {
+	g, err := Parse(`A = "a" "b" "c" .`)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	gen := NewGenerator(g, 1)
+	tokens := []string{"a", "b", "c"}
+	mutated := 0
+	for i := 0; i < 50; i++ {
+		out := gen.Mutate(tokens)
+		if n := len(out) - len(tokens); n < -1 || n > 1 {
+			t.Fatalf("mutate - want: at most one token changed, got: %q", out)
+		}
+		if !reflect.DeepEqual(out, tokens) {
+			mutated++
+		}
+	}
+	if !reflect.DeepEqual(tokens, []string{"a", "b", "c"}) {
+		t.Errorf("mutate changed input - want: %q, got: %q", []string{"a", "b", "c"}, tokens)
+	}
+	if mutated == 0 {
+		t.Errorf("mutate - want: changed tokens, got: no change")
+	}
+}
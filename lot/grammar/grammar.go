@@ -0,0 +1,216 @@
+// Package grammar holds the EBNF grammar of LoT and generates random
+// programs from it.
+//
+// The grammar is written in the EBNF of the Go specification. Productions
+// with lower case names are lexical tokens.
+package grammar
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"parking_lot/errors"
+)
+
+// Source is the EBNF grammar of LoT.
+//
+//go:embed lot.ebnf
+var Source string
+
+// Start is the name of the start production of LoT grammar.
+const Start = "Program"
+
+// Grammar is a set of productions by name.
+type Grammar map[string]*Production
+
+// Production is a single production of grammar.
+type Production struct {
+	Name string
+	Expr Expression // nil for empty production
+	Line int
+}
+
+// Lexical reports whether production is a lexical token.
+func (p *Production) Lexical() bool {
+	return isLexical(p.Name)
+}
+
+func isLexical(name string) bool {
+	return name != "" && unicode.IsLower(rune(name[0]))
+}
+
+// Expression is an expression of production.
+type Expression interface {
+	String() string
+}
+
+type (
+	// Alternative is a list of alternative expressions: x | y | z.
+	Alternative []Expression
+
+	// Sequence is a list of expressions: x y z.
+	Sequence []Expression
+
+	// Name is a reference to production.
+	Name string
+
+	// Token is a literal token: "if".
+	Token string
+
+	// Range is a range of characters: "a" … "z".
+	Range struct {
+		Begin, End Token
+	}
+
+	// Group is a grouped expression: ( x ).
+	Group struct {
+		Body Expression
+	}
+
+	// Option is an optional expression: [ x ].
+	Option struct {
+		Body Expression
+	}
+
+	// Repetition is an expression repeated zero or more times: { x }.
+	Repetition struct {
+		Body Expression
+	}
+)
+
+func (x Alternative) String() string { return join(x, " | ") }
+func (x Sequence) String() string    { return join(x, " ") }
+func (x Name) String() string        { return string(x) }
+func (x Token) String() string       { return strconv.Quote(string(x)) }
+func (x *Range) String() string      { return fmt.Sprintf("%s … %s", x.Begin, x.End) }
+func (x *Group) String() string      { return fmt.Sprintf("( %s )", x.Body) }
+func (x *Option) String() string     { return fmt.Sprintf("[ %s ]", x.Body) }
+func (x *Repetition) String() string { return fmt.Sprintf("{ %s }", x.Body) }
+
+func join(list []Expression, sep string) string {
+	s := make([]string, len(list))
+	for i := range list {
+		s[i] = list[i].String()
+	}
+	return strings.Join(s, sep)
+}
+
+// LoT returns parsed and verified grammar of LoT.
+func LoT() Grammar {
+	g, err := Parse(Source)
+	if err == nil {
+		err = g.Verify(Start)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("invalid LoT grammar: %s", err))
+	}
+	return g
+}
+
+// Tokens returns literal tokens of non-lexical productions, these are the
+// keywords, operators and words of the language.
+func (g Grammar) Tokens() []string {
+	set := make(map[string]bool)
+	for _, p := range g {
+		if !p.Lexical() {
+			walk(p.Expr, func(x Expression) {
+				if tok, ok := x.(Token); ok {
+					set[string(tok)] = true
+				}
+			})
+		}
+	}
+
+	var tokens []string
+	for tok := range set {
+		tokens = append(tokens, tok)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// Verify checks that all productions used are defined, all productions are
+// reachable from start production and lexical productions use only lexical
+// productions. Errors are ordered by lines.
+func (g Grammar) Verify(start string) error {
+	if g[start] == nil {
+		return fmt.Errorf("undefined start production %s", start)
+	}
+
+	type lineError struct {
+		line int
+		err  error
+	}
+	var errs []lineError
+	errorf := func(line int, format string, args ...interface{}) {
+		errs = append(errs, lineError{line, fmt.Errorf("%d: "+format, append([]interface{}{line}, args...)...)})
+	}
+
+	reached := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		p := g[queue[0]]
+		queue = queue[1:]
+		walk(p.Expr, func(x Expression) {
+			name, ok := x.(Name)
+			if !ok {
+				return
+			}
+			if g[string(name)] == nil {
+				errorf(p.Line, "undefined production %s", name)
+				return
+			}
+			if p.Lexical() && !isLexical(string(name)) {
+				errorf(p.Line, "lexical production %s uses %s", p.Name, name)
+			}
+			if !reached[string(name)] {
+				reached[string(name)] = true
+				queue = append(queue, string(name))
+			}
+		})
+	}
+
+	for _, p := range g {
+		if !reached[p.Name] {
+			errorf(p.Line, "unreachable production %s", p.Name)
+		}
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].line < errs[j].line })
+	list := make([]error, len(errs))
+	for i := range errs {
+		list[i] = errs[i].err
+	}
+	return errors.Join(list)
+}
+
+// walk calls f for x and all its subexpressions.
+func walk(x Expression, f func(Expression)) {
+	if x == nil {
+		return
+	}
+	f(x)
+	switch x := x.(type) {
+	case Alternative:
+		for _, y := range x {
+			walk(y, f)
+		}
+	case Sequence:
+		for _, y := range x {
+			walk(y, f)
+		}
+	case *Range:
+		f(x.Begin)
+		f(x.End)
+	case *Group:
+		walk(x.Body, f)
+	case *Option:
+		walk(x.Body, f)
+	case *Repetition:
+		walk(x.Body, f)
+	}
+}
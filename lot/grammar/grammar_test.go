@@ -0,0 +1,96 @@
+package grammar
+
+import (
+	"testing"
+
+	"parking_lot/lot/token"
+)
+
+func TestParse(t *testing.T) {
+	const src = `// comment
+Program = { Statement } .
+Statement = "status" | "leave" int_lit [ "," ] .
+int_lit = ( "0" … "9" ) { "0" … "9" } .
+empty = .
+`
+
+	g, err := Parse(src)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		line int
+	}{
+		{"Program", `{ Statement }`, 2},
+		{"Statement", `"status" | "leave" int_lit [ "," ]`, 3},
+		{"int_lit", `( "0" … "9" ) { "0" … "9" }`, 4},
+	}
+	for _, tt := range tests {
+		p := g[tt.name]
+		if p == nil {
+			t.Errorf("production %s - want: defined, got: undefined", tt.name)
+			continue
+		}
+		if s := p.Expr.String(); s != tt.expr || p.Line != tt.line {
+			t.Errorf("production %s - want: %s at %d, got: %s at %d", tt.name, tt.expr, tt.line, s, p.Line)
+		}
+	}
+	if p := g["empty"]; p == nil || p.Expr != nil {
+		t.Errorf("empty production - want: nil expression, got: %v", p)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{`A = "a"`, `1: unexpected "", expecting "."`},
+		{"A = \"a .", `1: token "a . not terminated`},
+		{`A = "a" ; .`, `1: illegal character ';'`},
+		{"A = ( \"a\" .", `1: unexpected ".", expecting ")"`},
+		{"A = | \"a\" .", `1: unexpected "|", expecting expression`},
+		{`A = "" .`, `1: invalid token ""`},
+		{"A = \"a\" .\n\nA = \"b\" .", "3: production A redeclared, previous declaration at line 1"},
+		{`"a" = "b" .`, `1: unexpected "\"a\"", expecting production name`},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parse %q - want: %s, got: %v", tt.src, tt.err, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	g, err := Parse("A = B c .\nB = \"b\" .\nc = D .\nD = \"d\" | e .\nF = .")
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	want := "3: lexical production c uses D\n4: undefined production e\n5: unreachable production F"
+	if err := g.Verify("A"); err == nil || err.Error() != want {
+		t.Errorf("verify - want: %q, got: %v", want, err)
+	}
+	if err := g.Verify("X"); err == nil || err.Error() != "undefined start production X" {
+		t.Errorf("verify - want: undefined start production X, got: %v", err)
+	}
+}
+
+func TestLoT(t *testing.T) {
+	g := LoT()
+
+	tokens := make(map[string]bool)
+	for _, tok := range g.Tokens() {
+		tokens[tok] = true
+	}
+	for _, tok := range token.Keywords() {
+		if !tokens[tok.String()] {
+			t.Errorf("keyword %s - want: in grammar, got: missing", tok)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package grammar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser parses grammar source. It reads source by characters, grammar
+// tokens are names, quoted tokens and single character operators.
+type parser struct {
+	src  string
+	off  int
+	line int
+
+	// current token
+	tok     string // name, quoted token, operator or "" at the end of source
+	tokLine int
+	err     error
+}
+
+// Parse parses grammar in EBNF. Productions may be separated by comments
+// starting with "//".
+func Parse(src string) (Grammar, error) {
+	p := &parser{src: src, line: 1}
+	p.next()
+
+	g := make(Grammar)
+	for p.tok != "" && p.err == nil {
+		prod := p.parseProduction()
+		if p.err != nil {
+			break
+		}
+		if prev := g[prod.Name]; prev != nil {
+			return nil, fmt.Errorf("%d: production %s redeclared, previous declaration at line %d", prod.Line, prod.Name, prev.Line)
+		}
+		g[prod.Name] = prod
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return g, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%d: "+format, append([]interface{}{p.tokLine}, args...)...)
+	}
+}
+
+// next scans the next token.
+func (p *parser) next() {
+	p.skipSpace()
+	p.tokLine = p.line
+	if p.off >= len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	start := p.off
+	switch c := p.src[p.off]; {
+	case isNameChar(c):
+		for p.off < len(p.src) && isNameChar(p.src[p.off]) {
+			p.off++
+		}
+	case c == '"' || c == '`':
+		p.off++
+		for p.off < len(p.src) && p.src[p.off] != c && p.src[p.off] != '\n' {
+			if c == '"' && p.src[p.off] == '\\' {
+				p.off++
+			}
+			p.off++
+		}
+		if p.off >= len(p.src) || p.src[p.off] != c {
+			p.tok = p.src[start:p.off]
+			p.errorf("token %s not terminated", p.tok)
+			p.tok = ""
+			return
+		}
+		p.off++
+	case strings.HasPrefix(p.src[p.off:], "…"):
+		p.off += len("…")
+	case strings.IndexByte("=|.()[]{}", c) >= 0:
+		p.off++
+	default:
+		p.tok = p.src[start : p.off+1]
+		p.errorf("illegal character %q", c)
+		p.tok = ""
+		return
+	}
+	p.tok = p.src[start:p.off]
+}
+
+// skipSpace skips white space and comments.
+func (p *parser) skipSpace() {
+	for p.off < len(p.src) {
+		switch {
+		case p.src[p.off] == '\n':
+			p.line++
+			p.off++
+		case p.src[p.off] == ' ' || p.src[p.off] == '\t' || p.src[p.off] == '\r':
+			p.off++
+		case strings.HasPrefix(p.src[p.off:], "//"):
+			for p.off < len(p.src) && p.src[p.off] != '\n' {
+				p.off++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isNameChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+func (p *parser) expect(tok string) {
+	if p.tok != tok {
+		p.errorf("unexpected %q, expecting %q", p.tok, tok)
+		return
+	}
+	p.next()
+}
+
+func (p *parser) parseProduction() *Production {
+	prod := &Production{Name: p.tok, Line: p.tokLine}
+	if p.tok == "" || !isNameChar(p.tok[0]) {
+		p.errorf("unexpected %q, expecting production name", p.tok)
+		return nil
+	}
+	p.next()
+	p.expect("=")
+	if p.tok != "." {
+		prod.Expr = p.parseExpression()
+	}
+	p.expect(".")
+	return prod
+}
+
+func (p *parser) parseExpression() Expression {
+	var list Alternative
+	for {
+		list = append(list, p.parseSequence())
+		if p.tok != "|" || p.err != nil {
+			break
+		}
+		p.next()
+	}
+	if len(list) == 1 {
+		return list[0]
+	}
+	return list
+}
+
+func (p *parser) parseSequence() Expression {
+	var list Sequence
+	for p.err == nil {
+		x := p.parseTerm()
+		if x == nil {
+			break
+		}
+		list = append(list, x)
+	}
+	switch len(list) {
+	case 0:
+		p.errorf("unexpected %q, expecting expression", p.tok)
+		return nil
+	case 1:
+		return list[0]
+	}
+	return list
+}
+
+// parseTerm parses a term or returns nil if the current token does not
+// start one.
+func (p *parser) parseTerm() Expression {
+	switch tok := p.tok; {
+	case tok == "":
+		return nil
+	case isNameChar(tok[0]):
+		p.next()
+		return Name(tok)
+	case tok[0] == '"' || tok[0] == '`':
+		begin := p.parseToken()
+		if p.tok != "…" {
+			return begin
+		}
+		p.next()
+		return &Range{Begin: begin, End: p.parseToken()}
+	case tok == "(":
+		p.next()
+		x := &Group{Body: p.parseExpression()}
+		p.expect(")")
+		return x
+	case tok == "[":
+		p.next()
+		x := &Option{Body: p.parseExpression()}
+		p.expect("]")
+		return x
+	case tok == "{":
+		p.next()
+		x := &Repetition{Body: p.parseExpression()}
+		p.expect("}")
+		return x
+	}
+	return nil
+}
+
+func (p *parser) parseToken() Token {
+	s, err := strconv.Unquote(p.tok)
+	if err != nil || s == "" {
+		p.errorf("invalid token %s", p.tok)
+	}
+	p.next()
+	return Token(s)
+}
@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"parking_lot/lot/ast"
+	"parking_lot/lot/grammar"
+	"parking_lot/lot/token"
+)
+
+// newGenerator returns generator of LoT programs. Identifiers and words
+// are not keywords and included file exists in dir.
+func newGenerator(seed int64) *grammar.Generator {
+	g := grammar.NewGenerator(grammar.LoT(), seed)
+	g.Terminals = map[string][]string{
+		"identifier":  {"a", "n", "slot", "refill", "x_1", "Park_2"},
+		"word":        {"White", "KA-01-HH-1234", "KA-01-*", "x", "Ab?[c]"},
+		"IncludeStmt": {`include "lib.lot"`},
+	}
+	// and, or following find used as operand continue its condition
+	g.Exclude = map[string]bool{"QueryWhere": true}
+	return g
+}
+
+// semanticErrors are errors of programs which are valid by grammar, but
+// violate constraints not expressed by it.
+var semanticErrors = []string{"unexpected return outside procedure", "duplicate parameter"}
+
+func isSemanticError(err error) bool {
+	list, ok := err.(ErrorList)
+	if !ok {
+		return false
+	}
+	for _, e := range list {
+		semantic := false
+		for _, msg := range semanticErrors {
+			semantic = semantic || strings.HasPrefix(e.Msg, msg)
+		}
+		if !semantic {
+			return false
+		}
+	}
+	return true
+}
+
+// programString returns program printed with String of its statements.
+func programString(program *ast.Program) string {
+	lines := make([]string, len(program.Statements))
+	for i, stmt := range program.Statements {
+		lines[i] = stmt.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkProgram checks that tokens of nodes in filename match src and
+// program parsed from its String is printed the same.
+func checkProgram(t *testing.T, fset *token.FileSet, filename, src string, program *ast.Program) {
+	t.Helper()
+	for _, stmt := range program.Statements {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			tok, pos, ok := nodeToken(n)
+			if !ok {
+				return true
+			}
+			position := fset.Position(pos)
+			if position.Filename != filename {
+				return true
+			}
+			s := tok.String()
+			if end := position.Offset + len(s); end > len(src) || !strings.EqualFold(src[position.Offset:end], s) {
+				t.Errorf("%s: token of %T - want: %s, got: %.20q\nsource:\n%s", position, n, s, src[position.Offset:], src)
+			}
+			return true
+		})
+	}
+
+	printed := programString(program)
+	reparsed, err := ParseFile(token.NewFileSet(), filename, []byte(printed))
+	if err != nil {
+		t.Fatalf("parse of printed program error: %s\nsource:\n%s\nprinted:\n%s", err, src, printed)
+	}
+	if s := programString(reparsed); s != printed {
+		t.Errorf("round trip - want:\n%s\ngot:\n%s\nsource:\n%s", printed, s, src)
+	}
+}
+
+// nodeToken returns Token and TokPos fields of node n.
+func nodeToken(n ast.Node) (token.Token, token.Pos, bool) {
+	v := reflect.ValueOf(n)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return 0, 0, false
+	}
+	tok, pos := v.Elem().FieldByName("Token"), v.Elem().FieldByName("TokPos")
+	if !tok.IsValid() || !pos.IsValid() {
+		return 0, 0, false
+	}
+	return tok.Interface().(token.Token), pos.Interface().(token.Pos), true
+}
+
+func TestParserGrammar(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"lib.lot": "def lib() { status }\n"})
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "main.lot")
+
+	gen := newGenerator(1)
+	semantic := 0
+	const n = 2000
+	for i := 0; i < n; i++ {
+		tokens, err := gen.Generate(grammar.Start)
+		if err != nil {
+			t.Fatalf("generate error: %s", err)
+		}
+		src := strings.Join(tokens, " ")
+
+		fset := token.NewFileSet()
+		program, err := ParseFile(fset, filename, []byte(src))
+		if err != nil {
+			if isSemanticError(err) {
+				semantic++
+				continue
+			}
+			t.Fatalf("parse error: %s\nsource:\n%s", err, src)
+		}
+		checkProgram(t, fset, filename, src, program)
+	}
+	if semantic > n/2 {
+		t.Errorf("programs with semantic errors - want: at most %d, got: %d", n/2, semantic)
+	}
+}
+
+func TestParserGrammarInvalid(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"lib.lot": "status\n"})
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "main.lot")
+
+	gen := newGenerator(2)
+	failed := 0
+	const n = 2000
+	for i := 0; i < n; i++ {
+		tokens, err := gen.Generate(grammar.Start)
+		if err != nil {
+			t.Fatalf("generate error: %s", err)
+		}
+		src := strings.Join(gen.Mutate(tokens), " ")
+
+		fset := token.NewFileSet()
+		program, err := ParseFile(fset, filename, []byte(src))
+		if err != nil {
+			failed++
+			list, ok := err.(ErrorList)
+			if !ok || len(list) == 0 {
+				t.Fatalf("parse error - want: ErrorList, got: %#v", err)
+			}
+			for _, e := range list {
+				if e.Pos.Filename == filename && (!e.Pos.IsValid() || e.Pos.Offset > len(src)) {
+					t.Errorf("invalid position of error %s\nsource:\n%s", e, src)
+				}
+			}
+			continue
+		}
+		checkProgram(t, fset, filename, src, program)
+	}
+	if failed < n/4 {
+		t.Errorf("invalid programs - want: at least %d, got: %d", n/4, failed)
+	}
+}
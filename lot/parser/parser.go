@@ -2,43 +2,123 @@ package parser
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"parking_lot/errors"
 	"parking_lot/lot/ast"
+	"parking_lot/lot/command"
 	"parking_lot/lot/scanner"
 	"parking_lot/lot/token"
 )
 
 // The parser structure holds the parser's internal state.
 type parser struct {
+	fset    *token.FileSet
+	file    *token.File
 	scanner *scanner.Scanner
-	errors  []error
+	errors  ErrorList
+
+	comments []*ast.Comment
+
+	procDepth int      // nesting level of procedure definitions
+	queryArgs bool     // parse statement arguments as single operands
+	includes  []string // absolute paths of files being parsed, for cycle detection
 
 	// next token
-	pos int         // token position
+	pos token.Pos   // token position
 	tok token.Token // one token look-ahead
 	lit string      // token literal
 }
 
-// newParser returns a new parser.
-func newParser(src string) *parser {
-	p := &parser{scanner: scanner.New(src)}
+// newParser returns a new parser of src added to fset as file. The includes
+// are paths of files which include the parsed file.
+func newParser(fset *token.FileSet, file *token.File, src []byte, includes []string) *parser {
+	file.SetLinesForContent(src)
+
+	p := &parser{
+		fset:     fset,
+		file:     file,
+		scanner:  scanner.New(string(src)),
+		includes: includes,
+	}
 	p.next()
 	return p
 }
 
-// next advance to the next token.
+// next advance to the next token. Comments are collected, so they never
+// become the current token.
 func (p *parser) next() {
-	p.pos, p.tok, p.lit = p.scanner.Scan()
+	for {
+		var offset int
+		offset, p.tok, p.lit = p.scanner.Scan()
+		p.pos = p.file.Pos(offset)
+		if p.tok != token.COMMENT {
+			return
+		}
+		p.comments = append(p.comments, &ast.Comment{Hash: p.pos, Text: p.lit})
+	}
 }
 
-func (p *parser) expect(tok token.Token) bool {
-	if p.next(); p.tok != tok {
-		p.errors = append(p.errors, fmt.Errorf("unexpected token %q at pos %d, expecting %q", p.lit, p.pos, tok))
-		return false
+// Error is a parse error at a source position.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+// Error returns the error as "file:line:column: message".
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ErrorCode returns SYNTAX_ERROR.
+func (e *Error) ErrorCode() errors.Code {
+	return errors.SyntaxError
+}
+
+// Is reports whether target is SYNTAX_ERROR.
+func (e *Error) Is(target error) bool {
+	return target == errors.SyntaxError
+}
+
+// ErrorList is a list of parse errors, ParseFile returns it when parsing
+// fails.
+type ErrorList []*Error
+
+// Error returns errors of the list separated with new line.
+func (l ErrorList) Error() string {
+	errs := make([]string, len(l))
+	for i := range l {
+		errs[i] = l[i].Error()
 	}
-	return true
+	return strings.Join(errs, "\n")
+}
+
+// ErrorCode returns SYNTAX_ERROR.
+func (l ErrorList) ErrorCode() errors.Code {
+	return errors.SyntaxError
+}
+
+// Is reports whether target is SYNTAX_ERROR.
+func (l ErrorList) Is(target error) bool {
+	return target == errors.SyntaxError
+}
+
+func (p *parser) errorf(pos token.Pos, format string, args ...interface{}) {
+	p.errors = append(p.errors, &Error{Pos: p.fset.Position(pos), Msg: fmt.Sprintf(format, args...)})
+}
+
+// expect consumes the current token if it is tok and returns its literal.
+func (p *parser) expect(tok token.Token) (string, bool) {
+	if p.tok != tok {
+		p.errorf(p.pos, "unexpected token %q, expecting %q", p.lit, tok)
+		return "", false
+	}
+	lit := p.lit
+	p.next()
+	return lit, true
 }
 
 func (p *parser) parseStatement() ast.Statement {
@@ -71,123 +151,982 @@ func (p *parser) parseStatement() ast.Statement {
 		if stmt := p.parseSlotNumberForRegistrationNumber(); stmt != nil {
 			return stmt
 		}
+	case token.LET:
+		if stmt := p.parseLet(); stmt != nil {
+			return stmt
+		}
+	case token.IF:
+		if stmt := p.parseIf(); stmt != nil {
+			return stmt
+		}
+	case token.FOR:
+		if stmt := p.parseFor(); stmt != nil {
+			return stmt
+		}
+	case token.WHILE:
+		if stmt := p.parseWhile(); stmt != nil {
+			return stmt
+		}
+	case token.DEF:
+		if stmt := p.parseDef(); stmt != nil {
+			return stmt
+		}
+	case token.RETURN:
+		if stmt := p.parseReturn(); stmt != nil {
+			return stmt
+		}
+	case token.STRING:
+		if p.lit == ast.FreeSlots {
+			if stmt := p.parseFreeSlots(); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
+		if cmd := command.Lookup(p.lit); cmd != nil && !cmd.Builtin {
+			if stmt := p.parseCommand(cmd); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
+		if stmt := p.parseCallStatement(); stmt != nil {
+			return stmt
+		}
+	case token.INCLUDE:
+		if stmt := p.parseInclude(); stmt != nil {
+			return stmt
+		}
+	case token.FIND:
+		if stmt := p.parseFind(); stmt != nil {
+			return stmt
+		}
+	case token.COUNT:
+		if stmt := p.parseCount(); stmt != nil {
+			return stmt
+		}
+	case token.OCCUPANCY:
+		if stmt := p.parseOccupancy(); stmt != nil {
+			return stmt
+		}
+	case token.CARS_WITH_REGISTRATION_MATCHING:
+		if stmt := p.parseCarsWithRegistrationMatching(); stmt != nil {
+			return stmt
+		}
 	default:
-		p.errors = append(p.errors, fmt.Errorf("unexpected token %q at pos %d", p.lit, p.pos))
+		p.errorf(p.pos, "unexpected token %q", p.lit)
 		return nil
 	}
 	return nil
 }
 
 func (p *parser) parseCreateParkingLot() *ast.CreateParkingLotStatement {
-	if !p.expect(token.INT) {
-		return nil
-	}
-
-	n, err := strconv.ParseInt(p.lit, 10, 64)
-	if err != nil {
-		p.errors = append(p.errors, fmt.Errorf("invalid number %q at pos %d", p.lit, p.pos))
+	pos := p.pos
+	p.next()
+	n := p.parseIntArg()
+	if n == nil {
 		return nil
 	}
 
 	return &ast.CreateParkingLotStatement{
 		Token:  token.CREATE_PARKING_LOT,
-		Number: int(n),
+		TokPos: pos,
+		Number: n,
 	}
 }
 
 func (p *parser) parsePark() *ast.ParkStatement {
-	if !p.expect(token.STRING) {
+	pos := p.pos
+	p.next()
+	registrationNumber := p.parseStringArg()
+	if registrationNumber == nil {
 		return nil
 	}
-	registrationNumber := p.lit
 
-	if !p.expect(token.STRING) {
+	color := p.parseStringArg()
+	if color == nil {
 		return nil
 	}
-	color := p.lit
 
 	return &ast.ParkStatement{
 		Token:              token.PARK,
+		TokPos:             pos,
 		RegistrationNumber: registrationNumber,
 		Color:              color,
 	}
 }
 
 func (p *parser) parseLeave() *ast.LeaveStatement {
-	if !p.expect(token.INT) {
-		return nil
-	}
-
-	n, err := strconv.ParseInt(p.lit, 10, 64)
-	if err != nil {
-		p.errors = append(p.errors, fmt.Errorf("invalid number %q at pos %d", p.lit, p.pos))
+	pos := p.pos
+	p.next()
+	n := p.parseIntArg()
+	if n == nil {
 		return nil
 	}
 
 	return &ast.LeaveStatement{
-		Token:  token.CREATE_PARKING_LOT,
-		Number: int(n),
+		Token:  token.LEAVE,
+		TokPos: pos,
+		Number: n,
 	}
 }
 
 func (p *parser) parseStatus() *ast.StatusStatement {
-	return &ast.StatusStatement{Token: token.STATUS}
+	pos := p.pos
+	p.next()
+	return &ast.StatusStatement{Token: token.STATUS, TokPos: pos}
 }
 
 func (p *parser) parseRegistrationNumbersForCarsWithColour() *ast.RegistrationNumbersForCarsWithColourStatement {
-	if !p.expect(token.STRING) {
+	pos := p.pos
+	p.next()
+	color := p.parseStringArg()
+	if color == nil {
 		return nil
 	}
-	color := p.lit
 
 	return &ast.RegistrationNumbersForCarsWithColourStatement{
-		Token: token.PARK,
-		Color: color,
+		Token:  token.REGISTRATION_NUMBERS_FOR_CARS_WITH_COLOUR,
+		TokPos: pos,
+		Color:  color,
 	}
 }
 
 func (p *parser) parseSlotNumbersForarsWithColour() *ast.SlotNumbersForCarsWithColourStatement {
-	if !p.expect(token.STRING) {
+	pos := p.pos
+	p.next()
+	color := p.parseStringArg()
+	if color == nil {
 		return nil
 	}
-	color := p.lit
 
 	return &ast.SlotNumbersForCarsWithColourStatement{
-		Token: token.PARK,
-		Color: color,
+		Token:  token.SLOT_NUMBERS_FOR_CARS_WITH_COLOUR,
+		TokPos: pos,
+		Color:  color,
 	}
 }
 
 func (p *parser) parseSlotNumberForRegistrationNumber() *ast.SlotNumberForRegistrationNumberStatement {
-	if !p.expect(token.STRING) {
+	pos := p.pos
+	p.next()
+	registrationNumber := p.parseStringArg()
+	if registrationNumber == nil {
 		return nil
 	}
-	registrationNumber := p.lit
 
 	return &ast.SlotNumberForRegistrationNumberStatement{
-		Token:              token.PARK,
+		Token:              token.SLOT_NUMBER_FOR_REGISTRATION_NUMBER,
+		TokPos:             pos,
 		RegistrationNumber: registrationNumber,
 	}
 }
 
-// Parse parses the lot source code and returns a new Program AST node.
-func Parse(src string) (*ast.Program, error) {
+func (p *parser) parseCarsWithRegistrationMatching() *ast.CarsWithRegistrationMatchingStatement {
+	pos := p.pos
+	p.next()
+	pattern := p.parseStringArg()
+	if pattern == nil {
+		return nil
+	}
+
+	return &ast.CarsWithRegistrationMatchingStatement{
+		Token:   token.CARS_WITH_REGISTRATION_MATCHING,
+		TokPos:  pos,
+		Pattern: pattern,
+	}
+}
+
+func (p *parser) parseLet() *ast.LetStatement {
+	pos := p.pos
+	p.next()
+	namePos := p.pos
+	name, ok := p.expect(token.STRING)
+	if !ok {
+		return nil
+	}
+	if !isIdentifier(name) {
+		p.errorf(namePos, "invalid variable name %q", name)
+		return nil
+	}
+
+	if _, ok := p.expect(token.ASSIGN); !ok {
+		return nil
+	}
+
+	value := p.parseExpr()
+	if value == nil {
+		return nil
+	}
+
+	return &ast.LetStatement{
+		Token:  token.LET,
+		TokPos: pos,
+		Name:   name,
+		Value:  value,
+	}
+}
+
+func (p *parser) parseBlock() *ast.BlockStatement {
+	lbrace := p.pos
+	if _, ok := p.expect(token.LBRACE); !ok {
+		return nil
+	}
+
+	block := &ast.BlockStatement{Lbrace: lbrace, Statements: []ast.Statement{}}
+	for p.tok != token.RBRACE && p.tok != token.EOF {
+		stmt := p.parseStatement()
+		if stmt == nil {
+			return nil
+		}
+		block.Statements = append(block.Statements, stmt)
+	}
+
+	block.Rbrace = p.pos
+	if _, ok := p.expect(token.RBRACE); !ok {
+		return nil
+	}
+	return block
+}
+
+func (p *parser) parseIf() *ast.IfStatement {
+	pos := p.pos
+	p.next()
+	cond := p.parseExpr()
+	if cond == nil {
+		return nil
+	}
+
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+
+	stmt := &ast.IfStatement{
+		Token:  token.IF,
+		TokPos: pos,
+		Cond:   cond,
+		Body:   body,
+	}
+
+	if p.tok != token.ELSE {
+		return stmt
+	}
+	p.next()
+
+	if p.tok == token.IF {
+		elseIf := p.parseIf()
+		if elseIf == nil {
+			return nil
+		}
+		stmt.Else = elseIf
+	} else {
+		elseBlock := p.parseBlock()
+		if elseBlock == nil {
+			return nil
+		}
+		stmt.Else = elseBlock
+	}
+	return stmt
+}
+
+func (p *parser) parseFor() *ast.ForStatement {
+	pos := p.pos
+	p.next()
+	namePos := p.pos
+	name, ok := p.expect(token.STRING)
+	if !ok {
+		return nil
+	}
+	if !isIdentifier(name) {
+		p.errorf(namePos, "invalid variable name %q", name)
+		return nil
+	}
+
+	if _, ok := p.expect(token.IN); !ok {
+		return nil
+	}
+
+	from := p.parseIntArg()
+	if from == nil {
+		return nil
+	}
+
+	if _, ok := p.expect(token.RANGE); !ok {
+		return nil
+	}
+
+	to := p.parseIntArg()
+	if to == nil {
+		return nil
+	}
+
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+
+	return &ast.ForStatement{
+		Token:  token.FOR,
+		TokPos: pos,
+		Var:    name,
+		From:   from,
+		To:     to,
+		Body:   body,
+	}
+}
+
+func (p *parser) parseWhile() *ast.WhileStatement {
+	pos := p.pos
+	p.next()
+	cond := p.parseExpr()
+	if cond == nil {
+		return nil
+	}
+
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+
+	return &ast.WhileStatement{
+		Token:  token.WHILE,
+		TokPos: pos,
+		Cond:   cond,
+		Body:   body,
+	}
+}
+
+func (p *parser) parseDef() *ast.DefStatement {
+	pos := p.pos
+	p.next()
+	namePos := p.pos
+	name, ok := p.expect(token.STRING)
+	if !ok {
+		return nil
+	}
+	if !isIdentifier(name) {
+		p.errorf(namePos, "invalid procedure name %q", name)
+		return nil
+	}
+
+	if _, ok := p.expect(token.LPAREN); !ok {
+		return nil
+	}
+
+	params := []string{}
+	for p.tok != token.RPAREN {
+		if len(params) > 0 {
+			if _, ok := p.expect(token.COMMA); !ok {
+				return nil
+			}
+		}
+
+		paramPos := p.pos
+		param, ok := p.expect(token.STRING)
+		if !ok {
+			return nil
+		}
+		if !isIdentifier(param) {
+			p.errorf(paramPos, "invalid parameter name %q", param)
+			return nil
+		}
+		for i := range params {
+			if params[i] == param {
+				p.errorf(paramPos, "duplicate parameter %q", param)
+				return nil
+			}
+		}
+		params = append(params, param)
+	}
+	p.next()
+
+	p.procDepth++
+	body := p.parseBlock()
+	p.procDepth--
+	if body == nil {
+		return nil
+	}
+
+	return &ast.DefStatement{
+		Token:  token.DEF,
+		TokPos: pos,
+		Name:   name,
+		Params: params,
+		Body:   body,
+	}
+}
+
+func (p *parser) parseReturn() *ast.ReturnStatement {
+	if p.procDepth == 0 {
+		p.errorf(p.pos, "unexpected return outside procedure")
+		return nil
+	}
+	stmt := &ast.ReturnStatement{Token: token.RETURN, TokPos: p.pos}
+	p.next()
+
+	if isOperand(p.tok) {
+		if stmt.Value = p.parseExpr(); stmt.Value == nil {
+			return nil
+		}
+	}
+	return stmt
+}
+
+func (p *parser) parseCallStatement() *ast.CallStatement {
+	pos, lit := p.pos, p.lit
+	p.next()
+	if p.tok != token.LPAREN || p.pos != pos+token.Pos(len(lit)) {
+		p.errorf(pos, "unexpected token %q", lit)
+		return nil
+	}
+
+	call := p.parseCall(lit)
+	if call == nil {
+		return nil
+	}
+	return &ast.CallStatement{Call: call}
+}
+
+func (p *parser) parseInclude() *ast.IncludeStatement {
+	pos := p.pos
+	p.next()
+
+	pathPos, lit := p.pos, p.lit
+	if _, ok := p.expect(token.QUOTED); !ok {
+		return nil
+	}
+	path, err := strconv.Unquote(lit)
+	if err != nil {
+		p.errorf(pathPos, "invalid string %s", lit)
+		return nil
+	}
+
+	program := p.parseIncludedFile(pos, path)
+	if program == nil {
+		return nil
+	}
+
+	return &ast.IncludeStatement{
+		Token:   token.INCLUDE,
+		TokPos:  pos,
+		Path:    path,
+		Program: program,
+	}
+}
+
+// parseIncludedFile reads and parses the file included at pos. Relative
+// paths are resolved against the directory of the including file.
+func (p *parser) parseIncludedFile(pos token.Pos, path string) *ast.Program {
+	filename := path
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(filepath.Dir(p.file.Name()), filename)
+	}
+
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		p.errorf(pos, "include %q: %s", path, err)
+		return nil
+	}
+	for i := range p.includes {
+		if p.includes[i] == abs {
+			cycle := append(append([]string{}, p.includes[i:]...), abs)
+			p.errorf(pos, "include cycle: %s", strings.Join(cycle, " -> "))
+			return nil
+		}
+	}
+
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		p.errorf(pos, "include %q: %s", path, err)
+		return nil
+	}
+
+	includes := append(p.includes[:len(p.includes):len(p.includes)], abs)
+	program, errs := parse(p.fset, filename, src, includes)
+	if len(errs) > 0 {
+		p.errors = append(p.errors, errs...)
+		return nil
+	}
+	return program
+}
+
+func (p *parser) parseFind() *ast.FindStatement {
+	stmt := &ast.FindStatement{Token: token.FIND, TokPos: p.pos}
+	p.next()
+	if !p.expectWord("cars") {
+		return nil
+	}
+
+	// clauses are optional, but must be given in order
+	if p.isWord("where") {
+		p.next()
+		if stmt.Where = p.parsePredicate(); stmt.Where == nil {
+			return nil
+		}
+	}
+
+	if p.isWord("select") {
+		p.next()
+		for {
+			field := p.parseField(ast.FieldSlot, ast.FieldRegistration, ast.FieldColour)
+			if field == "" {
+				return nil
+			}
+			stmt.Select = append(stmt.Select, field)
+			if p.tok != token.COMMA {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if p.isWord("order") {
+		p.next()
+		if !p.expectWord("by") {
+			return nil
+		}
+		if stmt.OrderBy = p.parseField(ast.FieldSlot, ast.FieldRegistration, ast.FieldColour); stmt.OrderBy == "" {
+			return nil
+		}
+		if p.isWord("asc") {
+			p.next()
+		} else if p.isWord("desc") {
+			p.next()
+			stmt.Desc = true
+		}
+	}
+
+	if p.isWord("limit") {
+		p.next()
+		if stmt.Limit = p.parseIntArg(); stmt.Limit == nil {
+			return nil
+		}
+	}
+	return stmt
+}
+
+func (p *parser) parseCount() *ast.CountStatement {
+	stmt := &ast.CountStatement{Token: token.COUNT, TokPos: p.pos}
+	p.next()
+	if p.isWord("by") {
+		p.next()
+		if stmt.By = p.parseField(ast.FieldColour); stmt.By == "" {
+			return nil
+		}
+	}
+	return stmt
+}
+
+func (p *parser) parseOccupancy() *ast.OccupancyStatement {
+	pos := p.pos
+	p.next()
+	return &ast.OccupancyStatement{Token: token.OCCUPANCY, TokPos: pos}
+}
+
+// parseFreeSlots parses free_slots statement. The free_slots() call is
+// accepted too, so the function can be used as a statement.
+func (p *parser) parseFreeSlots() *ast.FreeSlotsStatement {
+	pos := p.pos
+	p.next()
+	if p.tok == token.LPAREN && p.pos == pos+token.Pos(len(ast.FreeSlots)) {
+		p.next()
+		if _, ok := p.expect(token.RPAREN); !ok {
+			return nil
+		}
+	}
+	return &ast.FreeSlotsStatement{NamePos: pos}
+}
+
+// parseCommand parses statement of registered command, arguments are
+// checked by their kinds as arguments of built-in statements.
+func (p *parser) parseCommand(cmd *command.Command) *ast.CommandStatement {
+	stmt := &ast.CommandStatement{NamePos: p.pos, Name: cmd.Name}
+	p.next()
+	for _, arg := range cmd.Args {
+		var x ast.Expr
+		if arg.Kind == command.Int {
+			x = p.parseIntArg()
+		} else {
+			x = p.parseStringArg()
+		}
+		if x == nil {
+			return nil
+		}
+		stmt.Args = append(stmt.Args, x)
+	}
+	return stmt
+}
+
+// parsePredicate parses predicates joined with or.
+func (p *parser) parsePredicate() ast.Predicate {
+	x := p.parseAndPredicate()
+	for x != nil && p.tok == token.OR {
+		p.next()
+		y := p.parseAndPredicate()
+		if y == nil {
+			return nil
+		}
+		x = &ast.BinaryPredicate{X: x, Op: token.OR, Y: y}
+	}
+	return x
+}
+
+// parseAndPredicate parses predicates joined with and.
+func (p *parser) parseAndPredicate() ast.Predicate {
+	x := p.parseUnaryPredicate()
+	for x != nil && p.tok == token.AND {
+		p.next()
+		y := p.parseUnaryPredicate()
+		if y == nil {
+			return nil
+		}
+		x = &ast.BinaryPredicate{X: x, Op: token.AND, Y: y}
+	}
+	return x
+}
+
+func (p *parser) parseUnaryPredicate() ast.Predicate {
+	pos := p.pos
+	switch p.tok {
+	case token.NOT:
+		p.next()
+		x := p.parseUnaryPredicate()
+		if x == nil {
+			return nil
+		}
+		return &ast.NotPredicate{OpPos: pos, X: x}
+	case token.LPAREN:
+		p.next()
+		x := p.parsePredicate()
+		if x == nil {
+			return nil
+		}
+		if _, ok := p.expect(token.RPAREN); !ok {
+			return nil
+		}
+		return &ast.ParenPredicate{Lparen: pos, X: x}
+	}
+
+	field := p.parseField(ast.FieldRegistration, ast.FieldColour)
+	if field == "" {
+		return nil
+	}
+	pred := &ast.FieldPredicate{FieldPos: pos, Field: field, Op: p.tok}
+
+	switch p.tok {
+	case token.EQL, token.NEQ, token.TILDE:
+		p.next()
+		x := p.parseOperand()
+		if x == nil {
+			return nil
+		}
+		pred.Values = []ast.Expr{x}
+	case token.IN:
+		p.next()
+		if _, ok := p.expect(token.LPAREN); !ok {
+			return nil
+		}
+		for {
+			x := p.parseOperand()
+			if x == nil {
+				return nil
+			}
+			pred.Values = append(pred.Values, x)
+			if p.tok != token.COMMA {
+				break
+			}
+			p.next()
+		}
+		if _, ok := p.expect(token.RPAREN); !ok {
+			return nil
+		}
+	default:
+		p.errorf(p.pos, "unexpected token %q, expecting comparison", p.lit)
+		return nil
+	}
+	return pred
+}
+
+// parseField parses one of the given car fields. It returns "" on error.
+func (p *parser) parseField(fields ...string) string {
+	for _, field := range fields {
+		if p.isWord(field) {
+			p.next()
+			return field
+		}
+	}
+	p.errorf(p.pos, "unexpected token %q, expecting one of %s", p.lit, strings.Join(fields, ", "))
+	return ""
+}
+
+// isWord reports whether the current token is the bare word w, ignoring
+// case like keywords. Words of find statement are not keywords, so they
+// stay valid names elsewhere.
+func (p *parser) isWord(w string) bool {
+	return p.tok == token.STRING && strings.EqualFold(p.lit, w)
+}
+
+func (p *parser) expectWord(w string) bool {
+	if !p.isWord(w) {
+		p.errorf(p.pos, "unexpected token %q, expecting %q", p.lit, w)
+		return false
+	}
+	p.next()
+	return true
+}
+
+// parseIntArg parses an integer statement argument. Expressions are
+// checked at runtime, but literals of another type are rejected here.
+func (p *parser) parseIntArg() ast.Expr {
+	pos, lit := p.pos, p.lit
+	x := p.parseArgExpr()
+	switch x.(type) {
+	case *ast.StringLiteral, *ast.BoolLiteral:
+		p.errorf(pos, "unexpected token %q, expecting %q", lit, token.INT)
+		return nil
+	}
+	return x
+}
+
+// parseStringArg parses a string statement argument. Expressions are
+// checked at runtime, but literals of another type are rejected here.
+func (p *parser) parseStringArg() ast.Expr {
+	pos, lit := p.pos, p.lit
+	x := p.parseArgExpr()
+	switch x.(type) {
+	case *ast.IntLiteral, *ast.BoolLiteral:
+		p.errorf(pos, "unexpected token %q, expecting %q", lit, token.STRING)
+		return nil
+	}
+	return x
+}
+
+// parseArgExpr parses statement argument. Arguments of queries used as
+// expressions are single operands, so "slot_number_for_registration_number $r == 1"
+// compares the query result.
+func (p *parser) parseArgExpr() ast.Expr {
+	if p.queryArgs {
+		p.queryArgs = false
+		return p.parseOperand()
+	}
+	return p.parseExpr()
+}
+
+func (p *parser) parseExpr() ast.Expr {
+	return p.parseBinaryExpr(token.LowestPrec + 1)
+}
+
+func (p *parser) parseBinaryExpr(prec int) ast.Expr {
+	x := p.parseOperand()
+	if x == nil {
+		return nil
+	}
+
+	for {
+		op := p.tok
+		oprec := op.Precedence()
+		if oprec < prec {
+			return x
+		}
+		p.next()
+
+		y := p.parseBinaryExpr(oprec + 1)
+		if y == nil {
+			return nil
+		}
+		x = &ast.BinaryExpr{X: x, Op: op, Y: y}
+	}
+}
+
+func (p *parser) parseOperand() ast.Expr {
+	pos, lit := p.pos, p.lit
+
+	switch p.tok {
+	case token.INT:
+		p.next()
+		n, err := strconv.ParseInt(lit, 10, 64)
+		if err != nil {
+			p.errorf(pos, "invalid number %q", lit)
+			return nil
+		}
+		return &ast.IntLiteral{ValuePos: pos, Value: int(n)}
+	case token.STRING:
+		p.next()
+		// a word directly followed by parenthesis is a function call
+		if p.tok == token.LPAREN && p.pos == pos+token.Pos(len(lit)) {
+			if call := p.parseCall(lit); call != nil {
+				return call
+			}
+			return nil
+		}
+		return &ast.StringLiteral{ValuePos: pos, Value: lit}
+	case token.QUOTED:
+		p.next()
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			p.errorf(pos, "invalid string %s", lit)
+			return nil
+		}
+		return &ast.StringLiteral{ValuePos: pos, Value: s, Quoted: true}
+	case token.VAR:
+		p.next()
+		return &ast.Ident{NamePos: pos, Name: lit[1:]}
+	case token.TRUE, token.FALSE:
+		tok := p.tok
+		p.next()
+		return &ast.BoolLiteral{ValuePos: pos, Value: tok == token.TRUE}
+	case token.NOT:
+		p.next()
+		// not binds weaker than comparison, so "not $a == $b" negates
+		// the whole comparison
+		x := p.parseBinaryExpr(token.EQL.Precedence())
+		if x == nil {
+			return nil
+		}
+		return &ast.UnaryExpr{OpPos: pos, Op: token.NOT, X: x}
+	case token.REGISTRATION_NUMBERS_FOR_CARS_WITH_COLOUR,
+		token.SLOT_NUMBERS_FOR_CARS_WITH_COLOUR,
+		token.SLOT_NUMBER_FOR_REGISTRATION_NUMBER,
+		token.FIND,
+		token.COUNT,
+		token.OCCUPANCY,
+		token.CARS_WITH_REGISTRATION_MATCHING:
+		p.queryArgs = true
+		query := p.parseStatement()
+		p.queryArgs = false
+		if query != nil {
+			return &ast.QueryExpr{Query: query}
+		}
+		return nil
+	case token.LPAREN:
+		p.next()
+		x := p.parseExpr()
+		if x == nil {
+			return nil
+		}
+		if _, ok := p.expect(token.RPAREN); !ok {
+			return nil
+		}
+		return &ast.ParenExpr{Lparen: pos, X: x}
+	}
+
+	p.errorf(pos, "unexpected token %q, expecting expression", lit)
+	return nil
+}
+
+func (p *parser) parseCall(name string) *ast.CallExpr {
+	namePos := p.pos - token.Pos(len(name))
+	if !isIdentifier(name) {
+		p.errorf(namePos, "invalid function name %q", name)
+		return nil
+	}
+	p.next() // (
+
+	call := &ast.CallExpr{NamePos: namePos, Name: name, Args: []ast.Expr{}}
+	for p.tok != token.RPAREN {
+		if len(call.Args) > 0 {
+			if _, ok := p.expect(token.COMMA); !ok {
+				return nil
+			}
+		}
+		arg := p.parseExpr()
+		if arg == nil {
+			return nil
+		}
+		call.Args = append(call.Args, arg)
+	}
+	p.next() // )
+
+	return call
+}
+
+// isOperand reports whether tok starts an operand.
+func isOperand(tok token.Token) bool {
+	switch tok {
+	case token.INT, token.STRING, token.QUOTED, token.VAR, token.TRUE, token.FALSE, token.NOT, token.LPAREN,
+		token.REGISTRATION_NUMBERS_FOR_CARS_WITH_COLOUR,
+		token.SLOT_NUMBERS_FOR_CARS_WITH_COLOUR,
+		token.SLOT_NUMBER_FOR_REGISTRATION_NUMBER,
+		token.FIND,
+		token.COUNT,
+		token.OCCUPANCY,
+		token.CARS_WITH_REGISTRATION_MATCHING:
+		return true
+	}
+	return false
+}
+
+// isIdentifier reports whether name can be referenced as a variable.
+func isIdentifier(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// parse parses src of the file with given name.
+func parse(fset *token.FileSet, filename string, src []byte, includes []string) (*ast.Program, ErrorList) {
 	program := &ast.Program{
 		Statements: []ast.Statement{},
 	}
 
-	p := newParser(src)
+	p := newParser(fset, fset.AddFile(filename, len(src)), src, includes)
 	for p.tok != token.EOF {
 		if stmt := p.parseStatement(); stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		} else {
 			break
 		}
-		p.next()
 	}
 
 	if len(p.errors) > 0 {
-		return nil, errors.Join(p.errors)
+		return nil, p.errors
+	}
+	program.Comments = p.comments
+	return program, nil
+}
+
+// ParseFile parses the source code of a single lot file and returns
+// a new Program AST node. If src is nil, the source is read from filename.
+// Positions of nodes are recorded in fset. Included files are resolved
+// relative to the directory of filename. Syntax errors are returned as
+// ErrorList.
+func ParseFile(fset *token.FileSet, filename string, src []byte) (*ast.Program, error) {
+	if src == nil {
+		var err error
+		if src, err = ioutil.ReadFile(filename); err != nil {
+			return nil, err
+		}
+	}
+
+	includes, err := rootIncludes(filename)
+	if err != nil {
+		return nil, err
+	}
+	program, errs := parse(fset, filename, src, includes)
+	if len(errs) > 0 {
+		return nil, errs
 	}
 	return program, nil
 }
+
+// rootIncludes returns includes of the parsed file filename, which is not
+// included by other files.
+func rootIncludes(filename string) ([]string, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	return []string{abs}, nil
+}
+
+// Parse parses the lot source code and returns a new Program AST node.
+// Included files are resolved relative to the working directory.
+func Parse(src string) (*ast.Program, error) {
+	return ParseFile(token.NewFileSet(), "", []byte(src))
+}
@@ -1,7 +1,20 @@
 package parser
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	lerrors "parking_lot/errors"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/command"
+	"parking_lot/lot/grammar"
+	"parking_lot/lot/scanner"
+	"parking_lot/lot/token"
 )
 
 func TestParser(t *testing.T) {
@@ -39,11 +52,393 @@ func TestParserErrors(t *testing.T) {
 		{"registration_numbers_for_cars_with_colour 0"},
 		{"slot_numbers_for_cars_with_colour 0"},
 		{"slot_number_for_registration_number 0"},
+		{"let = 1"},
+		{"let KA-01 = 1"},
+		{"let n 1"},
+		{"let n = "},
+		{"create_parking_lot (1 + 2"},
+		{"park $reg +"},
+		{"park true White"},
+		{"create_parking_lot true"},
+		{"if true status"},
+		{"if true { status"},
+		{"if true { status } else status"},
+		{"for 1 in 1..2 { status }"},
+		{"for i 1..2 { status }"},
+		{"for i in 1 2 { status }"},
+		{"for i in a..b { status }"},
+		{"while { status }"},
+		{"let n = f(1 2)"},
+		{"let n = KA-01(1)"},
+		{"let n = 1 ! 2"},
+		{"let n = f (1)"},
+		{"return 1"},
+		{"if true { return }"},
+		{"def f { status }"},
+		{"def KA-01() { status }"},
+		{"def f(a b) { status }"},
+		{"def f(a, a) { status }"},
+		{"def f(1) { status }"},
+		{"def f() status"},
+		{"f (1)"},
+		{"KA-01-HH-1234"},
+		{"let s = slot_numbers_for_cars_with_colour 1"},
+		{"let s = slot_number_for_registration_number"},
+		{"find"},
+		{"find slots"},
+		{"find cars where"},
+		{"find cars where slot == 1"},
+		{"find cars where colour White"},
+		{"find cars where colour in White"},
+		{"find cars where colour in (White Red)"},
+		{"find cars where (colour == White"},
+		{"find cars where colour == White and"},
+		{"find cars select"},
+		{"find cars select slot,"},
+		{"find cars select owner"},
+		{"find cars order slot"},
+		{"find cars order by"},
+		{"find cars limit"},
+		{"find cars limit White"},
+		{"count by"},
+		{"count by slot"},
+		{"free_slots(1)"},
+		{"free_slots("},
+		{"cars_with_registration_matching"},
+		{"cars_with_registration_matching 1"},
 	}
 
 	for _, tt := range tests {
-		if _, err := Parse(tt.src); err == nil {
+		_, err := Parse(tt.src)
+		if err == nil {
 			t.Fatalf("expected error parse but got %q", err)
 		}
+		if !errors.Is(err, lerrors.SyntaxError) || lerrors.CodeOf(err) != lerrors.SyntaxError {
+			t.Errorf("parse %q error %q is not %s", tt.src, err, lerrors.SyntaxError)
+		}
+	}
+}
+
+func TestParserExpressions(t *testing.T) {
+	tests := []struct {
+		src string
+		out string
+	}{
+		{"let n = 1", "let n = 1"},
+		{"let n = 1 + 2 * 3", "let n = 1 + 2 * 3"},
+		{"let n = (1 + 2) * 3", "let n = (1 + 2) * 3"},
+		{`let reg = "KA-01-HH-" + $n`, `let reg = "KA-01-HH-" + $n`},
+		{`let c = "White"`, `let c = "White"`},
+		{"create_parking_lot $n * 2", "create_parking_lot $n * 2"},
+		{"park $reg $c", "park $reg $c"},
+		{"let b = not $a == 1 and $b or true", "let b = not $a == 1 and $b or true"},
+		{"let b = free_slots() >= 1", "let b = free_slots() >= 1"},
+		{"let b = f($a, 1 + 2)", "let b = f($a, 1 + 2)"},
+		{"if true {}", "if true {}"},
+		{"def f() {}", "def f() {}"},
+		{"def f(a, b) { return }", "def f(a, b) {\n\treturn\n}"},
+		{"def f(a) { if $a { return $a + 1 } status }", "def f(a) {\n\tif $a {\n\t\treturn $a + 1\n\t}\n\tstatus\n}"},
+		{"f()", "f()"},
+		{"move(1, KA-01-HH-1234)", "move(1, KA-01-HH-1234)"},
+		{"if $a < 1 { status } else if $a > 1 { status } else { park $r $c }",
+			"if $a < 1 {\n\tstatus\n} else if $a > 1 {\n\tstatus\n} else {\n\tpark $r $c\n}"},
+		{"for i in 1..$n { park $r White }", "for i in 1..$n {\n\tpark $r White\n}"},
+		{"while $i != 0 { for j in 1..2 { status } }", "while $i != 0 {\n\tfor j in 1..2 {\n\t\tstatus\n\t}\n}"},
+		{"find cars", "find cars"},
+		{`find cars where colour in (White, Red) and registration ~ "KA-01-*" select slot, registration order by slot limit 10`,
+			"find cars where colour in (White, Red) and registration ~ \"KA-01-*\" select slot, registration order by slot asc limit 10"},
+		{"find cars where not (colour == White or colour != $c) order by colour desc",
+			"find cars where not (colour == White or colour != $c) order by colour desc"},
+		{"find cars order by registration asc limit $n + 1", "find cars order by registration asc limit $n + 1"},
+		{"let n = len(find cars where colour == White select slot) + 1", "let n = len(find cars where colour == White select slot) + 1"},
+		{"leave $n", "leave $n"},
+		{"registration_numbers_for_cars_with_colour White", "registration_numbers_for_cars_with_colour White"},
+		{"slot_numbers_for_cars_with_colour White", "slot_numbers_for_cars_with_colour White"},
+		{"slot_number_for_registration_number $r", "slot_number_for_registration_number $r"},
+		{"count", "count"},
+		{"count by colour", "count by colour"},
+		{"occupancy", "occupancy"},
+		{"free_slots", "free_slots"},
+		{"free_slots()", "free_slots"},
+		{"let b = count + occupancy > free_slots()", "let b = count + occupancy > free_slots()"},
+		{"IF TRUE { Status }", "if true {\n\tstatus\n}"},
+		{"Find Cars Where Colour == White Order By Slot DESC", "find cars where colour == White order by slot desc"},
+		{"COUNT BY COLOUR", "count by colour"},
+		{`cars_with_registration_matching "KA-01-*-12??"`, `cars_with_registration_matching "KA-01-*-12??"`},
+		{`let n = len(cars_with_registration_matching "KA" + $r) > 1`, `let n = len(cars_with_registration_matching "KA" + $r) > 1`},
+	}
+
+	for _, tt := range tests {
+		program, err := Parse(tt.src)
+		if err != nil {
+			t.Fatalf("parse %q fail:\n%s", tt.src, err)
+		}
+		if l := len(program.Statements); l != 1 {
+			t.Fatalf("parse %q invalid number of statements - want: %d, got: %d", tt.src, 1, l)
+		}
+		if s := program.Statements[0].String(); s != tt.out {
+			t.Fatalf("parse %q invalid statement - want: %q, got: %q", tt.src, tt.out, s)
+		}
+	}
+}
+
+func TestParserPositions(t *testing.T) {
+	const src = "create_parking_lot 1\n  park KA-01-HH-1234 White\n"
+
+	fset := token.NewFileSet()
+	program, err := ParseFile(fset, "test.lot", []byte(src))
+	if err != nil {
+		t.Fatalf("parse fail:\n%s", err)
+	}
+
+	park := program.Statements[1].(*ast.ParkStatement)
+	if s := fset.Position(park.Pos()).String(); s != "test.lot:2:3" {
+		t.Errorf("invalid park position - want: %s, got: %s", "test.lot:2:3", s)
+	}
+	if s := fset.Position(park.Color.Pos()).String(); s != "test.lot:2:22" {
+		t.Errorf("invalid colour position - want: %s, got: %s", "test.lot:2:22", s)
+	}
+
+	_, err = ParseFile(token.NewFileSet(), "test.lot", []byte("status\nleave\n"))
+	if want := `test.lot:2:7: unexpected token "", expecting expression`; err == nil || err.Error() != want {
+		t.Errorf("invalid error - want: %s, got: %v", want, err)
+	}
+
+	list, ok := err.(ErrorList)
+	if !ok || len(list) != 1 {
+		t.Fatalf("invalid error list - want: 1 error, got: %#v", err)
+	}
+	if pos := list[0].Pos; pos.Offset != 13 || pos.Line != 2 || pos.Column != 7 {
+		t.Errorf("invalid error position - want: offset 13, line 2, column 7, got: %#v", pos)
+	}
+}
+
+// writeFiles writes files into a new temporary directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "lot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParserInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.lot":          "include \"lib/setup.lot\"\nstatus\n",
+		"lib/setup.lot":     "create_parking_lot 2\ninclude \"fixtures.lot\"\n",
+		"lib/fixtures.lot":  "park KA-01-HH-1234 White\n",
+		"cycle/a.lot":       "include \"b.lot\"\n",
+		"cycle/b.lot":       "status\ninclude \"a.lot\"\n",
+		"self.lot":          "include \"self.lot\"",
+		"missing.lot":       "status\ninclude \"nothing.lot\"",
+		"broken/main.lot":   "status\ninclude \"broken.lot\"",
+		"broken/broken.lot": "status\n\npark 1 White",
+	})
+	defer os.RemoveAll(dir)
+
+	program, err := ParseFile(token.NewFileSet(), filepath.Join(dir, "main.lot"), nil)
+	if err != nil {
+		t.Fatalf("parse fail:\n%s", err)
+	}
+	if s := program.Statements[0].String(); s != `include "lib/setup.lot"` {
+		t.Errorf("invalid include statement - want: %s, got: %s", `include "lib/setup.lot"`, s)
+	}
+	setup := program.Statements[0].(*ast.IncludeStatement).Program
+	if l := len(setup.Statements); l != 2 {
+		t.Fatalf("invalid number of included statements - want: %d, got: %d", 2, l)
+	}
+	if l := len(setup.Statements[1].(*ast.IncludeStatement).Program.Statements); l != 1 {
+		t.Fatalf("invalid number of nested included statements - want: %d, got: %d", 1, l)
+	}
+
+	tests := []struct {
+		file string
+		err  string
+	}{
+		{"cycle/a.lot", "b.lot:2:1: include cycle: "},
+		{"self.lot", "self.lot:1:1: include cycle: "},
+		{"missing.lot", "missing.lot:2:1: include \"nothing.lot\": open "},
+		{"broken/main.lot", "broken.lot:3:6: unexpected token \"1\", expecting \"STRING\""},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseFile(token.NewFileSet(), filepath.Join(dir, tt.file), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parse %s - want error containing: %q, got: %v", tt.file, tt.err, err)
+		}
 	}
 }
+
+func TestParserQueryExpressions(t *testing.T) {
+	program, err := Parse(`
+		let s = slot_numbers_for_cars_with_colour White
+		let b = slot_number_for_registration_number $r == 1
+		let n = len(registration_numbers_for_cars_with_colour ($c + 1)) + 1
+	`)
+	if err != nil {
+		t.Fatalf("parse fail:\n%s", err)
+	}
+
+	s := program.Statements[0].(*ast.LetStatement).Value.(*ast.QueryExpr)
+	if _, ok := s.Query.(*ast.SlotNumbersForCarsWithColourStatement); !ok {
+		t.Errorf("invalid query - want: %T, got: %T", &ast.SlotNumbersForCarsWithColourStatement{}, s.Query)
+	}
+
+	b := program.Statements[1].(*ast.LetStatement).Value.(*ast.BinaryExpr)
+	if _, ok := b.X.(*ast.QueryExpr); !ok || b.Op != token.EQL {
+		t.Errorf("query argument should be single operand, got: %s", b)
+	}
+
+	n := program.Statements[2].(*ast.LetStatement).Value.(*ast.BinaryExpr)
+	call := n.X.(*ast.CallExpr)
+	q := call.Args[0].(*ast.QueryExpr).Query.(*ast.RegistrationNumbersForCarsWithColourStatement)
+	if _, ok := q.Color.(*ast.ParenExpr); !ok {
+		t.Errorf("invalid query argument - want: %T, got: %T", &ast.ParenExpr{}, q.Color)
+	}
+}
+
+func TestParserFind(t *testing.T) {
+	program, err := Parse("find cars where colour == White or colour == Red and not registration ~ KA-* status")
+	if err != nil {
+		t.Fatalf("parse fail:\n%s", err)
+	}
+	if l := len(program.Statements); l != 2 {
+		t.Fatalf("invalid number of statements - want: %d, got: %d", 2, l)
+	}
+
+	find := program.Statements[0].(*ast.FindStatement)
+	or, ok := find.Where.(*ast.BinaryPredicate)
+	if !ok || or.Op != token.OR {
+		t.Fatalf("and should bind tighter than or, got: %s", find.Where)
+	}
+	and, ok := or.Y.(*ast.BinaryPredicate)
+	if !ok || and.Op != token.AND {
+		t.Fatalf("and should bind tighter than or, got: %s", find.Where)
+	}
+	if _, ok := and.Y.(*ast.NotPredicate); !ok {
+		t.Errorf("invalid predicate - want: %T, got: %T", &ast.NotPredicate{}, and.Y)
+	}
+}
+
+func TestParserCommand(t *testing.T) {
+	nop := func(lot command.Lot, args []interface{}) (interface{}, error) { return nil, nil }
+	command.Register(&command.Command{Name: "open_barrier", Args: []command.Arg{{Name: "GATE", Kind: command.Int}}, Handler: nop})
+	command.Register(&command.Command{Name: "Notify", Args: []command.Arg{{Name: "REGISTRATION", Kind: command.String}, {Name: "TEXT", Kind: command.String}}, Handler: nop})
+	defer command.Unregister("open_barrier")
+	defer command.Unregister("notify")
+
+	tests := []struct {
+		src   string
+		stmts []string
+	}{
+		{"open_barrier 1", []string{"open_barrier 1"}},
+		{"OPEN_BARRIER $n + 1 status", []string{"open_barrier $n + 1", "status"}},
+		{"notify KA-01-HH-1234 \"car is parked\"", []string{"Notify KA-01-HH-1234 \"car is parked\""}},
+		{"park KA-01-HH-1234 open_barrier", []string{"park KA-01-HH-1234 open_barrier"}},
+	}
+
+	for _, tt := range tests {
+		program, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("parse %q error: %s", tt.src, err)
+			continue
+		}
+		var stmts []string
+		for _, stmt := range program.Statements {
+			stmts = append(stmts, stmt.String())
+		}
+		if !reflect.DeepEqual(stmts, tt.stmts) {
+			t.Errorf("parse %q invalid statements - want: %q, got: %q", tt.src, tt.stmts, stmts)
+		}
+	}
+
+	for _, src := range []string{"open_barrier", "open_barrier White", "notify KA-01-HH-1234 1"} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("parse %q - expected error", src)
+		}
+	}
+}
+
+func TestParserComments(t *testing.T) {
+	program, err := Parse("# first\nstatus # second\nif true { # third\n}\n#fourth")
+	if err != nil {
+		t.Fatalf("parse fail:\n%s", err)
+	}
+	if l := len(program.Statements); l != 2 {
+		t.Fatalf("invalid number of statements - want: %d, got: %d", 2, l)
+	}
+
+	want := []string{"# first", "# second", "# third", "#fourth"}
+	if len(program.Comments) != len(want) {
+		t.Fatalf("invalid number of comments - want: %d, got: %d", len(want), len(program.Comments))
+	}
+	for i, c := range program.Comments {
+		if c.Text != want[i] {
+			t.Errorf("invalid comment - want: %q, got: %q", want[i], c.Text)
+		}
+	}
+	if pos := program.Comments[1].Pos(); pos != 16 {
+		t.Errorf("invalid comment position - want: %d, got: %d", 16, pos)
+	}
+}
+
+// hasInclude reports whether src has include keyword. Fuzzed programs must
+// not read arbitrary files.
+func hasInclude(src string) bool {
+	s := scanner.New(src)
+	for {
+		_, tok, _ := s.Scan()
+		switch tok {
+		case token.INCLUDE:
+			return true
+		case token.EOF:
+			return false
+		}
+	}
+}
+
+func FuzzParser(f *testing.F) {
+	for _, src := range []string{
+		"create_parking_lot 6\npark KA-01-HH-1234 White # comment\nleave 1\nstatus",
+		"def f(a, b) { if $a > $b { return $a } else { return $b } }\nleave f(1, 2)",
+		"let n = count by colour\nfind cars where not (colour == White or registration ~ \"KA-*\") select slot order by slot desc limit $n",
+		"for i in 1..3 { while free_slots() > 0 { park \"KA-01-HH-\" + $i White } }\nfree_slots",
+		"let x = find cars order by slot asc desc()",
+		"park find cars \"order\" count \"by\"",
+	} {
+		f.Add(src)
+	}
+	gen := newGenerator(3)
+	gen.Terminals["IncludeStmt"] = []string{"status"}
+	for i := 0; i < 50; i++ {
+		tokens, err := gen.Generate(grammar.Start)
+		if err != nil {
+			f.Fatalf("generate error: %s", err)
+		}
+		f.Add(strings.Join(tokens, " "))
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		if hasInclude(src) {
+			return
+		}
+		fset := token.NewFileSet()
+		program, err := ParseFile(fset, "fuzz.lot", []byte(src))
+		if err != nil {
+			if _, ok := err.(ErrorList); !ok {
+				t.Fatalf("parse error - want: ErrorList, got: %#v", err)
+			}
+			return
+		}
+		checkProgram(t, fset, "fuzz.lot", src, program)
+	})
+}
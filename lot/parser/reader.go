@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// A Reader parses statements from an input stream one by one. It reads
+// only as much input as the next statement needs, so long or endless
+// input, e.g. a replay file or piped commands, is parsed with memory
+// bounded by the size of a statement.
+type Reader struct {
+	// Interactive makes Next return a statement complete at the end of
+	// line without reading the next line, which is not typed yet in a
+	// shell or not written yet to a pipe. Otherwise a statement is
+	// complete when the token following it is read, so a statement may
+	// continue on the next line. In both modes an unfinished statement,
+	// e.g. an open block, continues on the next line.
+	Interactive bool
+
+	fset     *token.FileSet
+	filename string
+	in       *bufio.Reader
+	includes []string
+	err      error // error of including files resolution
+
+	buf   []byte         // read input which is not parsed yet
+	start token.Position // position of buf in the input
+	eof   bool           // input is read to the end
+
+	last *token.FileSet // file set of the last statement
+}
+
+// NewReader returns a Reader parsing file filename from r. Positions of
+// statements are recorded in fset as sections of the file. If fset is
+// nil, each statement gets a new file set, so memory does not grow with
+// the input. Included files are resolved relative to the directory of
+// filename.
+func NewReader(fset *token.FileSet, filename string, r io.Reader) *Reader {
+	includes, err := rootIncludes(filename)
+	return &Reader{
+		fset:     fset,
+		filename: filename,
+		in:       bufio.NewReader(r),
+		includes: includes,
+		err:      err,
+		start:    token.Position{Filename: filename, Line: 1, Column: 1},
+	}
+}
+
+// Next parses and returns the next statement. It returns io.EOF at the end
+// of input. Syntax errors are returned as ErrorList. The rest of the line
+// with the error is skipped, so Next may be called again to continue with
+// the following lines.
+func (r *Reader) Next() (ast.Statement, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for {
+		fset := r.fset
+		if fset == nil {
+			fset = token.NewFileSet()
+		}
+		file := fset.AddSection(r.filename, r.start, len(r.buf))
+		p := newParser(fset, file, r.buf, r.includes)
+
+		if p.tok == token.EOF {
+			// only blanks and comments
+			if r.eof {
+				return nil, io.EOF
+			}
+			r.skip(len(r.buf))
+			if err := r.read(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		stmt := p.parseStatement()
+		if len(p.errors) > 0 {
+			if r.atEnd(p.errors[0].Pos) && !r.eof {
+				if err := r.read(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			r.skipLine(p.errors[0].Pos)
+			return nil, p.errors
+		}
+
+		if p.tok == token.EOF {
+			if !r.eof && !r.Interactive {
+				if err := r.read(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			r.skip(len(r.buf))
+		} else {
+			r.skip(file.Offset(p.pos))
+		}
+		r.last = fset
+		return stmt, nil
+	}
+}
+
+// Position returns the position of pos of the statement returned by the
+// last Next call, also when the reader has no file set.
+func (r *Reader) Position(pos token.Pos) token.Position {
+	if r.last == nil {
+		return token.Position{}
+	}
+	return r.last.Position(pos)
+}
+
+// FileSet returns the file set of the statement returned by the last Next
+// call, which holds also positions of its included files.
+func (r *Reader) FileSet() *token.FileSet {
+	return r.last
+}
+
+// atEnd reports whether pos is the end of read input, so the statement
+// may be finished by the following input.
+func (r *Reader) atEnd(pos token.Position) bool {
+	return pos.Filename == r.filename && pos.Offset == r.start.Offset+len(r.buf)
+}
+
+// read reads more input. Interactive reader reads a single line, otherwise
+// the read input is doubled, so a long statement is not parsed again for
+// each of its lines.
+func (r *Reader) read() error {
+	want := len(r.buf) + 1
+	if !r.Interactive {
+		want = 2 * len(r.buf)
+	}
+	for !r.eof && (len(r.buf) < want || len(r.buf) == 0) {
+		line, err := r.in.ReadBytes('\n')
+		r.buf = append(r.buf, line...)
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipLine drops read input up to the end of line of error at pos. Error
+// in an included file drops all read input.
+func (r *Reader) skipLine(pos token.Position) {
+	n := len(r.buf)
+	if pos.Filename == r.filename {
+		offset := min(max(pos.Offset-r.start.Offset, 0), len(r.buf))
+		if i := bytes.IndexByte(r.buf[offset:], '\n'); i >= 0 {
+			n = offset + i + 1
+		}
+	}
+	r.skip(n)
+}
+
+// skip drops the first n bytes of read input.
+func (r *Reader) skip(n int) {
+	skipped := r.buf[:n]
+	r.start.Offset += n
+	if i := bytes.LastIndexByte(skipped, '\n'); i >= 0 {
+		r.start.Line += bytes.Count(skipped, []byte{'\n'})
+		r.start.Column = n - i
+	} else {
+		r.start.Column += n
+	}
+	r.buf = append(r.buf[:0], r.buf[n:]...)
+}
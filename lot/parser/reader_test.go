@@ -0,0 +1,266 @@
+package parser
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"parking_lot/lot/token"
+)
+
+// lineReader returns a single line of lines for each Read call.
+type lineReader struct {
+	lines []string
+	reads int
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, io.EOF
+	}
+	r.reads++
+	n := copy(p, r.lines[0])
+	r.lines[0] = r.lines[0][n:]
+	if r.lines[0] == "" {
+		r.lines = r.lines[1:]
+	}
+	return n, nil
+}
+
+// repeatReader repeats s endlessly.
+type repeatReader struct {
+	s   string
+	off int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.s[r.off]
+		r.off = (r.off + 1) % len(r.s)
+	}
+	return len(p), nil
+}
+
+// readAll returns statements read by r as strings and the first error.
+func readAll(r *Reader) ([]string, error) {
+	var stmts []string
+	for {
+		stmt, err := r.Next()
+		if err == io.EOF {
+			return stmts, nil
+		}
+		if err != nil {
+			return stmts, err
+		}
+		stmts = append(stmts, stmt.String())
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		src         string
+		interactive bool
+		stmts       []string
+	}{
+		{"", false, nil},
+		{"# comment\n\n", false, nil},
+		{"create_parking_lot 6\npark KA-01-HH-1234 White\nstatus", false,
+			[]string{"create_parking_lot 6", "park KA-01-HH-1234 White", "status"}},
+		{"park KA-01-HH-1234 White status leave 1\n", false,
+			[]string{"park KA-01-HH-1234 White", "status", "leave 1"}},
+		{"park\nKA-01-HH-1234\n# colour\nWhite\n", false,
+			[]string{"park KA-01-HH-1234 White"}},
+		{"if true { status }\nelse { leave 1 }\n", false,
+			[]string{"if true {\n\tstatus\n} else {\n\tleave 1\n}"}},
+		{"if true { status }\nstatus\n", true,
+			[]string{"if true {\n\tstatus\n}", "status"}},
+		{"for i in 1..3 {\n\tleave $i\n}\nstatus\n", true,
+			[]string{"for i in 1..3 {\n\tleave $i\n}", "status"}},
+		{"let n = find cars\nwhere colour == White\n", false,
+			[]string{"let n = find cars where colour == White"}},
+	}
+
+	for _, tt := range tests {
+		r := NewReader(nil, "", strings.NewReader(tt.src))
+		r.Interactive = tt.interactive
+		stmts, err := readAll(r)
+		if err != nil {
+			t.Errorf("read %q error: %s", tt.src, err)
+			continue
+		}
+		if strings.Join(stmts, "\n") != strings.Join(tt.stmts, "\n") {
+			t.Errorf("read %q invalid statements - want: %q, got: %q", tt.src, tt.stmts, stmts)
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	fset := token.NewFileSet()
+	r := NewReader(fset, "test.lot", strings.NewReader("status\n\n  leave x status\nleave 2\nfor i in 1..2 {\n"))
+	stmts, err := readAll(r)
+	if want := "test.lot:3:9: unexpected token \"x\", expecting \"INT\""; err == nil || err.Error() != want {
+		t.Fatalf("invalid error - want: %s, got: %v", want, err)
+	}
+	if _, ok := err.(ErrorList); !ok {
+		t.Errorf("invalid error type - want: ErrorList, got: %T", err)
+	}
+	if len(stmts) != 1 || stmts[0] != "status" {
+		t.Errorf("invalid statements - want: %q, got: %q", []string{"status"}, stmts)
+	}
+
+	// the rest of invalid line is skipped
+	stmt, err := r.Next()
+	if err != nil {
+		t.Fatalf("next error: %s", err)
+	}
+	if pos := fset.Position(stmt.Pos()).String(); pos != "test.lot:4:1" {
+		t.Errorf("invalid statement position - want: %s, got: %s", "test.lot:4:1", pos)
+	}
+
+	if _, err := r.Next(); err == nil || !strings.HasPrefix(err.Error(), "test.lot:5:17: ") {
+		t.Errorf("invalid error at the end - want: %s, got: %v", "test.lot:5:17: ...", err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("invalid error after the end - want: %s, got: %v", io.EOF, err)
+	}
+}
+
+func TestReaderSkipLine(t *testing.T) {
+	src := "create_parking_lot 1\nleave x status\npark KA-01-HH-1234 White\nif true {\nleave y\n}\nstatus\n"
+	r := NewReader(nil, "", strings.NewReader(src))
+
+	var stmts, errs []string
+	for {
+		stmt, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		stmts = append(stmts, stmt.String())
+	}
+
+	want := []string{"create_parking_lot 1", "park KA-01-HH-1234 White", "status"}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Errorf("invalid statements - want: %q, got: %q", want, stmts)
+	}
+	wantErrs := []string{
+		"2:7: unexpected token \"x\", expecting \"INT\"",
+		"5:7: unexpected token \"y\", expecting \"INT\"",
+		"6:1: unexpected token \"}\"",
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("invalid errors - want: %q, got: %q", wantErrs, errs)
+	}
+}
+
+func TestReaderPosition(t *testing.T) {
+	r := NewReader(nil, "test.lot", strings.NewReader("status\n\n  leave 1 status\n"))
+	if pos := r.Position(token.NoPos); pos.IsValid() {
+		t.Errorf("invalid position before the first statement - want: -, got: %s", pos)
+	}
+
+	for _, want := range []string{"test.lot:1:1", "test.lot:3:3", "test.lot:3:11"} {
+		stmt, err := r.Next()
+		if err != nil {
+			t.Fatalf("next error: %s", err)
+		}
+		if pos := r.Position(stmt.Pos()).String(); pos != want {
+			t.Errorf("invalid position of %s - want: %s, got: %s", stmt, want, pos)
+		}
+		if pos := r.FileSet().Position(stmt.Pos()).String(); pos != want {
+			t.Errorf("invalid position of %s in file set - want: %s, got: %s", stmt, want, pos)
+		}
+	}
+}
+
+func TestReaderPipe(t *testing.T) {
+	pr, pw := io.Pipe()
+	r := NewReader(nil, "", pr)
+	r.Interactive = true
+
+	tests := []struct {
+		lines []string
+		stmt  string
+	}{
+		{[]string{"create_parking_lot 3\n"}, "create_parking_lot 3"},
+		{[]string{"park KA-01-HH-1234 White\n"}, "park KA-01-HH-1234 White"},
+		{[]string{"if true {\n", "leave 1\n", "}\n"}, "if true {\n\tleave 1\n}"},
+	}
+	for _, tt := range tests {
+		go func(lines []string) {
+			for _, line := range lines {
+				pw.Write([]byte(line))
+			}
+		}(tt.lines)
+
+		// the statement is returned before the next line is written
+		next := make(chan string, 1)
+		go func() {
+			stmt, err := r.Next()
+			if err != nil {
+				next <- err.Error()
+				return
+			}
+			next <- stmt.String()
+		}()
+		select {
+		case stmt := <-next:
+			if stmt != tt.stmt {
+				t.Errorf("invalid statement - want: %q, got: %q", tt.stmt, stmt)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("statement %q not returned before the next line", tt.stmt)
+		}
+	}
+
+	pw.Close()
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("invalid error after the end - want: %s, got: %v", io.EOF, err)
+	}
+}
+
+func TestReaderInteractive(t *testing.T) {
+	in := &lineReader{lines: []string{"status\n", "if true {\n", "leave 1\n", "}\n"}}
+	r := NewReader(nil, "", in)
+	r.Interactive = true
+
+	want := []struct {
+		stmt  string
+		reads int
+	}{
+		{"status", 1},
+		{"if true {\n\tleave 1\n}", 4},
+	}
+	for _, w := range want {
+		stmt, err := r.Next()
+		if err != nil {
+			t.Fatalf("next error: %s", err)
+		}
+		if stmt.String() != w.stmt {
+			t.Errorf("invalid statement - want: %q, got: %q", w.stmt, stmt)
+		}
+		if in.reads != w.reads {
+			t.Errorf("statement %q read too much - want: %d reads, got: %d", w.stmt, w.reads, in.reads)
+		}
+	}
+}
+
+func TestReaderMemory(t *testing.T) {
+	r := NewReader(nil, "", &repeatReader{s: "park KA-01-HH-1234 White\n# comment\nleave 1\n"})
+	for i := 0; i < 10000; i++ {
+		if _, err := r.Next(); err != nil {
+			t.Fatalf("next error: %s", err)
+		}
+	}
+	if cap(r.buf) > 256 {
+		t.Errorf("buffer grows with input - capacity: %d", cap(r.buf))
+	}
+	if r.start.Line != 15001 {
+		t.Errorf("invalid line - want: %d, got: %d", 15001, r.start.Line)
+	}
+}
@@ -1,10 +1,13 @@
 package scanner
 
 import (
+	"strings"
+
 	"parking_lot/lot/token"
 )
 
-// end of file
+// eof is the current character at the end of source. NUL characters in
+// source are not the end, see atEOF.
 const eof byte = 0
 
 // A Scanner holds the scanner's internal state while processing a lot source.
@@ -33,6 +36,11 @@ func (s *Scanner) next() {
 	s.readOffset++
 }
 
+// atEOF reports whether the scanner reached the end of source.
+func (s *Scanner) atEOF() bool {
+	return s.offset >= len(s.src)
+}
+
 func (s *Scanner) skipWhitespace() {
 	for isWhitespace(s.ch) {
 		s.next()
@@ -41,7 +49,45 @@ func (s *Scanner) skipWhitespace() {
 
 func (s *Scanner) scanString() string {
 	offset := s.offset
-	for !isWhitespace(s.ch) && s.ch != eof {
+	for !token.IsDelimiter(s.ch) && !s.atEOF() {
+		s.next()
+	}
+	return s.src[offset:s.offset]
+}
+
+// scanQuoted scans a double-quoted string. It returns the literal with
+// quotes and false if the string is not terminated.
+func (s *Scanner) scanQuoted() (string, bool) {
+	offset := s.offset
+	s.next() // opening "
+	for s.ch != '"' {
+		if s.ch == '\n' || s.atEOF() {
+			return s.src[offset:s.offset], false
+		}
+		if s.ch == '\\' {
+			s.next()
+			if s.ch == '\n' || s.atEOF() {
+				return s.src[offset:s.offset], false
+			}
+		}
+		s.next()
+	}
+	s.next() // closing "
+	return s.src[offset:s.offset], true
+}
+
+// scanComment scans a comment up to the end of line.
+func (s *Scanner) scanComment() string {
+	offset := s.offset
+	for s.ch != '\n' && !s.atEOF() {
+		s.next()
+	}
+	return strings.TrimRight(s.src[offset:s.offset], " \t\r")
+}
+
+func (s *Scanner) scanIdentifier() string {
+	offset := s.offset
+	for isLetter(s.ch) || isDigit(s.ch) {
 		s.next()
 	}
 	return s.src[offset:s.offset]
@@ -69,15 +115,86 @@ func (s *Scanner) Scan() (pos int, tok token.Token, lit string) {
 	case isDigit(s.ch):
 		lit = s.scanNumber()
 		tok = token.INT
-	case s.ch == eof:
+		if isLetter(s.ch) {
+			// word starting with digits, e.g. registration number 7ABC123
+			lit += s.scanString()
+			tok = token.STRING
+		}
+	case s.ch == '"':
+		var ok bool
+		if lit, ok = s.scanQuoted(); ok {
+			tok = token.QUOTED
+		} else {
+			tok = token.ILLEGAL
+		}
+	case s.ch == '#':
+		lit = s.scanComment()
+		tok = token.COMMENT
+	case s.ch == '$':
+		s.next()
+		if isLetter(s.ch) {
+			lit = "$" + s.scanIdentifier()
+			tok = token.VAR
+		} else {
+			tok = token.ILLEGAL
+			lit = "$"
+		}
+	case s.atEOF():
 		tok = token.EOF
 	default:
-		tok = token.ILLEGAL
-		lit = string(s.ch)
+		ch := s.ch
+		s.next()
+		switch ch {
+		case '+':
+			tok = token.ADD
+		case '-':
+			tok = token.SUB
+		case '*':
+			tok = token.MUL
+		case '/':
+			tok = token.QUO
+		case '%':
+			tok = token.REM
+		case '=':
+			tok = s.switch2(token.ASSIGN, '=', token.EQL)
+		case '!':
+			tok = s.switch2(token.ILLEGAL, '=', token.NEQ)
+		case '<':
+			tok = s.switch2(token.LSS, '=', token.LEQ)
+		case '>':
+			tok = s.switch2(token.GTR, '=', token.GEQ)
+		case '.':
+			tok = s.switch2(token.ILLEGAL, '.', token.RANGE)
+		case '(':
+			tok = token.LPAREN
+		case ')':
+			tok = token.RPAREN
+		case '{':
+			tok = token.LBRACE
+		case '}':
+			tok = token.RBRACE
+		case ',':
+			tok = token.COMMA
+		case '~':
+			tok = token.TILDE
+		default:
+			tok = token.ILLEGAL
+		}
+		lit = s.src[pos:s.offset]
 	}
 	return pos, tok, lit
 }
 
+// switch2 returns tok1 if the current character is ch and consumes it,
+// otherwise it returns tok0.
+func (s *Scanner) switch2(tok0 token.Token, ch byte, tok1 token.Token) token.Token {
+	if s.ch == ch {
+		s.next()
+		return tok1
+	}
+	return tok0
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
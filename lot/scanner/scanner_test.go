@@ -12,9 +12,12 @@ func TestScanSingleToken(t *testing.T) {
 		tok token.Token
 	}{
 		{";", token.ILLEGAL},
+		{"\x00", token.ILLEGAL},
 		{" ", token.EOF},
 		{"0", token.INT},
 		{"a-", token.STRING},
+		{"7ABC123", token.STRING},
+		{"12_a", token.STRING},
 		{"create_parking_lot", token.CREATE_PARKING_LOT},
 		{"park", token.PARK},
 		{"leave", token.LEAVE},
@@ -22,6 +25,55 @@ func TestScanSingleToken(t *testing.T) {
 		{"registration_numbers_for_cars_with_colour", token.REGISTRATION_NUMBERS_FOR_CARS_WITH_COLOUR},
 		{"slot_numbers_for_cars_with_colour", token.SLOT_NUMBERS_FOR_CARS_WITH_COLOUR},
 		{"slot_number_for_registration_number", token.SLOT_NUMBER_FOR_REGISTRATION_NUMBER},
+		{"let", token.LET},
+		{`"KA-01"`, token.QUOTED},
+		{`"a\"b"`, token.QUOTED},
+		{`"a`, token.ILLEGAL},
+		{"$n", token.VAR},
+		{"$", token.ILLEGAL},
+		{"+", token.ADD},
+		{"-", token.SUB},
+		{"*", token.MUL},
+		{"/", token.QUO},
+		{"%", token.REM},
+		{"=", token.ASSIGN},
+		{"(", token.LPAREN},
+		{")", token.RPAREN},
+		{"==", token.EQL},
+		{"!=", token.NEQ},
+		{"!", token.ILLEGAL},
+		{"<", token.LSS},
+		{">", token.GTR},
+		{"<=", token.LEQ},
+		{">=", token.GEQ},
+		{"{", token.LBRACE},
+		{"}", token.RBRACE},
+		{",", token.COMMA},
+		{"..", token.RANGE},
+		{".", token.ILLEGAL},
+		{"if", token.IF},
+		{"else", token.ELSE},
+		{"for", token.FOR},
+		{"in", token.IN},
+		{"while", token.WHILE},
+		{"and", token.AND},
+		{"or", token.OR},
+		{"not", token.NOT},
+		{"true", token.TRUE},
+		{"false", token.FALSE},
+		{"def", token.DEF},
+		{"return", token.RETURN},
+		{"find", token.FIND},
+		{"~", token.TILDE},
+		{"count", token.COUNT},
+		{"occupancy", token.OCCUPANCY},
+		{"free_slots", token.STRING},
+		{"cars_with_registration_matching", token.CARS_WITH_REGISTRATION_MATCHING},
+		{"Park", token.PARK},
+		{"STATUS", token.STATUS},
+		{"White", token.STRING},
+		{"# comment", token.COMMENT},
+		{"#", token.COMMENT},
 	}
 
 	for _, tt := range tests {
@@ -49,6 +101,10 @@ func TestScanMultipleToken(t *testing.T) {
 			"park KA-01-AA-0000 White",
 			[]token.Token{token.PARK, token.STRING, token.STRING},
 		},
+		{
+			"park 7ABC123 White leave 7 +1",
+			[]token.Token{token.PARK, token.STRING, token.STRING, token.LEAVE, token.INT, token.ADD, token.INT},
+		},
 		{
 			"status leave 1",
 			[]token.Token{token.STATUS, token.LEAVE, token.INT},
@@ -62,6 +118,33 @@ func TestScanMultipleToken(t *testing.T) {
 				token.STRING,
 			},
 		},
+		{
+			`let reg = "KA-01-HH-"+$n*(2 - 1)`,
+			[]token.Token{
+				token.LET, token.STRING, token.ASSIGN, token.QUOTED, token.ADD,
+				token.VAR, token.MUL, token.LPAREN, token.INT, token.SUB, token.INT, token.RPAREN,
+			},
+		},
+		{
+			"for i in 1..$n {park KA-01-HH-1234 White}",
+			[]token.Token{
+				token.FOR, token.STRING, token.IN, token.INT, token.RANGE, token.VAR,
+				token.LBRACE, token.PARK, token.STRING, token.STRING, token.RBRACE,
+			},
+		},
+		{
+			"status # comment\npark White#colour\n#",
+			[]token.Token{
+				token.STATUS, token.COMMENT, token.PARK, token.STRING, token.COMMENT, token.COMMENT, token.EOF,
+			},
+		},
+		{
+			"if free_slots()>=1 {}",
+			[]token.Token{
+				token.IF, token.STRING, token.LPAREN, token.RPAREN, token.GEQ, token.INT,
+				token.LBRACE, token.RBRACE,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -78,3 +161,37 @@ func TestScanMultipleToken(t *testing.T) {
 		}
 	}
 }
+
+func FuzzScanner(f *testing.F) {
+	for _, src := range []string{
+		"create_parking_lot 6\npark KA-01-HH-1234 White # comment\n",
+		"let x = \"a\\\"b\" + $y\nif $x >= 1 { leave $x } else { status }",
+		"for i in 1..3 { } find cars where registration ~ \"KA-*\"",
+		"\"unterminated\n$ !x ..",
+		"",
+	} {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		s := New(src)
+		prev := -1
+		for i := 0; i <= len(src); i++ {
+			pos, tok, lit := s.Scan()
+			if pos < 0 || pos > len(src) || pos <= prev && tok != token.EOF {
+				t.Fatalf("invalid position %d after %d of %q", pos, prev, src)
+			}
+			if tok == token.EOF {
+				if pos != len(src) {
+					t.Fatalf("EOF at %d - want: %d", pos, len(src))
+				}
+				return
+			}
+			if lit == "" || src[pos:pos+len(lit)] != lit {
+				t.Fatalf("%s literal %q at %d is not source %q", tok, lit, pos, src)
+			}
+			prev = pos
+		}
+		t.Fatalf("scanner did not reach EOF of %q", src)
+	})
+}
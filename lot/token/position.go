@@ -0,0 +1,189 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Pos is a compact encoding of a source position within a file set.
+// It can be converted into a Position for a more convenient, but much
+// larger, representation.
+type Pos int
+
+// NoPos is the zero value for Pos; there is no file and line information
+// associated with it.
+const NoPos Pos = 0
+
+// IsValid reports whether the position is valid.
+func (p Pos) IsValid() bool {
+	return p != NoPos
+}
+
+// Position describes an arbitrary source position including the file,
+// line, and column location. A Position is valid if the line number is > 0.
+type Position struct {
+	Filename string // filename, if any
+	Offset   int    // offset, starting at 0
+	Line     int    // line number, starting at 1
+	Column   int    // column number, starting at 1 (byte count)
+}
+
+// IsValid reports whether the position is valid.
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+// String returns a string in one of several forms:
+//
+//	file:line:column    valid position with file name
+//	line:column         valid position without file name
+//	file                invalid position with file name
+//	-                   invalid position without file name
+func (pos Position) String() string {
+	s := pos.Filename
+	if pos.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// A File is a handle for a file belonging to a FileSet.
+// A File has a name, size, and line offset table.
+type File struct {
+	name  string
+	base  int
+	size  int
+	start Position // position of the first character, see AddSection
+	lines []int    // offsets of the first character of each line
+}
+
+// Name returns the file name of file f as registered with AddFile.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Base returns the base offset of file f as registered with AddFile.
+func (f *File) Base() int {
+	return f.base
+}
+
+// Size returns the size of file f as registered with AddFile.
+func (f *File) Size() int {
+	return f.size
+}
+
+// SetLinesForContent sets the line offsets for the given file content.
+func (f *File) SetLinesForContent(content []byte) {
+	lines := []int{0}
+	for offset, b := range content {
+		if b == '\n' && offset+1 < len(content) {
+			lines = append(lines, offset+1)
+		}
+	}
+	f.lines = lines
+}
+
+// Pos returns the Pos value for the given file offset.
+// The offset must be <= f.Size().
+func (f *File) Pos(offset int) Pos {
+	if offset > f.size {
+		panic("illegal file offset")
+	}
+	return Pos(f.base + offset)
+}
+
+// Offset returns the offset for the given file position p.
+func (f *File) Offset(p Pos) int {
+	if int(p) < f.base || int(p) > f.base+f.size {
+		panic("illegal Pos value")
+	}
+	return int(p) - f.base
+}
+
+// Line returns the line number for the given file position p.
+func (f *File) Line(p Pos) int {
+	return f.Position(p).Line
+}
+
+// Position returns the Position value for the given file position p.
+func (f *File) Position(p Pos) Position {
+	offset := f.Offset(p)
+	i := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset }) - 1
+	if i < 0 {
+		i = 0
+	}
+	pos := Position{
+		Filename: f.name,
+		Offset:   f.start.Offset + offset,
+		Line:     f.start.Line + i,
+		Column:   offset - f.lines[i] + 1,
+	}
+	if i == 0 {
+		pos.Column += f.start.Column - 1
+	}
+	return pos
+}
+
+// A FileSet represents a set of source files. Positions of all files are
+// unique within the set. FileSet is not safe for concurrent use.
+type FileSet struct {
+	base  int
+	files []*File
+}
+
+// NewFileSet creates a new file set.
+func NewFileSet() *FileSet {
+	return &FileSet{base: 1} // 0 == NoPos
+}
+
+// AddFile adds a new file with a given filename and file size to the file
+// set and returns the file.
+func (s *FileSet) AddFile(filename string, size int) *File {
+	return s.AddSection(filename, Position{Line: 1, Column: 1}, size)
+}
+
+// AddSection adds a section of file filename, which starts at position
+// start of the file and has given size, to the file set and returns it.
+// Positions in the section are reported as positions in the whole file,
+// so a file can be added piece by piece as it is read.
+func (s *FileSet) AddSection(filename string, start Position, size int) *File {
+	start.Filename = filename
+	f := &File{
+		name:  filename,
+		base:  s.base,
+		size:  size,
+		start: start,
+		lines: []int{0},
+	}
+	s.base += size + 1 // +1 because EOF also has a position
+	s.files = append(s.files, f)
+	return f
+}
+
+// File returns the file that contains the position p.
+// If no such file is found, the result is nil.
+func (s *FileSet) File(p Pos) *File {
+	if !p.IsValid() {
+		return nil
+	}
+	for _, f := range s.files {
+		if f.base <= int(p) && int(p) <= f.base+f.size {
+			return f
+		}
+	}
+	return nil
+}
+
+// Position converts a Pos p in the fileset into a Position value.
+func (s *FileSet) Position(p Pos) Position {
+	if f := s.File(p); f != nil {
+		return f.Position(p)
+	}
+	return Position{}
+}
@@ -0,0 +1,73 @@
+package token
+
+import "testing"
+
+func TestPosition(t *testing.T) {
+	const src = "create_parking_lot 1\n\npark KA-01-HH-1234 White\n"
+
+	fset := NewFileSet()
+	fset.AddFile("other.lot", 10)
+	f := fset.AddFile("test.lot", len(src))
+	f.SetLinesForContent([]byte(src))
+
+	tests := []struct {
+		offset int
+		pos    string
+	}{
+		{0, "test.lot:1:1"},
+		{19, "test.lot:1:20"},
+		{21, "test.lot:2:1"},
+		{22, "test.lot:3:1"},
+		{27, "test.lot:3:6"},
+		{len(src), "test.lot:3:26"},
+	}
+
+	for _, tt := range tests {
+		p := f.Pos(tt.offset)
+		if got := fset.Position(p).String(); got != tt.pos {
+			t.Errorf("position of offset %d - want: %s, got: %s", tt.offset, tt.pos, got)
+		}
+		if got := f.Offset(p); got != tt.offset {
+			t.Errorf("offset of %d - want: %d, got: %d", p, tt.offset, got)
+		}
+	}
+
+	if fset.File(NoPos) != nil {
+		t.Errorf("NoPos should not belong to any file")
+	}
+	if s := fset.Position(NoPos).String(); s != "-" {
+		t.Errorf("invalid position string - want: %s, got: %s", "-", s)
+	}
+	if s := (Position{Line: 2, Column: 3}).String(); s != "2:3" {
+		t.Errorf("invalid position string - want: %s, got: %s", "2:3", s)
+	}
+}
+
+func TestSectionPosition(t *testing.T) {
+	const src = "park KA-01-HH-1234 White\nstatus\n"
+
+	fset := NewFileSet()
+	f := fset.AddSection("test.lot", Position{Offset: 30, Line: 3, Column: 8}, len(src))
+	f.SetLinesForContent([]byte(src))
+
+	tests := []struct {
+		offset int
+		pos    string
+		off    int
+	}{
+		{0, "test.lot:3:8", 30},
+		{5, "test.lot:3:13", 35},
+		{25, "test.lot:4:1", 55},
+		{len(src), "test.lot:4:8", 62},
+	}
+
+	for _, tt := range tests {
+		pos := fset.Position(f.Pos(tt.offset))
+		if pos.String() != tt.pos {
+			t.Errorf("position of offset %d - want: %s, got: %s", tt.offset, tt.pos, pos)
+		}
+		if pos.Offset != tt.off {
+			t.Errorf("file offset of %d - want: %d, got: %d", tt.offset, tt.off, pos.Offset)
+		}
+	}
+}
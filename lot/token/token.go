@@ -1,7 +1,10 @@
 // Package token defines constants representing the lexical tokens of the LoT language.
 package token
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 // Token is the set of lexical tokens.
 type Token int
@@ -12,10 +15,35 @@ const (
 	EOF
 
 	// Identifiers and basic type literals
-	INT
-	STRING
+	INT     // 12345
+	STRING  // KA-01-HH-1234
+	QUOTED  // "KA-01-HH-"
+	VAR     // $name
+	COMMENT // # comment
+
+	// Operators and delimiters
+	ADD    // +
+	SUB    // -
+	MUL    // *
+	QUO    // /
+	REM    // %
+	ASSIGN // =
+	EQL    // ==
+	NEQ    // !=
+	LSS    // <
+	GTR    // >
+	LEQ    // <=
+	GEQ    // >=
+	LPAREN // (
+	RPAREN // )
+	LBRACE // {
+	RBRACE // }
+	COMMA  // ,
+	RANGE  // ..
+	TILDE  // ~
 
 	// Keywords
+	keyword_beg
 	CREATE_PARKING_LOT
 	PARK
 	LEAVE
@@ -23,6 +51,25 @@ const (
 	REGISTRATION_NUMBERS_FOR_CARS_WITH_COLOUR
 	SLOT_NUMBERS_FOR_CARS_WITH_COLOUR
 	SLOT_NUMBER_FOR_REGISTRATION_NUMBER
+	LET
+	IF
+	ELSE
+	FOR
+	IN
+	WHILE
+	AND
+	OR
+	NOT
+	TRUE
+	FALSE
+	DEF
+	RETURN
+	INCLUDE
+	FIND
+	COUNT
+	OCCUPANCY
+	CARS_WITH_REGISTRATION_MATCHING
+	keyword_end
 )
 
 func (tok Token) String() string {
@@ -40,8 +87,31 @@ var tokens = [...]string{
 	ILLEGAL: "ILLEGAL",
 	EOF:     "EOF",
 
-	INT:    "INT",
-	STRING: "STRING",
+	INT:     "INT",
+	STRING:  "STRING",
+	QUOTED:  "QUOTED",
+	VAR:     "VAR",
+	COMMENT: "COMMENT",
+
+	ADD:    "+",
+	SUB:    "-",
+	MUL:    "*",
+	QUO:    "/",
+	REM:    "%",
+	ASSIGN: "=",
+	EQL:    "==",
+	NEQ:    "!=",
+	LSS:    "<",
+	GTR:    ">",
+	LEQ:    "<=",
+	GEQ:    ">=",
+	LPAREN: "(",
+	RPAREN: ")",
+	LBRACE: "{",
+	RBRACE: "}",
+	COMMA:  ",",
+	RANGE:  "..",
+	TILDE:  "~",
 
 	CREATE_PARKING_LOT: "create_parking_lot",
 	PARK:               "park",
@@ -50,6 +120,24 @@ var tokens = [...]string{
 	REGISTRATION_NUMBERS_FOR_CARS_WITH_COLOUR: "registration_numbers_for_cars_with_colour",
 	SLOT_NUMBERS_FOR_CARS_WITH_COLOUR:         "slot_numbers_for_cars_with_colour",
 	SLOT_NUMBER_FOR_REGISTRATION_NUMBER:       "slot_number_for_registration_number",
+	LET:                                       "let",
+	IF:                                        "if",
+	ELSE:                                      "else",
+	FOR:                                       "for",
+	IN:                                        "in",
+	WHILE:                                     "while",
+	AND:                                       "and",
+	OR:                                        "or",
+	NOT:                                       "not",
+	TRUE:                                      "true",
+	FALSE:                                     "false",
+	DEF:                                       "def",
+	RETURN:                                    "return",
+	INCLUDE:                                   "include",
+	FIND:                                      "find",
+	COUNT:                                     "count",
+	OCCUPANCY:                                 "occupancy",
+	CARS_WITH_REGISTRATION_MATCHING:           "cars_with_registration_matching",
 }
 
 var keywords = map[string]Token{
@@ -60,12 +148,99 @@ var keywords = map[string]Token{
 	"registration_numbers_for_cars_with_colour": REGISTRATION_NUMBERS_FOR_CARS_WITH_COLOUR,
 	"slot_numbers_for_cars_with_colour":         SLOT_NUMBERS_FOR_CARS_WITH_COLOUR,
 	"slot_number_for_registration_number":       SLOT_NUMBER_FOR_REGISTRATION_NUMBER,
+	"let":                                       LET,
+	"if":                                        IF,
+	"else":                                      ELSE,
+	"for":                                       FOR,
+	"in":                                        IN,
+	"while":                                     WHILE,
+	"and":                                       AND,
+	"or":                                        OR,
+	"not":                                       NOT,
+	"true":                                      TRUE,
+	"false":                                     FALSE,
+	"def":                                       DEF,
+	"return":                                    RETURN,
+	"include":                                   INCLUDE,
+	"find":                                      FIND,
+	"count":                                     COUNT,
+	"occupancy":                                 OCCUPANCY,
+	"cars_with_registration_matching":           CARS_WITH_REGISTRATION_MATCHING,
 }
 
-// Lookup maps an identifier to its keyword token or ILLEGAL (if not a keyword).
+// Lookup maps an identifier to its keyword token or STRING (if not a keyword).
+// Keywords are case-insensitive.
 func Lookup(ident string) Token {
-	if tok, isKeyword := keywords[ident]; isKeyword {
+	if tok, isKeyword := keywords[strings.ToLower(ident)]; isKeyword {
 		return tok
 	}
 	return STRING
 }
+
+// IsKeyword reports whether tok is a keyword.
+func (tok Token) IsKeyword() bool {
+	return keyword_beg < tok && tok < keyword_end
+}
+
+// Keywords returns all keywords in the order of their tokens.
+func Keywords() []Token {
+	var toks []Token
+	for tok := keyword_beg + 1; tok < keyword_end; tok++ {
+		toks = append(toks, tok)
+	}
+	return toks
+}
+
+// LowestPrec is the precedence of non-operators. Binary operators have
+// precedence starting with 1.
+const LowestPrec = 0
+
+// Precedence returns the operator precedence of the binary
+// operator tok. If tok is not a binary operator, the result
+// is LowestPrec.
+func (tok Token) Precedence() int {
+	switch tok {
+	case OR:
+		return 1
+	case AND:
+		return 2
+	case EQL, NEQ, LSS, GTR, LEQ, GEQ:
+		return 3
+	case ADD, SUB:
+		return 4
+	case MUL, QUO, REM:
+		return 5
+	}
+	return LowestPrec
+}
+
+// IsDelimiter reports whether ch ends a bare word (STRING token).
+func IsDelimiter(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\n', '\r', '"', '#', '$', '+', '/', '%', '=', '!', '<', '>', '.', '~', '(', ')', '{', '}', ',':
+		return true
+	}
+	return false
+}
+
+// IsWord reports whether s can be written as a bare word, i.e. it is scanned
+// back as a single STRING token. Words start with a letter, which may be
+// preceded by digits.
+func IsWord(s string) bool {
+	i := 0
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	if i == len(s) {
+		return false
+	}
+	if c := s[i]; !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if IsDelimiter(s[i]) || s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return Lookup(s) == STRING
+}
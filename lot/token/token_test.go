@@ -0,0 +1,48 @@
+package token
+
+import "testing"
+
+func TestKeywords(t *testing.T) {
+	keywords := Keywords()
+	if len(keywords) != len(tokens)-int(keyword_beg)-1 {
+		t.Errorf("invalid keywords - want: %d, got: %d", len(tokens)-int(keyword_beg)-1, len(keywords))
+	}
+
+	for _, tok := range keywords {
+		if !tok.IsKeyword() {
+			t.Errorf("keyword %s - want: IsKeyword, got: not keyword", tok)
+		}
+		if got := Lookup(tok.String()); got != tok {
+			t.Errorf("lookup %q - want: %s, got: %s", tok.String(), tok, got)
+		}
+	}
+
+	for _, tok := range []Token{ILLEGAL, STRING, TILDE, keyword_beg, keyword_end} {
+		if tok.IsKeyword() {
+			t.Errorf("token %s - want: not keyword, got: IsKeyword", tok)
+		}
+	}
+}
+
+func TestIsWord(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"White", true},
+		{"KA-01-HH-1234", true},
+		{"7ABC123", true},
+		{"1", false},
+		{"", false},
+		{"-A", false},
+		{"AB12 CDE", false},
+		{"park", false},
+		{"7$", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsWord(tt.s); got != tt.want {
+			t.Errorf("IsWord(%q) - want: %t, got: %t", tt.s, tt.want, got)
+		}
+	}
+}
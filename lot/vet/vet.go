@@ -0,0 +1,231 @@
+// Package vet reports suspicious constructs of LoT programs, like parking
+// before the parking lot is created or parking cars with invalid colour.
+// Programs are checked without execution, so only literal arguments are
+// checked and the state of the parking lot is tracked only through
+// statements of the top level.
+package vet
+
+import (
+	"fmt"
+	"sort"
+
+	"parking_lot/database"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// Warning is a single problem found in the program.
+type Warning struct {
+	Pos token.Position
+	Msg string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Pos, w.Msg)
+}
+
+// Checker checks programs against validation rules of the parking lot.
+type Checker struct {
+	// Rules validate registration numbers and colours. Nil means
+	// database.DefaultRules.
+	Rules *database.Rules
+
+	// Normalizer converts literals to canonical form. Nil means
+	// normalizer of Rules colours.
+	Normalizer *database.Normalizer
+}
+
+// Check checks program with default rules.
+func Check(fset *token.FileSet, program *ast.Program) []Warning {
+	return (&Checker{}).Check(fset, program)
+}
+
+// Check checks the program parsed with fset and returns warnings in order
+// of statements.
+func (c *Checker) Check(fset *token.FileSet, program *ast.Program) []Warning {
+	v := &checker{
+		fset:       fset,
+		rules:      c.Rules,
+		normalizer: c.Normalizer,
+		procs:      make(map[string]bool),
+		capacity:   -1,
+	}
+	if v.rules == nil {
+		v.rules = database.DefaultRules
+	}
+	if v.normalizer == nil {
+		v.normalizer = &database.Normalizer{Colors: v.rules.Colors, Canonical: v.rules.Canonical}
+	}
+
+	for _, stmt := range program.Statements {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if def, ok := n.(*ast.DefStatement); ok {
+				v.procs[def.Name] = true
+			}
+			return true
+		})
+	}
+
+	v.statements(program.Statements)
+	return v.warnings
+}
+
+// checker holds the state of single check.
+type checker struct {
+	fset       *token.FileSet
+	rules      *database.Rules
+	normalizer *database.Normalizer
+	procs      map[string]bool // procedures defined in the program
+	warnings   []Warning
+
+	// state of the parking lot after the checked statements
+	created  bool                 // create_parking_lot may have been executed
+	capacity int                  // number of slots, -1 if not known
+	parked   map[string]token.Pos // registration numbers of surely parked cars
+}
+
+func (c *checker) warnf(pos token.Pos, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, Warning{Pos: c.fset.Position(pos), Msg: fmt.Sprintf(format, args...)})
+}
+
+// statements checks statements executed in order.
+func (c *checker) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		if include, ok := stmt.(*ast.IncludeStatement); ok {
+			c.statements(include.Program.Statements)
+			continue
+		}
+
+		start := len(c.warnings)
+		ast.Inspect(stmt, c.literals)
+
+		switch s := stmt.(type) {
+		case *ast.CreateParkingLotStatement:
+			c.created = true
+			c.capacity = -1
+			if n, ok := s.Number.(*ast.IntLiteral); ok {
+				c.capacity = n.Value
+			}
+			c.parked = make(map[string]token.Pos)
+		case *ast.ParkStatement:
+			c.park(s)
+			if reg, ok := s.RegistrationNumber.(*ast.StringLiteral); ok && c.parked != nil {
+				key := c.normalizer.RegistrationNumber(reg.Value)
+				if pos, ok := c.parked[key]; ok {
+					c.warnf(reg.Pos(), "car %s is already parked (%s)", key, c.fset.Position(pos))
+				} else {
+					c.parked[key] = reg.Pos()
+				}
+			}
+		case *ast.LeaveStatement:
+			c.leave(s)
+			if n, ok := s.Number.(*ast.IntLiteral); ok && c.capacity >= 0 && n.Value > c.capacity {
+				c.warnf(n.Pos(), "slot number %d out of range [1, %d]", n.Value, c.capacity)
+			}
+			c.parked = nil
+		default:
+			c.effects(stmt)
+		}
+
+		// warnings of single statement are ordered by position
+		w := c.warnings[start:]
+		sort.SliceStable(w, func(i, j int) bool {
+			return w[i].Pos.Offset < w[j].Pos.Offset
+		})
+	}
+}
+
+// effects updates the state with possible effects of compound statement.
+func (c *checker) effects(stmt ast.Statement) {
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.DefStatement:
+			return false // checked when called
+		case *ast.CreateParkingLotStatement:
+			c.created = true
+			c.capacity = -1
+			c.parked = nil
+		case *ast.ParkStatement:
+			c.park(n)
+		case *ast.LeaveStatement:
+			c.leave(n)
+			c.parked = nil
+		case *ast.CallExpr:
+			if c.procs[n.Name] {
+				// procedure may create the parking lot or leave cars
+				c.created = true
+				c.capacity = -1
+				c.parked = nil
+			}
+		case *ast.CommandStatement:
+			// handler of command may change the parking lot in any way
+			c.created = true
+			c.capacity = -1
+			c.parked = nil
+		}
+		return true
+	})
+}
+
+func (c *checker) park(s *ast.ParkStatement) {
+	if !c.created {
+		c.warnf(s.Pos(), "%s before %s", token.PARK, token.CREATE_PARKING_LOT)
+	}
+}
+
+func (c *checker) leave(s *ast.LeaveStatement) {
+	if !c.created {
+		c.warnf(s.Pos(), "%s before %s", token.LEAVE, token.CREATE_PARKING_LOT)
+	}
+}
+
+// literals checks literal arguments of the node.
+func (c *checker) literals(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.ParkStatement:
+		c.registrationNumber(n.RegistrationNumber)
+		c.colour(n.Color)
+	case *ast.LeaveStatement:
+		if x, ok := n.Number.(*ast.IntLiteral); ok && x.Value < 1 {
+			c.warnf(x.Pos(), "invalid slot number %d", x.Value)
+		}
+	case *ast.RegistrationNumbersForCarsWithColourStatement:
+		c.colour(n.Color)
+	case *ast.SlotNumbersForCarsWithColourStatement:
+		c.colour(n.Color)
+	case *ast.SlotNumberForRegistrationNumberStatement:
+		c.registrationNumber(n.RegistrationNumber)
+	case *ast.FieldPredicate:
+		for _, x := range n.Values {
+			switch {
+			case n.Field == ast.FieldColour:
+				c.colour(x)
+			case n.Op != token.TILDE:
+				c.registrationNumber(x)
+			}
+		}
+	}
+	return true
+}
+
+// registrationNumber checks literal registration number.
+func (c *checker) registrationNumber(x ast.Expr) {
+	if s, ok := x.(*ast.StringLiteral); ok {
+		if reg := c.normalizer.RegistrationNumber(s.Value); !c.rules.RegistrationNumber.MatchString(reg) {
+			c.warnf(s.Pos(), "invalid registration number %q", s.Value)
+		}
+	}
+}
+
+// colour checks literal colour.
+func (c *checker) colour(x ast.Expr) {
+	if s, ok := x.(*ast.StringLiteral); ok {
+		colour := c.normalizer.Color(s.Value)
+		for _, valid := range c.rules.Colors {
+			if colour == valid {
+				return
+			}
+		}
+		c.warnf(s.Pos(), "invalid colour %q", s.Value)
+	}
+}
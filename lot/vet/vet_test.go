@@ -0,0 +1,91 @@
+package vet
+
+import (
+	"reflect"
+	"testing"
+
+	"parking_lot/database"
+	"parking_lot/lot/parser"
+	"parking_lot/lot/token"
+)
+
+func check(t *testing.T, c *Checker, src string) []string {
+	t.Helper()
+	fset := token.NewFileSet()
+	program, err := parser.ParseFile(fset, "test.lot", []byte(src))
+	if err != nil {
+		t.Fatalf("parse %q error: %s", src, err)
+	}
+
+	var warnings []string
+	for _, w := range c.Check(fset, program) {
+		warnings = append(warnings, w.String())
+	}
+	return warnings
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		src      string
+		warnings []string
+	}{
+		{"create_parking_lot 2\npark KA-01-HH-1234 white\nleave 1\nstatus", nil},
+		{
+			"park KA-01-HH-1234 Pink\nleave 1\ncreate_parking_lot 1",
+			[]string{
+				"test.lot:1:1: park before create_parking_lot",
+				`test.lot:1:20: invalid colour "Pink"`,
+				"test.lot:2:1: leave before create_parking_lot",
+			},
+		},
+		{
+			"create_parking_lot 2\nleave 3\nleave 0\nleave $n",
+			[]string{"test.lot:2:7: slot number 3 out of range [1, 2]", "test.lot:3:7: invalid slot number 0"},
+		},
+		{
+			"create_parking_lot $n\nleave 3",
+			nil,
+		},
+		{
+			"create_parking_lot 2\npark KA-1-HH-1234 Pink\npark $r $c\nslot_numbers_for_cars_with_colour Purple",
+			[]string{
+				`test.lot:2:6: invalid registration number "KA-1-HH-1234"`,
+				`test.lot:2:19: invalid colour "Pink"`,
+				`test.lot:4:35: invalid colour "Purple"`,
+			},
+		},
+		{
+			"create_parking_lot 3\npark KA-01-HH-1234 White\npark ka-01-hh-1234 Black\nleave 1\npark KA-01-HH-1234 Red",
+			[]string{"test.lot:3:6: car KA-01-HH-1234 is already parked (test.lot:2:6)"},
+		},
+		{
+			"if true { park KA-01-HH-1234 White }\ncreate_parking_lot 1\nfor i in 1..2 { leave 5 }",
+			[]string{"test.lot:1:11: park before create_parking_lot"},
+		},
+		{
+			"def init() { create_parking_lot 1 }\ninit()\npark KA-01-HH-1234 White\ndef f() { park KA-01-HH-9999 Pink }",
+			[]string{`test.lot:4:30: invalid colour "Pink"`},
+		},
+		{
+			"create_parking_lot 1\nfind cars where colour in (White, Pink) and registration == AB and registration ~ \"AB*\"",
+			[]string{`test.lot:2:35: invalid colour "Pink"`, `test.lot:2:61: invalid registration number "AB"`},
+		},
+	}
+
+	for _, tt := range tests {
+		warnings := check(t, &Checker{}, tt.src)
+		if !reflect.DeepEqual(warnings, tt.warnings) {
+			t.Errorf("check %q - want: %q, got: %q", tt.src, tt.warnings, warnings)
+		}
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	c := &Checker{Rules: database.Presets["UK"]}
+	warnings := check(t, c, "create_parking_lot 1\npark \"ab12 cde\" silver\npark KA-01-HH-1234 White")
+
+	want := []string{`test.lot:3:6: invalid registration number "KA-01-HH-1234"`}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("check - want: %q, got: %q", want, warnings)
+	}
+}
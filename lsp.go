@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"parking_lot/config"
+	"parking_lot/lot/vet"
+	"parking_lot/lsp"
+)
+
+// runLSP runs lsp subcommand, which serves Language Server Protocol client
+// connected to stdin and stdout:
+//
+//	parking_lot lsp [-config FILE]
+func runLSP(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", "", "JSON configuration file with validation rules")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: parking_lot lsp [-config FILE]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments")
+	}
+
+	checker := &vet.Checker{}
+	if *configFile != "" {
+		c, err := config.Load(*configFile)
+		if err != nil {
+			return fmt.Errorf("config error: %s", err)
+		}
+		checker.Rules = c.Rules()
+		checker.Normalizer = c.Normalizer()
+	}
+
+	return lsp.NewServer(checker).Serve(stdin, stdout)
+}
@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"parking_lot/lot/command"
+)
+
+// doc is hover documentation of a keyword or built-in function.
+type doc struct {
+	syntax string
+	text   string
+}
+
+// keywordDocs documents keywords which are not statements by their lower
+// case spelling. Statements are documented by the command registry.
+var keywordDocs = map[string]doc{
+	"else":  {"if EXPR { ... } else { ... }", "Block executed when the condition of `if` is false."},
+	"in":    {"for NAME in EXPR..EXPR { ... }\nfind cars where FIELD in (VALUE, ...)", "Range of `for` loop or membership test of `find` condition."},
+	"and":   {"EXPR and EXPR", "True when both conditions are true."},
+	"or":    {"EXPR or EXPR", "True when any of conditions is true."},
+	"not":   {"not EXPR", "Negates condition."},
+	"true":  {"true", "Boolean true."},
+	"false": {"false", "Boolean false."},
+}
+
+// keywordDoc returns documentation of keyword, statements are documented
+// by the command registry.
+func keywordDoc(name string) (doc, bool) {
+	if cmd := command.Lookup(name); cmd != nil {
+		return doc{cmd.Syntax(), cmd.Help}, true
+	}
+	d, ok := keywordDocs[name]
+	return d, ok
+}
+
+// builtinDocs documents built-in functions.
+var builtinDocs = map[string]doc{
+	"free_slots": {"free_slots()", "Number of free parking slots."},
+	"len":        {"len(LIST)\nlen(STRING)", "Length of list or string."},
+	"contains":   {"contains(LIST, VALUE)\ncontains(STRING, SUBSTRING)", "Reports whether list contains value or string contains substring."},
+	"first":      {"first(LIST)", "First element of non-empty list."},
+}
+
+// markdown returns the documentation as markdown.
+func (d doc) markdown() string {
+	return "```lot\n" + d.syntax + "\n```\n\n" + d.text
+}
@@ -0,0 +1,94 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+)
+
+// document is a text document opened in the client.
+type document struct {
+	uri      string
+	filename string
+	version  int
+	text     string
+	lines    []int // offsets of the first characters of lines
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, filename: uriFilename(uri), version: version}
+	d.setText(text)
+	return d
+}
+
+// uriFilename returns path of file URI, other URIs are returned unchanged.
+func uriFilename(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func (d *document) setText(text string) {
+	d.text = text
+	d.lines = []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+}
+
+// lineEnd returns offset of the end of line, excluding the new line.
+func (d *document) lineEnd(line int) int {
+	if line+1 < len(d.lines) {
+		return d.lines[line+1] - 1
+	}
+	return len(d.text)
+}
+
+// offset returns byte offset of position pos. Positions past the end of
+// line or document are clamped.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+
+	offset, end := d.lines[pos.Line], d.lineEnd(pos.Line)
+	for n := 0; n < pos.Character && offset < end; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:end])
+		n += utf16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+// position returns position of byte offset.
+func (d *document) position(offset int) Position {
+	offset = max(0, min(offset, len(d.text)))
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+
+	character := 0
+	for _, r := range d.text[d.lines[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// rangeOf returns range of text between byte offsets.
+func (d *document) rangeOf(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// utf16Len returns number of UTF-16 code units of r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
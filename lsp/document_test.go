@@ -0,0 +1,54 @@
+package lsp
+
+import "testing"
+
+func TestDocumentPositions(t *testing.T) {
+	d := newDocument("file:///tmp/a%20b.lot", 1, "park\n# ĉu 😀 x\n\nstatus")
+	if d.filename != "/tmp/a b.lot" {
+		t.Errorf("invalid filename - want: %s, got: %s", "/tmp/a b.lot", d.filename)
+	}
+
+	tests := []struct {
+		offset int
+		pos    Position
+	}{
+		{0, pos(0, 0)},
+		{4, pos(0, 4)},
+		{5, pos(1, 0)},
+		{7, pos(1, 2)},
+		{9, pos(1, 3)},
+		{11, pos(1, 5)},
+		{15, pos(1, 7)},
+		{17, pos(1, 9)},
+		{18, pos(2, 0)},
+		{19, pos(3, 0)},
+		{25, pos(3, 6)},
+	}
+	for _, tt := range tests {
+		if p := d.position(tt.offset); p != tt.pos {
+			t.Errorf("position of %d - want: %+v, got: %+v", tt.offset, tt.pos, p)
+		}
+		if offset := d.offset(tt.pos); offset != tt.offset {
+			t.Errorf("offset of %+v - want: %d, got: %d", tt.pos, tt.offset, offset)
+		}
+	}
+
+	clamped := []struct {
+		pos    Position
+		offset int
+	}{
+		{pos(0, 10), 4},
+		{pos(-1, 0), 0},
+		{pos(9, 0), 25},
+		{pos(1, 6), 15},
+	}
+	for _, tt := range clamped {
+		if offset := d.offset(tt.pos); offset != tt.offset {
+			t.Errorf("offset of %+v - want: %d, got: %d", tt.pos, tt.offset, offset)
+		}
+	}
+
+	if u := uriFilename("untitled:Untitled-1"); u != "untitled:Untitled-1" {
+		t.Errorf("filename of untitled - want: %s, got: %s", "untitled:Untitled-1", u)
+	}
+}
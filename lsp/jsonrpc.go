@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError           = -32700
+	codeInvalidRequest       = -32600
+	codeMethodNotFound       = -32601
+	codeInvalidParams        = -32602
+	codeServerNotInitialized = -32002
+	codeRequestFailed        = -32803
+)
+
+// message is a JSON-RPC 2.0 request, notification or response. Requests
+// and responses have ID, notifications do not.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// ResponseError is an error of JSON-RPC response.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// conn reads and writes messages with the base protocol of LSP: every
+// message has a header with Content-Length followed by JSON content.
+type conn struct {
+	r  *textproto.Reader
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: textproto.NewReader(bufio.NewReader(r)), w: w}
+}
+
+// read reads the next message.
+func (c *conn) read() (*message, error) {
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading header: %s", err)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.r.R, content); err != nil {
+		return nil, fmt.Errorf("reading content: %s", err)
+	}
+
+	m := &message{}
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, &ResponseError{Code: codeParseError, Message: err.Error()}
+	}
+	return m, nil
+}
+
+// write writes message m, it is safe for concurrent use.
+func (c *conn) write(m *message) error {
+	m.JSONRPC = "2.0"
+	content, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(content)
+	return err
+}
+
+// reply writes response to request with given id. Result is ignored when
+// err is not nil.
+func (c *conn) reply(id json.RawMessage, result interface{}, err *ResponseError) error {
+	m := &message{ID: id, Error: err}
+	if err == nil {
+		data, merr := json.Marshal(result)
+		if merr != nil {
+			return merr
+		}
+		m.Result = data
+	}
+	return c.write(m)
+}
+
+// call writes request with given id.
+func (c *conn) call(id int, method string, params interface{}) error {
+	return c.send(json.RawMessage(strconv.Itoa(id)), method, params)
+}
+
+// notify writes notification.
+func (c *conn) notify(method string, params interface{}) error {
+	return c.send(nil, method, params)
+}
+
+func (c *conn) send(id json.RawMessage, method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{ID: id, Method: method, Params: data})
+}
@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConn(t *testing.T) {
+	var buf bytes.Buffer
+	c := newConn(&buf, &buf)
+
+	if err := c.call(1, "textDocument/hover", map[string]int{"line": 2}); err != nil {
+		t.Fatalf("call error: %s", err)
+	}
+	if err := c.notify("initialized", struct{}{}); err != nil {
+		t.Fatalf("notify error: %s", err)
+	}
+	if err := c.reply(json.RawMessage("2"), nil, &ResponseError{Code: codeMethodNotFound, Message: "not found"}); err != nil {
+		t.Fatalf("reply error: %s", err)
+	}
+
+	var want string
+	for _, content := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{"line":2}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"not found"}}`,
+	} {
+		want += fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(content), content)
+	}
+	if buf.String() != want {
+		t.Fatalf("invalid messages - want: %q, got: %q", want, buf.String())
+	}
+
+	tests := []struct {
+		method string
+		id     string
+		params string
+		err    string
+	}{
+		{"textDocument/hover", "1", `{"line":2}`, ""},
+		{"initialized", "", "{}", ""},
+		{"", "2", "", ""},
+	}
+	for _, tt := range tests {
+		m, err := c.read()
+		if err != nil {
+			t.Fatalf("read error: %s", err)
+		}
+		if m.Method != tt.method || string(m.ID) != tt.id || string(m.Params) != tt.params {
+			t.Errorf("invalid message - want: %s %s %s, got: %s %s %s", tt.method, tt.id, tt.params, m.Method, m.ID, m.Params)
+		}
+	}
+	if _, err := c.read(); err != io.EOF {
+		t.Errorf("read at end - want: %v, got: %v", io.EOF, err)
+	}
+}
+
+func TestConnErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"Content-Type: application/json\r\n\r\n{}", `invalid Content-Length ""`},
+		{"Content-Length: x\r\n\r\n{}", `invalid Content-Length "x"`},
+		{"Content-Length: 10\r\n\r\n{}", "reading content: unexpected EOF"},
+		{"Content-Length: 2\r\n\r\n{]", "invalid character ']' looking for beginning of object key string (code -32700)"},
+	}
+
+	for _, tt := range tests {
+		c := newConn(strings.NewReader(tt.input), io.Discard)
+		_, err := c.read()
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("read %q - want: %s, got: %v", tt.input, tt.err, err)
+		}
+	}
+}
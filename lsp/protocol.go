@@ -0,0 +1,159 @@
+package lsp
+
+// Types of the Language Server Protocol used by the server, see
+// https://microsoft.github.io/language-server-protocol/specification.
+
+// Position is a zero-based line and character offset in UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a text document, End is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TextDocumentIdentifier identifies a text document by its URI.
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// TextDocumentItem is a text document opened in the client.
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// VersionedTextDocumentIdentifier identifies a version of a text document.
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+// TextDocumentPositionParams are parameters of requests at a position.
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// InitializeParams are parameters of initialize request.
+type InitializeParams struct {
+	ProcessID int    `json:"processId,omitempty"`
+	RootURI   string `json:"rootUri,omitempty"`
+}
+
+// InitializeResult is the result of initialize request.
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   *ServerInfo        `json:"serverInfo,omitempty"`
+}
+
+// ServerInfo describes the server.
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Text document synchronization kinds.
+const (
+	TextDocumentSyncNone = 0
+	TextDocumentSyncFull = 1
+)
+
+// ServerCapabilities are features provided by the server.
+type ServerCapabilities struct {
+	TextDocumentSync           int                `json:"textDocumentSync"`
+	CompletionProvider         *CompletionOptions `json:"completionProvider,omitempty"`
+	HoverProvider              bool               `json:"hoverProvider"`
+	DocumentFormattingProvider bool               `json:"documentFormattingProvider"`
+}
+
+// CompletionOptions are options of completion provider.
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+// DidOpenTextDocumentParams are parameters of textDocument/didOpen.
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// DidChangeTextDocumentParams are parameters of textDocument/didChange.
+// The server synchronizes full documents, so every change holds the whole
+// text.
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// TextDocumentContentChangeEvent is a change of a text document.
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+// DidCloseTextDocumentParams are parameters of textDocument/didClose.
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// Diagnostic severities.
+const (
+	SeverityError   = 1
+	SeverityWarning = 2
+)
+
+// Diagnostic is an error or warning in a text document.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// PublishDiagnosticsParams are parameters of
+// textDocument/publishDiagnostics notification.
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Completion item kinds.
+const (
+	CompletionItemKindFunction = 3
+	CompletionItemKindKeyword  = 14
+)
+
+// CompletionItem is a completion proposal.
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// MarkupContent is a text in plaintext or markdown.
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover is the result of textDocument/hover request.
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// DocumentFormattingParams are parameters of textDocument/formatting.
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// TextEdit replaces text of a range.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
@@ -0,0 +1,355 @@
+// Package lsp implements a Language Server Protocol server of LoT. The
+// server synchronizes full documents and provides diagnostics of the parser
+// and vet, keyword completion, hover documentation and formatting.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"parking_lot/lot/command"
+	"parking_lot/lot/format"
+	"parking_lot/lot/parser"
+	"parking_lot/lot/scanner"
+	"parking_lot/lot/token"
+	"parking_lot/lot/vet"
+	"parking_lot/version"
+)
+
+// Server is a language server of LoT. A server serves a single client.
+type Server struct {
+	// Checker reports warnings of documents. Nil means vet with default
+	// rules.
+	Checker *vet.Checker
+
+	conn        *conn
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns new server which checks documents with checker.
+func NewServer(checker *vet.Checker) *Server {
+	return &Server{Checker: checker, docs: make(map[string]*document)}
+}
+
+// Serve reads messages of the client from r and writes responses and
+// notifications to w until the client sends exit notification or closes
+// r. It fails if the client exits without shutdown request.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		m, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if rerr, ok := err.(*ResponseError); ok {
+			if err := s.conn.reply(json.RawMessage("null"), nil, rerr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		if m.ID == nil {
+			s.handleNotification(m.Method, m.Params)
+			continue
+		}
+		result, rerr := s.handleRequest(m.Method, m.Params)
+		if err := s.conn.reply(m.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+// handleRequest returns result of request or its error.
+func (s *Server) handleRequest(method string, params json.RawMessage) (interface{}, *ResponseError) {
+	if method == "initialize" {
+		if s.initialized {
+			return nil, &ResponseError{Code: codeInvalidRequest, Message: "server is already initialized"}
+		}
+		s.initialized = true
+		return s.initialize(), nil
+	}
+	if !s.initialized {
+		return nil, &ResponseError{Code: codeServerNotInitialized, Message: "server is not initialized"}
+	}
+	if s.shutdown {
+		return nil, &ResponseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return s.completion(p)
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return s.hover(p)
+	case "textDocument/formatting":
+		var p DocumentFormattingParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return s.formatting(p)
+	}
+	return nil, &ResponseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+}
+
+// handleNotification handles notification, unknown notifications and
+// notifications before initialization are ignored.
+func (s *Server) handleNotification(method string, params json.RawMessage) {
+	if !s.initialized || s.shutdown {
+		return
+	}
+
+	switch method {
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if unmarshal(params, &p) == nil {
+			d := newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+			s.docs[d.uri] = d
+			s.publishDiagnostics(d)
+		}
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if unmarshal(params, &p) == nil && len(p.ContentChanges) > 0 {
+			d, ok := s.docs[p.TextDocument.URI]
+			if !ok {
+				return
+			}
+			d.version = p.TextDocument.Version
+			d.setText(p.ContentChanges[len(p.ContentChanges)-1].Text)
+			s.publishDiagnostics(d)
+		}
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if unmarshal(params, &p) == nil {
+			delete(s.docs, p.TextDocument.URI)
+			s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+				URI:         p.TextDocument.URI,
+				Diagnostics: []Diagnostic{},
+			})
+		}
+	}
+}
+
+// unmarshal decodes params into v.
+func unmarshal(params json.RawMessage, v interface{}) *ResponseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &ResponseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize() *InitializeResult {
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           TextDocumentSyncFull,
+			CompletionProvider:         &CompletionOptions{},
+			HoverProvider:              true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: &ServerInfo{Name: "parking_lot", Version: version.Version},
+	}
+}
+
+// document returns opened document with given URI.
+func (s *Server) document(uri string) (*document, *ResponseError) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &ResponseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %s is not open", uri)}
+	}
+	return d, nil
+}
+
+// publishDiagnostics sends diagnostics of document d to the client.
+func (s *Server) publishDiagnostics(d *document) {
+	s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: s.diagnostics(d),
+	})
+}
+
+// diagnostics returns syntax errors of document d or, if it is parsed,
+// warnings of vet. Problems of included files are reported at the
+// beginning of the document.
+func (s *Server) diagnostics(d *document) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	fset := token.NewFileSet()
+	program, err := parser.ParseFile(fset, d.filename, []byte(d.text))
+	if err != nil {
+		list, ok := err.(parser.ErrorList)
+		if !ok {
+			return append(diagnostics, Diagnostic{Severity: SeverityError, Source: "lot", Message: err.Error()})
+		}
+		for _, e := range list {
+			diagnostics = append(diagnostics, d.diagnostic(e.Pos, SeverityError, "lot", e.Msg))
+		}
+		return diagnostics
+	}
+
+	checker := s.Checker
+	if checker == nil {
+		checker = &vet.Checker{}
+	}
+	for _, w := range checker.Check(fset, program) {
+		diagnostics = append(diagnostics, d.diagnostic(w.Pos, SeverityWarning, "vet", w.Msg))
+	}
+	return diagnostics
+}
+
+// diagnostic returns diagnostic of the token at pos.
+func (d *document) diagnostic(pos token.Position, severity int, source, msg string) Diagnostic {
+	if pos.Filename != d.filename {
+		return Diagnostic{Severity: severity, Source: source, Message: pos.String() + ": " + msg}
+	}
+
+	end := pos.Offset
+	if start, _, lit, ok := d.tokenAt(pos.Offset); ok && start == pos.Offset {
+		end += len(lit)
+	}
+	return Diagnostic{Range: d.rangeOf(pos.Offset, end), Severity: severity, Source: source, Message: msg}
+}
+
+// tokenAt returns token of document which contains byte offset.
+func (d *document) tokenAt(offset int) (start int, tok token.Token, lit string, ok bool) {
+	s := scanner.New(d.text)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF || pos > offset {
+			return 0, token.ILLEGAL, "", false
+		}
+		if offset < pos+len(lit) {
+			return pos, tok, lit, true
+		}
+	}
+}
+
+// completion returns keywords and built-in functions starting with the word
+// before the position. Nothing is completed in comments and strings.
+func (s *Server) completion(p TextDocumentPositionParams) ([]CompletionItem, *ResponseError) {
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := d.offset(p.Position)
+	if _, tok, lit, ok := d.tokenAt(offset - 1); ok && (tok == token.COMMENT || strings.HasPrefix(lit, "\"")) {
+		return []CompletionItem{}, nil
+	}
+	start := offset
+	for start > 0 && isWordChar(d.text[start-1]) {
+		start--
+	}
+	if start > 0 && d.text[start-1] == '$' {
+		return []CompletionItem{}, nil
+	}
+	prefix := strings.ToLower(d.text[start:offset])
+
+	items := []CompletionItem{}
+	for _, tok := range token.Keywords() {
+		if name := tok.String(); strings.HasPrefix(name, prefix) {
+			dc, _ := keywordDoc(name)
+			items = append(items, CompletionItem{Label: name, Kind: CompletionItemKindKeyword, Detail: dc.syntax})
+		}
+	}
+	for _, cmd := range command.Commands() {
+		// built-in statements are keywords or free_slots, which is
+		// completed as the function
+		if cmd.Builtin {
+			continue
+		}
+		if name := strings.ToLower(cmd.Name); strings.HasPrefix(name, prefix) {
+			items = append(items, CompletionItem{Label: cmd.Name, Kind: CompletionItemKindKeyword, Detail: cmd.Syntax()})
+		}
+	}
+	var builtins []string
+	for name := range builtinDocs {
+		if strings.HasPrefix(name, prefix) {
+			builtins = append(builtins, name)
+		}
+	}
+	sort.Strings(builtins)
+	for _, name := range builtins {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionItemKindFunction, Detail: builtinDocs[name].syntax})
+	}
+	return items, nil
+}
+
+func isWordChar(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '_'
+}
+
+// hover returns documentation of the keyword or built-in function at the
+// position, nil if there is none.
+func (s *Server) hover(p TextDocumentPositionParams) (*Hover, *ResponseError) {
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	start, tok, lit, ok := d.tokenAt(d.offset(p.Position))
+	if !ok {
+		return nil, nil
+	}
+
+	var dc doc
+	switch {
+	case tok.IsKeyword():
+		dc, ok = keywordDoc(tok.String())
+	case tok == token.STRING:
+		if _, next, _ := scanner.New(d.text[start+len(lit):]).Scan(); next == token.LPAREN {
+			dc, ok = builtinDocs[lit]
+		} else {
+			dc, ok = keywordDoc(lit)
+		}
+	default:
+		ok = false
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	r := d.rangeOf(start, start+len(lit))
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: dc.markdown()}, Range: &r}, nil
+}
+
+// formatting returns edit replacing the document with its canonical format.
+func (s *Server) formatting(p DocumentFormattingParams) ([]TextEdit, *ResponseError) {
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ferr := format.Source(d.filename, []byte(d.text))
+	if ferr != nil {
+		return nil, &ResponseError{Code: codeRequestFailed, Message: ferr.Error()}
+	}
+	if string(out) == d.text {
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{Range: d.rangeOf(0, len(d.text)), NewText: string(out)}}, nil
+}
@@ -0,0 +1,381 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"parking_lot/database"
+	"parking_lot/lot/command"
+	"parking_lot/lot/vet"
+)
+
+const testURI = "file:///tmp/test.lot"
+
+// client is an in-process LSP client connected with the server by pipes.
+type client struct {
+	t    *testing.T
+	conn *conn
+	id   int
+	done chan error
+}
+
+// startServer starts server and returns its client, the server is not
+// initialized.
+func startServer(t *testing.T, checker *vet.Checker) *client {
+	t.Helper()
+	serverR, clientW := io.Pipe()
+	clientR, serverW := io.Pipe()
+
+	c := &client{t: t, conn: newConn(clientR, clientW), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(checker).Serve(serverR, serverW)
+		serverW.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { clientW.Close() })
+	return c
+}
+
+// initialize starts and initializes server.
+func initialize(t *testing.T, checker *vet.Checker) *client {
+	t.Helper()
+	c := startServer(t, checker)
+	if err := c.call("initialize", &InitializeParams{}, nil); err != nil {
+		t.Fatalf("initialize error: %s", err)
+	}
+	c.notify("initialized", struct{}{})
+	return c
+}
+
+// call sends request and decodes its result into result.
+func (c *client) call(method string, params, result interface{}) error {
+	c.t.Helper()
+	c.id++
+	if err := c.conn.call(c.id, method, params); err != nil {
+		c.t.Fatalf("call %s error: %s", method, err)
+	}
+
+	m := c.read()
+	if m.Method != "" || string(m.ID) != string(mustMarshal(c.t, c.id)) {
+		c.t.Fatalf("call %s - want: response %d, got: %s %s", method, c.id, m.Method, m.ID)
+	}
+	if m.Error != nil {
+		return m.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(m.Result, result); err != nil {
+			c.t.Fatalf("call %s result %s error: %s", method, m.Result, err)
+		}
+	}
+	return nil
+}
+
+// notify sends notification.
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatalf("notify %s error: %s", method, err)
+	}
+}
+
+func (c *client) read() *message {
+	c.t.Helper()
+	m, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("read error: %s", err)
+	}
+	return m
+}
+
+// diagnostics reads published diagnostics.
+func (c *client) diagnostics() *PublishDiagnosticsParams {
+	c.t.Helper()
+	m := c.read()
+	if m.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("invalid notification - want: textDocument/publishDiagnostics, got: %s", m.Method)
+	}
+	p := &PublishDiagnosticsParams{}
+	if err := json.Unmarshal(m.Params, p); err != nil {
+		c.t.Fatalf("diagnostics %s error: %s", m.Params, err)
+	}
+	return p
+}
+
+// open opens document with testURI and returns its diagnostics.
+func (c *client) open(text string) []Diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "lot", Version: 1, Text: text},
+	})
+	return c.diagnostics().Diagnostics
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func pos(line, character int) Position {
+	return Position{Line: line, Character: character}
+}
+
+func rng(line, start, end int) Range {
+	return Range{Start: pos(line, start), End: pos(line, end)}
+}
+
+func TestServerLifecycle(t *testing.T) {
+	c := startServer(t, nil)
+
+	err := c.call("shutdown", nil, nil)
+	if rerr, ok := err.(*ResponseError); !ok || rerr.Code != codeServerNotInitialized {
+		t.Errorf("request before initialize - want: code %d, got: %v", codeServerNotInitialized, err)
+	}
+
+	var result InitializeResult
+	if err := c.call("initialize", &InitializeParams{RootURI: "file:///tmp"}, &result); err != nil {
+		t.Fatalf("initialize error: %s", err)
+	}
+	want := ServerCapabilities{
+		TextDocumentSync:           TextDocumentSyncFull,
+		CompletionProvider:         &CompletionOptions{},
+		HoverProvider:              true,
+		DocumentFormattingProvider: true,
+	}
+	if !reflect.DeepEqual(result.Capabilities, want) {
+		t.Errorf("invalid capabilities - want: %+v, got: %+v", want, result.Capabilities)
+	}
+	if result.ServerInfo == nil || result.ServerInfo.Name != "parking_lot" {
+		t.Errorf("invalid server info - want: parking_lot, got: %+v", result.ServerInfo)
+	}
+
+	err = c.call("textDocument/definition", &TextDocumentPositionParams{}, nil)
+	if rerr, ok := err.(*ResponseError); !ok || rerr.Code != codeMethodNotFound {
+		t.Errorf("unknown method - want: code %d, got: %v", codeMethodNotFound, err)
+	}
+	err = c.call("textDocument/hover", &TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, nil)
+	if rerr, ok := err.(*ResponseError); !ok || rerr.Code != codeInvalidParams {
+		t.Errorf("hover of closed document - want: code %d, got: %v", codeInvalidParams, err)
+	}
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatalf("shutdown error: %s", err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("serve error: %s", err)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := initialize(t, nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err == nil || err.Error() != "exit without shutdown" {
+		t.Errorf("serve error - want: exit without shutdown, got: %v", err)
+	}
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	tests := []struct {
+		text        string
+		diagnostics []Diagnostic
+	}{
+		{"create_parking_lot 1\npark KA-01-HH-1234 White\n", []Diagnostic{}},
+		{
+			"create_parking_lot 1\nleave\n",
+			[]Diagnostic{{Range: rng(2, 0, 0), Severity: SeverityError, Source: "lot", Message: `unexpected token "", expecting expression`}},
+		},
+		{
+			"create_parking_lot 1\n  let 12 = 2",
+			[]Diagnostic{{Range: rng(1, 6, 8), Severity: SeverityError, Source: "lot", Message: `unexpected token "12", expecting "STRING"`}},
+		},
+		{
+			"park KA-01-HH-1234 White\ncreate_parking_lot 2\n# ĉu €\npark \"KA-01-HH-9999\" Pink",
+			[]Diagnostic{
+				{Range: rng(0, 0, 4), Severity: SeverityWarning, Source: "vet", Message: "park before create_parking_lot"},
+				{Range: rng(3, 21, 25), Severity: SeverityWarning, Source: "vet", Message: `invalid colour "Pink"`},
+			},
+		},
+		{
+			"include \"missing.lot\"",
+			[]Diagnostic{{Range: rng(0, 0, 7), Severity: SeverityError, Source: "lot", Message: `include "missing.lot": open /tmp/missing.lot: no such file or directory`}},
+		},
+	}
+
+	for _, tt := range tests {
+		c := initialize(t, nil)
+		if diagnostics := c.open(tt.text); !reflect.DeepEqual(diagnostics, tt.diagnostics) {
+			t.Errorf("diagnostics of %q - want: %+v, got: %+v", tt.text, tt.diagnostics, diagnostics)
+		}
+	}
+}
+
+func TestServerDiagnosticsChange(t *testing.T) {
+	c := initialize(t, &vet.Checker{Rules: database.Presets["UK"]})
+	if diagnostics := c.open("create_parking_lot 1\npark \"AB12 CDE\" Silver"); len(diagnostics) != 0 {
+		t.Errorf("diagnostics - want: none, got: %+v", diagnostics)
+	}
+
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "create_parking_lot 1\npark KA-01-HH-1234 Silver"}},
+	})
+	p := c.diagnostics()
+	want := []Diagnostic{{Range: rng(1, 5, 18), Severity: SeverityWarning, Source: "vet", Message: `invalid registration number "KA-01-HH-1234"`}}
+	if p.URI != testURI || p.Version != 2 || !reflect.DeepEqual(p.Diagnostics, want) {
+		t.Errorf("diagnostics of change - want: %s 2 %+v, got: %s %d %+v", testURI, want, p.URI, p.Version, p.Diagnostics)
+	}
+
+	c.notify("textDocument/didClose", &DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: testURI}})
+	if p := c.diagnostics(); p.URI != testURI || len(p.Diagnostics) != 0 {
+		t.Errorf("diagnostics of close - want: none, got: %+v", p.Diagnostics)
+	}
+}
+
+func TestServerCompletion(t *testing.T) {
+	const text = "cre\nif free_slots() > 0 { le }\n# pa\npark \"p\n$c\n"
+
+	tests := []struct {
+		pos    Position
+		labels []string
+	}{
+		{pos(0, 3), []string{"create_parking_lot"}},
+		{pos(0, 1), []string{"create_parking_lot", "count", "cars_with_registration_matching", "contains"}},
+		{pos(1, 4), []string{"for", "false", "find", "first", "free_slots"}},
+		{pos(1, 6), []string{"free_slots"}},
+		{pos(1, 24), []string{"leave", "let", "len"}},
+		{pos(2, 4), nil},
+		{pos(3, 7), nil},
+		{pos(4, 2), nil},
+	}
+
+	c := initialize(t, nil)
+	c.open(text)
+	for _, tt := range tests {
+		var items []CompletionItem
+		err := c.call("textDocument/completion", &TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}, Position: tt.pos}, &items)
+		if err != nil {
+			t.Fatalf("completion error: %s", err)
+		}
+
+		var labels []string
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		if !reflect.DeepEqual(labels, tt.labels) {
+			t.Errorf("completion at %+v - want: %q, got: %q", tt.pos, tt.labels, labels)
+		}
+	}
+}
+
+func TestServerHover(t *testing.T) {
+	const text = "Park KA-01-HH-1234 White\nif free_slots() > 0 { free_slots }\n# status"
+
+	tests := []struct {
+		pos    Position
+		syntax string
+		rng    Range
+	}{
+		{pos(0, 0), "park REGISTRATION COLOUR", rng(0, 0, 4)},
+		{pos(0, 3), "park REGISTRATION COLOUR", rng(0, 0, 4)},
+		{pos(0, 4), "", Range{}},
+		{pos(0, 10), "", Range{}},
+		{pos(1, 0), "if EXPR { ... } else { ... }", rng(1, 0, 2)},
+		{pos(1, 5), "free_slots()", rng(1, 3, 13)},
+		{pos(1, 25), "free_slots", rng(1, 22, 32)},
+		{pos(2, 4), "", Range{}},
+	}
+
+	c := initialize(t, nil)
+	c.open(text)
+	for _, tt := range tests {
+		var hover *Hover
+		err := c.call("textDocument/hover", &TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}, Position: tt.pos}, &hover)
+		if err != nil {
+			t.Fatalf("hover error: %s", err)
+		}
+
+		if tt.syntax == "" {
+			if hover != nil {
+				t.Errorf("hover at %+v - want: none, got: %+v", tt.pos, hover)
+			}
+			continue
+		}
+		if hover == nil || !strings.HasPrefix(hover.Contents.Value, "```lot\n"+tt.syntax+"\n```") || hover.Range == nil || *hover.Range != tt.rng {
+			t.Errorf("hover at %+v - want: %s %+v, got: %+v", tt.pos, tt.syntax, tt.rng, hover)
+		}
+	}
+}
+
+func TestServerCommand(t *testing.T) {
+	command.Register(&command.Command{
+		Name:    "open_barrier",
+		Args:    []command.Arg{{Name: "GATE", Kind: command.Int}},
+		Help:    "Opens barrier of the gate.",
+		Handler: func(lot command.Lot, args []interface{}) (interface{}, error) { return nil, nil },
+	})
+	defer command.Unregister("open_barrier")
+
+	c := initialize(t, nil)
+	c.open("open_barrier 1\no")
+
+	var items []CompletionItem
+	if err := c.call("textDocument/completion", &TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}, Position: pos(1, 1)}, &items); err != nil {
+		t.Fatalf("completion error: %s", err)
+	}
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	if want := []string{"or", "occupancy", "open_barrier"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("invalid completion - want: %q, got: %q", want, labels)
+	}
+
+	var hover *Hover
+	if err := c.call("textDocument/hover", &TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}, Position: pos(0, 2)}, &hover); err != nil {
+		t.Fatalf("hover error: %s", err)
+	}
+	want := "```lot\nopen_barrier GATE\n```\n\nOpens barrier of the gate."
+	if hover == nil || hover.Contents.Value != want {
+		t.Errorf("invalid hover - want: %q, got: %+v", want, hover)
+	}
+}
+
+func TestServerFormatting(t *testing.T) {
+	c := initialize(t, nil)
+	c.open("CREATE_PARKING_LOT 1\n\n\nif true {   status }")
+
+	params := &DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}}
+	var edits []TextEdit
+	if err := c.call("textDocument/formatting", params, &edits); err != nil {
+		t.Fatalf("formatting error: %s", err)
+	}
+	want := []TextEdit{{Range: Range{Start: pos(0, 0), End: pos(3, 20)}, NewText: "create_parking_lot 1\n\nif true {\n\tstatus\n}\n"}}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("formatting - want: %+v, got: %+v", want, edits)
+	}
+
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: want[0].NewText}},
+	})
+	c.diagnostics()
+	if err := c.call("textDocument/formatting", params, &edits); err != nil || len(edits) != 0 {
+		t.Errorf("formatting of formatted document - want: no edits, got: %+v %v", edits, err)
+	}
+
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "leave"}},
+	})
+	c.diagnostics()
+	err := c.call("textDocument/formatting", params, &edits)
+	if rerr, ok := err.(*ResponseError); !ok || rerr.Code != codeRequestFailed {
+		t.Errorf("formatting of invalid document - want: code %d, got: %v", codeRequestFailed, err)
+	}
+}
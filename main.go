@@ -1,18 +1,22 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"parking_lot/config"
 	"parking_lot/database"
+	"parking_lot/event"
 	"parking_lot/exec"
 	"parking_lot/lot/parser"
 	"parking_lot/shell"
 	"parking_lot/version"
+	"parking_lot/webhook"
 )
 
 // Storage types.
@@ -26,6 +30,10 @@ var (
 	storage      = flag.String("storage", MemoryStorage, "type of storage [memory|file]")
 	storageFile  = flag.String("storage-file", "", "file to store database")
 	printVersion = flag.Bool("version", false, "print version and exit")
+	dryRun       = flag.Bool("dry-run", false, "run without saving changes to storage and print changed slots")
+	configFile   = flag.String("config", "", "JSON configuration file (validation preset, registration format, colours and colour aliases)")
+	failFast     = flag.Bool("fail-fast", false, "stop at the first failed statement")
+	output       = flag.String("output", exec.FormatText, "output format ["+strings.Join(exec.Formats, "|")+"]")
 	sourceFile   string
 )
 
@@ -33,12 +41,12 @@ var (
 func parseFlags() error {
 	flag.Parse()
 
-	if len(os.Args) > 2 {
+	if flag.NArg() > 1 {
 		return fmt.Errorf("give only one source file")
 	}
 
-	if len(os.Args) == 2 {
-		sourceFile = os.Args[1]
+	if flag.NArg() == 1 {
+		sourceFile = flag.Arg(0)
 		// COMMENT for tests
 		// if !strings.HasSuffix(sourceFile, ".lot") {
 		// 	return fmt.Errorf("file %s is not lot source file", filepath.Base(sourceFile))
@@ -50,6 +58,10 @@ func parseFlags() error {
 		f.Close()
 	}
 
+	if _, err := exec.NewRenderer(*output, nil, nil); err != nil {
+		return err
+	}
+
 	if *storage != MemoryStorage && *storage != FileStorage {
 		return fmt.Errorf("invalid %q storage type: use \"memory\" or \"file\"", *storage)
 	}
@@ -65,65 +77,169 @@ func parseFlags() error {
 	return nil
 }
 
-// initDatabase creates database based on set flags
-func initDatabase() (*database.Database, error) {
-	var db *database.Database
+// initDatabase creates database based on set flags. In dry run the
+// returned overlay holds changes of the storage. The returned dispatcher
+// delivers events to webhooks of the configuration, it is nil if there are
+// none or in dry run.
+func initDatabase() (*database.Database, *database.OverlayWriter, *webhook.Dispatcher, error) {
+	var w database.Writer
 
 	if *storage == MemoryStorage {
-		db = database.NewDatabase(database.NewMemoryWriter())
+		w = database.NewMemoryWriter()
 	} else if *storage == FileStorage {
-		w, err := database.NewFileWriter(*storageFile)
+		fw, err := database.NewFileWriter(*storageFile)
 		if err != nil {
-			return nil, fmt.Errorf("creating file storage error: %s", err)
+			return nil, nil, nil, fmt.Errorf("creating file storage error: %w", err)
 		}
-		db = database.NewDatabase(w)
+		w = fw
+	}
+
+	var overlay *database.OverlayWriter
+	if *dryRun {
+		overlay = database.NewOverlayWriter(w)
+		w = overlay
 	}
-	return db, nil
+	db := database.NewDatabase(w)
+	db.Events = event.NewBus()
+
+	var dispatcher *webhook.Dispatcher
+	if *configFile != "" {
+		c, err := config.Load(*configFile)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("config error: %w", err)
+		}
+		c.Configure(db)
+
+		// changes of dry run are not published
+		if len(c.Webhooks) > 0 && !*dryRun {
+			if dispatcher, err = startWebhooks(c, db.Events); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	return db, overlay, dispatcher, nil
 }
 
-// processSourceFile parse and process lot source file.
-func processSourceFile(db *database.Database) error {
+// startWebhooks starts delivery of events to webhooks of the configuration.
+func startWebhooks(c *config.Config, events *event.Bus) (*webhook.Dispatcher, error) {
+	outbox := webhook.NewOutbox()
+	if c.WebhookOutbox != "" {
+		var err error
+		if outbox, err = webhook.OpenOutbox(c.WebhookOutbox); err != nil {
+			return nil, err
+		}
+	}
+
+	d := webhook.NewDispatcher(c.Webhooks, outbox)
+	events.Subscribe(d.Handle, event.Kinds...)
+	d.Start()
+	return d, nil
+}
+
+// newExecutor creates executor publishing results on events of the
+// database. Results are printed in the output format by a subscriber.
+func newExecutor(db *database.Database) *exec.Executor {
 	e := exec.NewExecutor()
+	e.Renderer, _ = exec.NewRenderer(*output, e.Stdout, e.Stderr)
+	e.Events = db.Events
+	e.Events.Subscribe(exec.RenderHandler(e.Renderer), exec.KindResult)
+	return e
+}
 
-	content, err := ioutil.ReadFile(sourceFile)
+// processSourceFile parses and processes lot source file statement by
+// statement as it is read, so large files are not loaded in memory.
+// Processing stops at the first syntax error and, with --fail-fast, at
+// the first failed statement. Failed statements are returned as
+// failuresError.
+func processSourceFile(db *database.Database) error {
+	f, err := os.Open(sourceFile)
 	if err != nil {
 		return fmt.Errorf("reading source code error: %s", err)
 	}
+	defer f.Close()
 
-	program, err := parser.Parse(string(content))
-	if err != nil {
-		return fmt.Errorf("parsing source code error: %s", err)
-	}
+	run := &runner{e: newExecutor(db), db: db, failFast: *failFast}
+	r := parser.NewReader(nil, sourceFile, f)
+	for {
+		stmt, err := r.Next()
+		if err == io.EOF {
+			return run.err()
+		}
+		if _, ok := err.(parser.ErrorList); ok {
+			return errors.Join(fmt.Errorf("parsing source code error: %w", err), run.err())
+		}
+		if err != nil {
+			return fmt.Errorf("reading source code error: %s", err)
+		}
 
-	e.Execute(program, db)
-	return nil
+		run.e.FileSet = r.FileSet()
+		if !run.execute(stmt, r.Position(stmt.Pos())) {
+			return run.err()
+		}
+	}
 }
 
-// startShell starts interactive shell for processing lot source.
+// startShell starts interactive shell for processing lot source. Statements
+// may span lines, a syntax error skips the rest of the line. Piped input is
+// processed as a source file, except that syntax errors do not stop it and
+// statements complete at the end of line are executed as they arrive.
 func startShell(db *database.Database) error {
-	e := exec.NewExecutor()
-
 	shell := shell.NewShell()
-	for {
-		line, err := shell.ReadLine()
-		if err != nil {
-			if err != io.EOF {
-				return err
-			}
+	r := parser.NewReader(nil, "", shell)
+	// the next line may not be typed or piped yet
+	r.Interactive = true
+	interactive := shell.Interactive()
+
+	run := &runner{e: newExecutor(db), db: db, failFast: *failFast && !interactive}
+	result := func() error {
+		if interactive {
 			return nil
 		}
-
-		program, err := parser.Parse(line)
-		if err != nil {
+		return run.err()
+	}
+	for {
+		stmt, err := r.Next()
+		if err == io.EOF {
+			return result()
+		}
+		if _, ok := err.(parser.ErrorList); ok {
 			fmt.Fprintf(os.Stderr, "parsing error: %s\n", err)
+			if !run.fail(err) {
+				return result()
+			}
 			continue
 		}
+		if err != nil {
+			return err
+		}
 
-		e.Execute(program, db)
+		run.e.FileSet = r.FileSet()
+		if !run.execute(stmt, r.Position(stmt.Pos())) && !interactive {
+			return result()
+		}
 	}
 }
 
+// subcommands are run by the first program argument instead of a source file.
+var subcommands = map[string]func(args []string) error{
+	"fmt": func(args []string) error { return runFmt(args, os.Stdout, os.Stderr) },
+	"vet": func(args []string) error { return runVet(args, os.Stderr) },
+	"lsp": func(args []string) error { return runLSP(args, os.Stdin, os.Stdout, os.Stderr) },
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				if err != flag.ErrHelp {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	if err := parseFlags(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -134,10 +250,10 @@ func main() {
 		os.Exit(0)
 	}
 
-	db, err := initDatabase()
+	db, overlay, dispatcher, err := initDatabase()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 
 	if sourceFile != "" {
@@ -145,8 +261,25 @@ func main() {
 	} else {
 		err = startShell(db)
 	}
+	// async subscribers handle events of all executed statements
+	if closeErr := db.Events.Close(); err == nil {
+		err = closeErr
+	}
+	// failed deliveries do not fail the program, they are reported and
+	// left in the outbox
+	if dispatcher != nil {
+		if webhookErr := dispatcher.Close(); webhookErr != nil {
+			fmt.Fprintln(os.Stderr, webhookErr)
+		}
+	}
+	// changes of executed statements are printed also when some failed
+	if overlay != nil {
+		if dryRunErr := printDryRun(os.Stdout, overlay); err == nil {
+			err = dryRunErr
+		}
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
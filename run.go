@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"parking_lot/database"
+	lerrors "parking_lot/errors"
+	"parking_lot/exec"
+	"parking_lot/lot/ast"
+	"parking_lot/lot/token"
+)
+
+// Exit codes of the program.
+const (
+	exitError        = 1 // invalid flags or configuration, or other error
+	exitParseError   = 2 // syntax error in the source
+	exitRuntimeError = 3 // failed statement
+	exitStorageError = 4 // failed storage
+)
+
+// exitCode returns the exit code of error returned by the program.
+func exitCode(err error) int {
+	switch lerrors.CodeOf(err) {
+	case "", lerrors.InvalidConfig:
+		return exitError
+	case lerrors.SyntaxError:
+		return exitParseError
+	case lerrors.StorageError:
+		return exitStorageError
+	}
+	return exitRuntimeError
+}
+
+// failure is an error of statement at position, if it is known.
+type failure struct {
+	pos token.Position
+	err error
+}
+
+// failuresError is returned when statements of a program fail. It lists
+// errors of the failed statements. Statements are counted as executed, so
+// statements of loops, procedures and included files count once per
+// execution, as their errors do.
+type failuresError struct {
+	failures   []failure
+	statements int // number of executed statements
+}
+
+func (e *failuresError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d statements failed", len(e.failures), e.statements)
+	for _, f := range e.failures {
+		b.WriteString("\n")
+		if f.pos.IsValid() {
+			fmt.Fprintf(&b, "%s: ", f.pos)
+		}
+		b.WriteString(f.err.Error())
+	}
+	return b.String()
+}
+
+// ErrorCode returns the code of the most severe failure: STORAGE_ERROR,
+// SYNTAX_ERROR, or EXECUTION_FAILED.
+func (e *failuresError) ErrorCode() lerrors.Code {
+	code := lerrors.ExecutionFailed
+	for _, f := range e.failures {
+		switch lerrors.CodeOf(f.err) {
+		case lerrors.StorageError:
+			return lerrors.StorageError
+		case lerrors.SyntaxError:
+			code = lerrors.SyntaxError
+		}
+	}
+	return code
+}
+
+// runner executes statements of a program and records failed ones.
+type runner struct {
+	e        *exec.Executor
+	db       *database.Database
+	failFast bool
+
+	failures   []failure
+	statements int
+}
+
+// execute executes stmt at pos and reports whether the program goes on.
+// It stops after a statement which aborts the program or, in fail fast
+// mode, after the first failed statement. Failures are reported at
+// positions of the failed statements, which may be nested in stmt or
+// included by it; pos is used if the position is unknown.
+func (r *runner) execute(stmt ast.Statement, pos token.Position) bool {
+	results, err := r.e.ExecuteStatement(stmt, r.db)
+	r.statements += r.e.Steps()
+
+	failed := false
+	for _, result := range results {
+		result, ok := result.(exec.Error)
+		if !ok {
+			continue
+		}
+
+		f := failure{pos, result.Err}
+		if result.Pos.IsValid() {
+			f.pos = result.Pos
+		}
+		// the position of the failure replaces the one in the message
+		if fe, ok := f.err.(*exec.FileError); ok {
+			f.err = fe.Err
+		}
+		r.failures = append(r.failures, f)
+		failed = true
+	}
+	return err == nil && !(failed && r.failFast)
+}
+
+// fail records syntax error of a statement and reports whether the program
+// goes on. Syntax errors hold their positions.
+func (r *runner) fail(err error) bool {
+	r.statements++
+	r.failures = append(r.failures, failure{err: err})
+	return !r.failFast
+}
+
+// err returns failuresError of failed statements, or nil.
+func (r *runner) err() error {
+	if len(r.failures) == 0 {
+		return nil
+	}
+	return &failuresError{failures: r.failures, statements: r.statements}
+}
@@ -6,6 +6,9 @@ import (
 	"io"
 	"os"
 	"strings"
+	"text/tabwriter"
+
+	"parking_lot/lot/command"
 )
 
 const (
@@ -15,6 +18,7 @@ const (
 	// builtin functions.
 	exitFn    = "exit"
 	historyFn = "history"
+	helpFn    = "help"
 )
 
 // Shell (lots) handles interactive user input.
@@ -23,6 +27,8 @@ type Shell struct {
 	scanner *bufio.Scanner
 	history []string
 	isPipe  bool
+
+	line []byte // rest of the line being read by Read
 }
 
 // NewShell creates new shell with standard stdin and stdout.
@@ -58,6 +64,10 @@ func (s *Shell) ReadLine() (string, error) {
 			fmt.Fprintln(s.stdout, strings.Join(s.history, "\n"))
 			s.prompt()
 			continue
+		case helpFn:
+			s.help()
+			s.prompt()
+			continue
 		case "":
 			s.prompt()
 			continue
@@ -72,3 +82,40 @@ func (s *Shell) ReadLine() (string, error) {
 	}
 	return "", io.EOF
 }
+
+// help prints shell functions and statements, built-in and registered
+// commands. Syntax of statements is long, so their help is on the next
+// line.
+func (s *Shell) help() {
+	tw := tabwriter.NewWriter(s.stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintf(tw, "%s\tQuits the shell.\n", exitFn)
+	fmt.Fprintf(tw, "%s\tPrints typed lines.\n", historyFn)
+	fmt.Fprintf(tw, "%s\tPrints this help.\n", helpFn)
+	tw.Flush()
+
+	fmt.Fprintln(s.stdout, "\nStatements:")
+	for _, cmd := range command.Commands() {
+		fmt.Fprintf(s.stdout, "%s\n    %s\n", cmd.Syntax(), cmd.Help)
+	}
+}
+
+// Read reads shell input line by line, so a shell can be read by
+// parser.Reader. Each Read call returns at most one line, with new line
+// character, and reading the next line shows the prompt.
+func (s *Shell) Read(p []byte) (int, error) {
+	if len(s.line) == 0 {
+		line, err := s.ReadLine()
+		if err != nil {
+			return 0, err
+		}
+		s.line = []byte(line + "\n")
+	}
+	n := copy(p, s.line)
+	s.line = s.line[n:]
+	return n, nil
+}
+
+// Interactive reports whether the shell input is typed by user, not piped.
+func (s *Shell) Interactive() bool {
+	return !s.isPipe
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"parking_lot/lot/command"
 )
 
 func newShellWithInput(input string) (*bytes.Buffer, io.ReadCloser, *Shell) {
@@ -86,3 +88,64 @@ func TestHisotryFn(t *testing.T) {
 		t.Errorf("history failed - want: %s, got: %s", "1\n2\n", line)
 	}
 }
+
+func TestHelpFn(t *testing.T) {
+	command.Register(&command.Command{
+		Name:    "open_barrier",
+		Args:    []command.Arg{{Name: "GATE", Kind: command.Int}},
+		Help:    "Opens barrier of the gate.",
+		Handler: func(lot command.Lot, args []interface{}) (interface{}, error) { return nil, nil },
+	})
+	defer command.Unregister("open_barrier")
+
+	out, _, s := newShellWithInput("help\nstatus\n")
+	if line, err := s.ReadLine(); err != nil || line != "status" {
+		t.Fatalf("read line - want: status, got: %q, %v", line, err)
+	}
+
+	want := "$ exit       Quits the shell.\n" +
+		"history    Prints typed lines.\n" +
+		"help       Prints this help.\n" +
+		"\nStatements:\n"
+	if !strings.HasPrefix(out.String(), want) || !strings.HasSuffix(out.String(), "\n$ ") {
+		t.Errorf("invalid help:\n\twant prefix: %q\n\t got: %q", want, out.String())
+	}
+	for _, statement := range []string{
+		"create_parking_lot CAPACITY\n    Creates parking lot with given number of slots, existing cars are removed.\n",
+		"free_slots\n    Prints number of free slots.\n",
+		"open_barrier GATE\n    Opens barrier of the gate.\n",
+	} {
+		if !strings.Contains(out.String(), statement) {
+			t.Errorf("help does not contain %q", statement)
+		}
+	}
+	for _, line := range s.history {
+		if line == helpFn {
+			t.Errorf("help is saved in history")
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	out, _, s := newShellWithInput("status\n\nhistory\nleave 1\nexit\nstatus\n")
+
+	var got []string
+	p := make([]byte, 4)
+	for {
+		n, err := s.Read(p)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read error: %s", err)
+		}
+		got = append(got, string(p[:n]))
+	}
+
+	if want := "stat|us\n|leav|e 1\n"; strings.Join(got, "|") != want {
+		t.Errorf("invalid reads - want: %q, got: %q", want, strings.Join(got, "|"))
+	}
+	if !strings.HasSuffix(out.String(), "Goodbye!\n") {
+		t.Errorf("exit not handled - got: %q", out.String())
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"parking_lot/config"
+	"parking_lot/lot/parser"
+	"parking_lot/lot/token"
+	"parking_lot/lot/vet"
+)
+
+// runVet runs vet subcommand, which reports suspicious constructs of lot
+// source files:
+//
+//	parking_lot vet [-config FILE] FILE...
+//
+// Warnings are printed to stderr, it fails if there is any warning.
+func runVet(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("vet", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", "", "JSON configuration file with validation rules")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: parking_lot vet [-config FILE] FILE...\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no source files")
+	}
+
+	checker := &vet.Checker{}
+	if *configFile != "" {
+		c, err := config.Load(*configFile)
+		if err != nil {
+			return fmt.Errorf("config error: %s", err)
+		}
+		checker.Rules = c.Rules()
+		checker.Normalizer = c.Normalizer()
+	}
+
+	failed := false
+	for _, file := range fs.Args() {
+		fset := token.NewFileSet()
+		program, err := parser.ParseFile(fset, file, nil)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			failed = true
+			continue
+		}
+
+		for _, w := range checker.Check(fset, program) {
+			fmt.Fprintln(stderr, w)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("vet failed")
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"parking_lot/event"
+)
+
+// Defaults of Dispatcher.
+const (
+	// DefaultMaxAttempts is the default number of attempts of a delivery.
+	DefaultMaxAttempts = 5
+
+	// DefaultBackoff is the default delay before the first retry.
+	DefaultBackoff = 500 * time.Millisecond
+
+	// DefaultMaxBackoff is the default limit of delay between retries.
+	DefaultMaxBackoff = 30 * time.Second
+
+	// DefaultTimeout is the default timeout of a request.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultDrainTimeout is the default time Close waits for pending
+	// deliveries.
+	DefaultDrainTimeout = 2 * time.Second
+)
+
+// Dispatcher delivers events to hooks. Handle stores deliveries of an event
+// in the outbox and the worker started by Start syncs the outbox and posts
+// them in order.
+//
+// A failed delivery is retried with exponential backoff: the delay starts
+// at Backoff and doubles up to MaxBackoff. Later deliveries to the same
+// hook wait for it, so the hook receives events in order, while other
+// hooks are served. Deliveries rejected by the endpoint are dropped.
+// Close gives failing deliveries MaxAttempts attempts, but waits at most
+// DrainTimeout; deliveries still failing or not posted yet stay in the
+// outbox and are retried after restart.
+type Dispatcher struct {
+	Hooks  []Hook
+	Outbox *Outbox
+
+	// Client posts payloads. Nil means client with DefaultTimeout.
+	Client *http.Client
+
+	// MaxAttempts limits attempts of a delivery Close waits for. Zero
+	// means DefaultMaxAttempts.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. Zero means
+	// DefaultBackoff.
+	Backoff time.Duration
+
+	// MaxBackoff limits delay between retries. Zero means
+	// DefaultMaxBackoff.
+	MaxBackoff time.Duration
+
+	// DrainTimeout limits the time Close waits for pending deliveries,
+	// requests in flight are canceled after it. Zero means
+	// DefaultDrainTimeout.
+	DrainTimeout time.Duration
+
+	wake    chan struct{} // signals new deliveries to the worker
+	closing chan struct{} // closed by Close
+	stopped chan struct{} // closed by the worker when it stops
+
+	// ctx cancels requests of the worker, and stops it, when Close gives
+	// up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+	errs   []error // errors of rejected deliveries, owned by the worker
+
+	// retries are failed deliveries by URLs of their hooks. They are
+	// owned by the worker.
+	retries map[string]*retry
+}
+
+// retry is a failed delivery waiting for the next attempt.
+type retry struct {
+	delivery Delivery
+	attempts int           // failed attempts
+	at       time.Time     // time of the next attempt
+	backoff  time.Duration // delay after the next failure
+	err      error         // error of the last attempt
+}
+
+// NewDispatcher creates dispatcher of events to hooks through outbox.
+func NewDispatcher(hooks []Hook, outbox *Outbox) *Dispatcher {
+	return &Dispatcher{Hooks: hooks, Outbox: outbox}
+}
+
+// Handle stores deliveries of event e to hooks subscribed to its kind. It
+// is a synchronous event.Handler, so deliveries are stored before the
+// statement publishing the event finishes.
+func (d *Dispatcher) Handle(e event.Event) error {
+	now := time.Now()
+	var deliveries []Delivery
+	for i := range d.Hooks {
+		h := &d.Hooks[i]
+		if !h.accepts(e.Kind()) {
+			continue
+		}
+
+		delivery, err := newDelivery(h, e, now)
+		if err != nil {
+			return fmt.Errorf("webhook %s: %s", h.URL, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.Outbox.Add(deliveries...); err != nil {
+		return fmt.Errorf("webhook %s event: %s", e.Kind(), err)
+	}
+
+	if d.wake != nil {
+		select {
+		case d.wake <- struct{}{}:
+		default: // the worker is already woken
+		}
+	}
+	return nil
+}
+
+// Start starts the worker posting pending deliveries, including those left
+// in the outbox by previous runs.
+func (d *Dispatcher) Start() {
+	d.wake = make(chan struct{}, 1)
+	d.closing = make(chan struct{})
+	d.stopped = make(chan struct{})
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		defer close(d.stopped)
+		closing := d.closing
+		for {
+			next, ok := d.deliverPending(closing == nil)
+			if !ok {
+				return
+			}
+
+			var timer *time.Timer
+			var timeout <-chan time.Time
+			if !next.IsZero() {
+				timer = time.NewTimer(time.Until(next))
+				timeout = timer.C
+			}
+			select {
+			case <-d.wake:
+			case <-timeout:
+			case <-closing:
+				// deliveries stored after the last wake are posted
+				// and failed ones retried by the next pass
+				closing = nil
+			case <-d.ctx.Done():
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// Close stops the worker after it posts pending deliveries, retrying
+// failed ones, or after DrainTimeout, and closes the outbox. It returns
+// errors of rejected deliveries and of those left in the outbox.
+func (d *Dispatcher) Close() error {
+	if d.closing != nil {
+		close(d.closing)
+		timer := time.NewTimer(d.drainTimeout())
+		select {
+		case <-d.stopped:
+		case <-timer.C:
+			d.cancel()
+			<-d.stopped
+		}
+		timer.Stop()
+		d.cancel()
+	}
+
+	errs := d.errs
+	pending := d.Outbox.Pending()
+	for _, delivery := range pending {
+		if r := d.retries[delivery.URL]; r != nil && r.delivery.ID == delivery.ID {
+			errs = append(errs, deliveryError(delivery, fmt.Errorf("%w after %d attempts", r.err, r.attempts)))
+		}
+	}
+	err := errors.Join(errs...)
+	if len(pending) > 0 {
+		err = errors.Join(err, fmt.Errorf("%d webhook deliveries left in outbox", len(pending)))
+	}
+	return errors.Join(err, d.Outbox.Close())
+}
+
+// deliverPending syncs the outbox and posts pending deliveries which are
+// not waiting for a retry, in order. It returns time of the earliest retry,
+// zero if none is scheduled. After Close, retries of deliveries without
+// attempts left are not scheduled and ok is false when there is nothing to
+// wait for or Close gave up waiting.
+func (d *Dispatcher) deliverPending(closing bool) (next time.Time, ok bool) {
+	if err := d.Outbox.Sync(); err != nil {
+		d.errs = append(d.errs, fmt.Errorf("webhook outbox: %w", err))
+	}
+
+	waiting := make(map[string]bool) // hooks with earlier delivery waiting
+	schedule := func(r *retry) {
+		if closing && r.attempts >= d.maxAttempts() {
+			return
+		}
+		if next.IsZero() || r.at.Before(next) {
+			next = r.at
+		}
+	}
+
+	for _, delivery := range d.Outbox.Pending() {
+		if d.ctx.Err() != nil {
+			return time.Time{}, false
+		}
+		if waiting[delivery.URL] {
+			continue
+		}
+		r := d.retries[delivery.URL]
+		if r != nil && (time.Now().Before(r.at) || closing && r.attempts >= d.maxAttempts()) {
+			waiting[delivery.URL] = true
+			schedule(r)
+			continue
+		}
+
+		err := d.post(delivery)
+		if d.ctx.Err() != nil {
+			// canceled by Close, the delivery stays in the outbox
+			return time.Time{}, false
+		}
+		var rejected *rejectedError
+		if err == nil || errors.As(err, &rejected) {
+			delete(d.retries, delivery.URL)
+			if doneErr := d.Outbox.Done(delivery.ID); doneErr != nil {
+				err = errors.Join(err, doneErr)
+			}
+			if err != nil {
+				d.errs = append(d.errs, deliveryError(delivery, err))
+			}
+			continue
+		}
+
+		if r == nil {
+			if d.retries == nil {
+				d.retries = make(map[string]*retry)
+			}
+			r = &retry{delivery: delivery, backoff: d.backoff()}
+			d.retries[delivery.URL] = r
+		}
+		r.attempts++
+		r.err = err
+		r.at = time.Now().Add(r.backoff)
+		r.backoff = min(2*r.backoff, d.maxBackoff())
+		waiting[delivery.URL] = true
+		schedule(r)
+	}
+	return next, !closing || !next.IsZero()
+}
+
+// deliveryError returns err of delivery with its hook, event and ID.
+func deliveryError(delivery Delivery, err error) error {
+	return fmt.Errorf("webhook %s %s delivery %s: %w", delivery.URL, delivery.Event, delivery.ID, err)
+}
+
+// rejectedError is returned when endpoint rejects delivery with client
+// error status, so retries would fail too.
+type rejectedError struct {
+	status string
+}
+
+func (e *rejectedError) Error() string {
+	return "rejected: " + e.status
+}
+
+// post posts payload of delivery once.
+func (d *Dispatcher) post(delivery Delivery) error {
+	h := d.hook(delivery.URL)
+	if h == nil {
+		return &rejectedError{"webhook is not configured"}
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return &rejectedError{err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, delivery.Payload))
+	}
+
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return &rejectedError{resp.Status}
+	}
+	return fmt.Errorf("status %s", resp.Status)
+}
+
+// hook returns hook of the URL, nil if it is not configured anymore.
+// Deliveries left by previous runs are signed with the current secret.
+func (d *Dispatcher) hook(url string) *Hook {
+	for i := range d.Hooks {
+		if d.Hooks[i].URL == url {
+			return &d.Hooks[i]
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return &http.Client{Timeout: DefaultTimeout}
+}
+
+func (d *Dispatcher) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+func (d *Dispatcher) backoff() time.Duration {
+	if d.Backoff > 0 {
+		return d.Backoff
+	}
+	return DefaultBackoff
+}
+
+func (d *Dispatcher) maxBackoff() time.Duration {
+	if d.MaxBackoff > 0 {
+		return d.MaxBackoff
+	}
+	return DefaultMaxBackoff
+}
+
+func (d *Dispatcher) drainTimeout() time.Duration {
+	if d.DrainTimeout > 0 {
+		return d.DrainTimeout
+	}
+	return DefaultDrainTimeout
+}
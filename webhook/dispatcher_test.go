@@ -0,0 +1,341 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"parking_lot/event"
+)
+
+// endpoint is a webhook endpoint answering requests with statuses in order,
+// the last one repeated.
+type endpoint struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+	times    []time.Time
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, r)
+	e.bodies = append(e.bodies, string(body))
+	e.times = append(e.times, time.Now())
+
+	status := e.statuses[min(len(e.requests), len(e.statuses))-1]
+	w.WriteHeader(status)
+}
+
+// newTestDispatcher creates dispatcher with short backoff.
+func newTestDispatcher(hooks []Hook, outbox *Outbox) *Dispatcher {
+	d := NewDispatcher(hooks, outbox)
+	d.MaxAttempts = 3
+	d.Backoff = 10 * time.Millisecond
+	d.MaxBackoff = 15 * time.Millisecond
+	return d
+}
+
+func TestDispatcher(t *testing.T) {
+	parked := &endpoint{statuses: []int{http.StatusOK}}
+	all := &endpoint{statuses: []int{http.StatusNoContent}}
+	parkedServer := httptest.NewServer(parked)
+	defer parkedServer.Close()
+	allServer := httptest.NewServer(all)
+	defer allServer.Close()
+
+	d := newTestDispatcher([]Hook{
+		{URL: parkedServer.URL, Events: []event.Kind{event.KindCarParked}, Secret: "secret"},
+		{URL: allServer.URL},
+	}, NewOutbox())
+	d.Start()
+
+	bus := event.NewBus()
+	bus.Subscribe(d.Handle, event.Kinds...)
+	events := []event.Event{
+		event.LotCreated{Capacity: 1},
+		event.CarParked{Slot: 1, RegistrationNumber: "KA-01-HH-1234", Colour: "White"},
+		event.LotFull{Capacity: 1},
+	}
+	for _, e := range events {
+		if err := bus.Publish(e); err != nil {
+			t.Fatalf("publish error: %s", err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("close error: %s", err)
+	}
+
+	if len(parked.requests) != 1 {
+		t.Fatalf("invalid number of car_parked requests - want: 1, got: %d", len(parked.requests))
+	}
+	r, body := parked.requests[0], parked.bodies[0]
+	if got := r.Header.Get(SignatureHeader); got != Sign("secret", []byte(body)) {
+		t.Errorf("invalid signature - want: %s, got: %s", Sign("secret", []byte(body)), got)
+	}
+	if got := r.Header.Get(EventHeader); got != "car_parked" {
+		t.Errorf("invalid event header - want: car_parked, got: %s", got)
+	}
+	var p struct {
+		ID    string
+		Event string
+		Data  event.CarParked
+	}
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatalf("invalid payload %s: %s", body, err)
+	}
+	if p.Event != "car_parked" || p.ID != r.Header.Get(DeliveryHeader) || p.Data != events[1] {
+		t.Errorf("invalid payload %s", body)
+	}
+
+	var kinds []string
+	for i, r := range all.requests {
+		kinds = append(kinds, r.Header.Get(EventHeader))
+		if r.Header.Get(SignatureHeader) != "" {
+			t.Errorf("payload of hook without secret is signed")
+		}
+		if !strings.Contains(all.bodies[i], `"event":"`+kinds[i]+`"`) {
+			t.Errorf("invalid payload %s", all.bodies[i])
+		}
+	}
+	if want := "lot_created car_parked lot_full"; strings.Join(kinds, " ") != want {
+		t.Errorf("invalid events - want: %s, got: %s", want, strings.Join(kinds, " "))
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	tests := []struct {
+		statuses []int
+		requests int
+		pending  int
+		err      string
+	}{
+		{[]int{500, 503, 200}, 3, 0, ""},
+		{[]int{429, 200}, 2, 0, ""},
+		{[]int{502}, 3, 1, "status 502 Bad Gateway"},
+		{[]int{500, 400}, 2, 0, "rejected: 400 Bad Request"},
+	}
+
+	for _, tt := range tests {
+		e := &endpoint{statuses: tt.statuses}
+		server := httptest.NewServer(e)
+
+		d := newTestDispatcher([]Hook{{URL: server.URL}}, NewOutbox())
+		d.Start()
+		d.Handle(event.LotCreated{Capacity: 1})
+		err := d.Close()
+		server.Close()
+
+		if len(e.requests) != tt.requests {
+			t.Errorf("%v invalid number of requests - want: %d, got: %d", tt.statuses, tt.requests, len(e.requests))
+		}
+		if n := len(d.Outbox.Pending()); n != tt.pending {
+			t.Errorf("%v invalid pending - want: %d, got: %d", tt.statuses, tt.pending, n)
+		}
+		if tt.err == "" && err != nil {
+			t.Errorf("%v unexpected error: %s", tt.statuses, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%v invalid error - want: %s, got: %v", tt.statuses, tt.err, err)
+		}
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	e := &endpoint{statuses: []int{500, 500, 500, 200}}
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	d := newTestDispatcher([]Hook{{URL: server.URL}}, NewOutbox())
+	d.MaxAttempts = 4
+	d.Backoff = 20 * time.Millisecond
+	d.MaxBackoff = 50 * time.Millisecond
+	d.Start()
+	d.Handle(event.LotCreated{Capacity: 1})
+	if err := d.Close(); err != nil {
+		t.Fatalf("close error: %s", err)
+	}
+
+	// delays 20ms, 40ms and 50ms limited by MaxBackoff
+	for i, want := range []time.Duration{20, 40, 50} {
+		got := e.times[i+1].Sub(e.times[i])
+		if got < want*time.Millisecond {
+			t.Errorf("retry %d too early - want: >= %dms, got: %s", i+1, want, got)
+		}
+	}
+}
+
+// waitRequests waits until endpoint receives n requests.
+func waitRequests(t *testing.T, e *endpoint, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		e.mu.Lock()
+		got := len(e.requests)
+		e.mu.Unlock()
+		if got >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("endpoint did not receive %d requests", n)
+}
+
+func TestDispatcherRetryLater(t *testing.T) {
+	e := &endpoint{statuses: []int{500, 500, 500, 500, 200}}
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	// running dispatcher retries deliveries after MaxAttempts too
+	d := newTestDispatcher([]Hook{{URL: server.URL}}, NewOutbox())
+	d.MaxAttempts = 2
+	d.Start()
+	d.Handle(event.LotCreated{Capacity: 1})
+	waitRequests(t, e, 5)
+	if err := d.Close(); err != nil {
+		t.Fatalf("close error: %s", err)
+	}
+	if n := len(d.Outbox.Pending()); n != 0 {
+		t.Errorf("invalid pending - want: 0, got: %d", n)
+	}
+}
+
+func TestDispatcherFailingHook(t *testing.T) {
+	down := &endpoint{statuses: []int{http.StatusServiceUnavailable}}
+	up := &endpoint{statuses: []int{http.StatusOK}}
+	downServer := httptest.NewServer(down)
+	defer downServer.Close()
+	upServer := httptest.NewServer(up)
+	defer upServer.Close()
+
+	d := newTestDispatcher([]Hook{{URL: downServer.URL}, {URL: upServer.URL}}, NewOutbox())
+	d.MaxAttempts = 2
+	d.Backoff = 300 * time.Millisecond
+	d.MaxBackoff = 300 * time.Millisecond
+	d.Start()
+
+	// the hook which is down waits for retry, the other one is served
+	d.Handle(event.LotCreated{Capacity: 2})
+	waitRequests(t, up, 1)
+	start := time.Now()
+	d.Handle(event.CarParked{Slot: 1, RegistrationNumber: "KA-01-HH-1234", Colour: "White"})
+	waitRequests(t, up, 2)
+	if delay := time.Since(start); delay >= d.Backoff {
+		t.Errorf("delivery to hook which is up waited for retry of the other - want: < %s, got: %s", d.Backoff, delay)
+	}
+
+	err := d.Close()
+	if len(down.requests) != 2 {
+		t.Errorf("invalid number of requests - want: 2, got: %d", len(down.requests))
+	}
+	if want := "status 503 Service Unavailable after 2 attempts"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("invalid close error - want: %s, got: %v", want, err)
+	}
+	if n := len(d.Outbox.Pending()); n != 2 {
+		t.Errorf("invalid pending - want: 2, got: %d", n)
+	}
+}
+
+func TestDispatcherDrainTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"backoff", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}},
+		{"hanging", func(w http.ResponseWriter, r *http.Request) {
+			// the body is read, so the server notices canceled request
+			io.ReadAll(r.Body)
+			<-r.Context().Done()
+		}},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(tt.handler)
+
+		// Close does not wait for all attempts of unreachable endpoint,
+		// the delivery stays in the outbox
+		d := newTestDispatcher([]Hook{{URL: server.URL}}, NewOutbox())
+		d.Backoff = time.Second
+		d.DrainTimeout = 50 * time.Millisecond
+		d.Start()
+		d.Handle(event.LotCreated{Capacity: 1})
+		start := time.Now()
+		err := d.Close()
+		if elapsed := time.Since(start); elapsed >= d.Backoff {
+			t.Errorf("%s close waited too long - want: < %s, got: %s", tt.name, d.Backoff, elapsed)
+		}
+		if want := "1 webhook deliveries left in outbox"; err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s invalid close error - want: %s, got: %v", tt.name, want, err)
+		}
+		if n := len(d.Outbox.Pending()); n != 1 {
+			t.Errorf("%s invalid pending - want: 1, got: %d", tt.name, n)
+		}
+		server.Close()
+	}
+}
+
+func TestDispatcherOutbox(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "outbox.jsonl")
+	e := &endpoint{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(e)
+	defer server.Close()
+	hooks := []Hook{{URL: server.URL, Secret: "secret"}}
+
+	// endpoint is down, deliveries stay in the outbox
+	outbox, err := OpenOutbox(filename)
+	if err != nil {
+		t.Fatalf("open outbox error: %s", err)
+	}
+	d := newTestDispatcher(hooks, outbox)
+	d.Handle(event.LotCreated{Capacity: 2})
+	d.Handle(event.CarParked{Slot: 1, RegistrationNumber: "KA-01-HH-1234", Colour: "White"})
+	d.Start()
+	if err := d.Close(); err == nil {
+		t.Errorf("close expected error of failed deliveries")
+	}
+
+	// after restart pending deliveries are delivered in order
+	e.mu.Lock()
+	e.statuses, e.requests, e.bodies = []int{http.StatusOK}, nil, nil
+	e.mu.Unlock()
+	outbox, err = OpenOutbox(filename)
+	if err != nil {
+		t.Fatalf("reopen outbox error: %s", err)
+	}
+	if n := len(outbox.Pending()); n != 2 {
+		t.Fatalf("invalid pending after restart - want: 2, got: %d", n)
+	}
+	d = newTestDispatcher(hooks, outbox)
+	d.Start()
+	if err := d.Close(); err != nil {
+		t.Fatalf("close error: %s", err)
+	}
+
+	var kinds []string
+	for _, r := range e.requests {
+		kinds = append(kinds, r.Header.Get(EventHeader))
+	}
+	if want := "lot_created car_parked"; strings.Join(kinds, " ") != want {
+		t.Errorf("invalid delivered events - want: %s, got: %s", want, strings.Join(kinds, " "))
+	}
+
+	outbox, err = OpenOutbox(filename)
+	if err != nil {
+		t.Fatalf("reopen outbox error: %s", err)
+	}
+	defer outbox.Close()
+	if n := len(outbox.Pending()); n != 0 {
+		t.Errorf("invalid pending after delivery - want: 0, got: %d", n)
+	}
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"parking_lot/event"
+)
+
+// Delivery is a payload waiting to be posted to URL of hook.
+type Delivery struct {
+	ID      string          `json:"id"`
+	URL     string          `json:"url"`
+	Event   event.Kind      `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// record is a line of outbox file: added delivery or ID of finished one.
+type record struct {
+	Add  *Delivery `json:"add,omitempty"`
+	Done string    `json:"done,omitempty"`
+}
+
+// Outbox keeps deliveries until they are finished. Outbox opened with
+// OpenOutbox stores them in a file, so deliveries pending when the program
+// stops are sent after restart.
+//
+// The file is a log of JSON lines, every line adds a delivery or finishes
+// one. It is compacted to pending deliveries when opened.
+//
+// Added deliveries are written at once, but synced to disk by Sync, so
+// the disk flush is not paid by the caller of Add; Dispatcher syncs them
+// before posting. Finished deliveries are not synced: a crash may lose the
+// record and the delivery is posted again after restart, receivers
+// recognize it by the X-Parking-Lot-Delivery header. The file is synced
+// when it is closed.
+type Outbox struct {
+	mu       sync.Mutex
+	file     *os.File // nil for memory outbox
+	pending  []Delivery
+	unsynced bool // deliveries were added since the last sync
+}
+
+// NewOutbox creates outbox keeping deliveries in memory.
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+// OpenOutbox opens outbox stored in file, which is created if it does not
+// exist.
+func OpenOutbox(filename string) (*Outbox, error) {
+	o := &Outbox{}
+	if err := o.load(filename); err != nil {
+		return nil, fmt.Errorf("webhook outbox %s: %s", filename, err)
+	}
+	if err := o.compact(filename); err != nil {
+		return nil, fmt.Errorf("webhook outbox %s: %s", filename, err)
+	}
+	return o, nil
+}
+
+// load reads pending deliveries from file. Missing file has none.
+func (o *Outbox) load(filename string) error {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	var invalid error
+	for line := 1; s.Scan(); line++ {
+		if invalid != nil {
+			return invalid
+		}
+
+		var r record
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			// only the last line may be invalid, it is cut by a crash
+			// while it was written
+			invalid = fmt.Errorf("line %d: %s", line, err)
+			continue
+		}
+		if r.Add != nil {
+			o.pending = append(o.pending, *r.Add)
+		} else {
+			o.remove(r.Done)
+		}
+	}
+	return s.Err()
+}
+
+// compact writes pending deliveries to new file which replaces filename,
+// and opens it for appending.
+func (o *Outbox) compact(filename string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for i := range o.pending {
+		if err := enc.Encode(record{Add: &o.pending[i]}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+
+	o.file, err = os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	return err
+}
+
+// append writes records to the file by a single write.
+func (o *Outbox) append(records ...record) error {
+	if o.file == nil {
+		return nil
+	}
+
+	var b []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		b = append(append(b, line...), '\n')
+	}
+	_, err := o.file.Write(b)
+	return err
+}
+
+// remove removes pending delivery with id.
+func (o *Outbox) remove(id string) {
+	for i := range o.pending {
+		if o.pending[i].ID == id {
+			o.pending = append(o.pending[:i:i], o.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// Add stores deliveries by a single write, see Sync. They are pending
+// until Done.
+func (o *Outbox) Add(deliveries ...Delivery) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	records := make([]record, len(deliveries))
+	for i := range deliveries {
+		records[i] = record{Add: &deliveries[i]}
+	}
+	if err := o.append(records...); err != nil {
+		return err
+	}
+	o.pending = append(o.pending, deliveries...)
+	o.unsynced = o.file != nil
+	return nil
+}
+
+// Sync syncs deliveries added since the last sync to disk.
+func (o *Outbox) Sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.unsynced {
+		return nil
+	}
+	o.unsynced = false
+	return o.file.Sync()
+}
+
+// Done finishes delivery with id. The record is not synced, see Outbox.
+func (o *Outbox) Done(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.append(record{Done: id}); err != nil {
+		return err
+	}
+	o.remove(id)
+	return nil
+}
+
+// Pending returns pending deliveries in order they were added.
+func (o *Outbox) Pending() []Delivery {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Delivery(nil), o.pending...)
+}
+
+// Close syncs and closes file of the outbox.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := errors.Join(o.file.Sync(), o.file.Close())
+	o.file = nil
+	o.unsynced = false
+	return err
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testDeliveries = []Delivery{
+	{ID: "1", URL: "http://example.com/a", Event: "car_parked", Payload: []byte(`{"id":"1"}`)},
+	{ID: "2", URL: "http://example.com/b", Event: "car_left", Payload: []byte(`{"id":"2"}`)},
+	{ID: "3", URL: "http://example.com/a", Event: "lot_full", Payload: []byte(`{"id":"3"}`)},
+}
+
+func TestOutbox(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	o, err := OpenOutbox(filename)
+	if err != nil {
+		t.Fatalf("open error: %s", err)
+	}
+	if err := o.Add(testDeliveries[0]); err != nil {
+		t.Fatalf("add error: %s", err)
+	}
+	// deliveries added at once are written together, Sync flushes
+	// those added since the last sync
+	if err := o.Add(testDeliveries[1:]...); err != nil {
+		t.Fatalf("add error: %s", err)
+	}
+	if err := o.Sync(); err != nil {
+		t.Fatalf("sync error: %s", err)
+	}
+	if err := o.Done("2"); err != nil {
+		t.Fatalf("done error: %s", err)
+	}
+	want := []Delivery{testDeliveries[0], testDeliveries[2]}
+	if got := o.Pending(); !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid pending - want: %v, got: %v", want, got)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatalf("close error: %s", err)
+	}
+
+	// pending deliveries are loaded and the file is compacted
+	o, err = OpenOutbox(filename)
+	if err != nil {
+		t.Fatalf("reopen error: %s", err)
+	}
+	defer o.Close()
+	if got := o.Pending(); !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid pending after reopen - want: %v, got: %v", want, got)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFile := `{"add":{"id":"1","url":"http://example.com/a","event":"car_parked","payload":{"id":"1"}}}` + "\n" +
+		`{"add":{"id":"3","url":"http://example.com/a","event":"lot_full","payload":{"id":"3"}}}` + "\n"
+	if string(b) != wantFile {
+		t.Errorf("invalid compacted file - want: %s, got: %s", wantFile, b)
+	}
+}
+
+func TestOpenOutboxInvalid(t *testing.T) {
+	dir := t.TempDir()
+	add := `{"add":{"id":"1","url":"http://example.com/a","event":"car_parked","payload":{"id":"1"}}}`
+
+	tests := []struct {
+		data    string
+		pending int
+		err     bool
+	}{
+		{add + "\n" + `{"add":{"id":"2","ur`, 1, false}, // cut by crash
+		{add + "\n" + `{"done":"1"}` + "\n", 0, false},
+		{`{"add":` + "\n" + add + "\n", 0, true},
+	}
+
+	for i, tt := range tests {
+		filename := filepath.Join(dir, "outbox.jsonl")
+		if err := os.WriteFile(filename, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		o, err := OpenOutbox(filename)
+		if (err != nil) != tt.err {
+			t.Errorf("%d. invalid error - want error: %t, got: %v", i, tt.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if n := len(o.Pending()); n != tt.pending {
+			t.Errorf("%d. invalid pending - want: %d, got: %d", i, tt.pending, n)
+		}
+		o.Close()
+	}
+}
@@ -0,0 +1,133 @@
+// Package webhook delivers events of parking lot to HTTP endpoints.
+//
+// Every event is posted as JSON payload to URLs of hooks subscribed to its
+// kind:
+//
+//	{
+//		"id": "5f0c4bd2e1a39c7e0b6d1f2a8c4e9b13",
+//		"event": "car_parked",
+//		"time": "2026-10-19T08:30:00Z",
+//		"data": {"slot": 1, "registration": "KA-01-HH-1234", "colour": "White"}
+//	}
+//
+// The payload of a hook with secret is signed with HMAC-SHA256 of the
+// secret, the signature is sent in the X-Parking-Lot-Signature header as
+// "sha256=" followed by hex encoded MAC, see Sign. Deliveries are stored in
+// Outbox before they are sent and failed ones are retried with exponential
+// backoff, so events are not lost when the endpoint is down or the program
+// restarts.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"parking_lot/event"
+)
+
+// Headers of webhook requests.
+const (
+	SignatureHeader = "X-Parking-Lot-Signature"
+	EventHeader     = "X-Parking-Lot-Event"
+	DeliveryHeader  = "X-Parking-Lot-Delivery"
+)
+
+// Hook is an endpoint receiving events.
+type Hook struct {
+	// URL is http or https URL events are posted to.
+	URL string `json:"url"`
+
+	// Events are kinds of events posted to the URL. Empty list means all
+	// kinds, see event.Kinds.
+	Events []event.Kind `json:"events"`
+
+	// Secret signs payloads. Empty secret means payloads are not signed.
+	Secret string `json:"secret"`
+}
+
+// Validate checks the URL and kinds of events of the hook.
+func (h *Hook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL %q: %s", h.URL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: use http or https URL", h.URL)
+	}
+
+	for _, k := range h.Events {
+		if !isKind(k) {
+			return fmt.Errorf("webhook %s: unknown event %q", h.URL, k)
+		}
+	}
+	return nil
+}
+
+// accepts reports whether hook receives events of kind k.
+func (h *Hook) accepts(k event.Kind) bool {
+	if len(h.Events) == 0 {
+		return isKind(k)
+	}
+	for _, kind := range h.Events {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// isKind reports whether k is kind of event.Kinds.
+func isKind(k event.Kind) bool {
+	for _, kind := range event.Kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns signature of payload with secret, "sha256=" followed by hex
+// encoded HMAC-SHA256. Receivers compare it to the X-Parking-Lot-Signature
+// header with hmac.Equal.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// payload is the JSON posted to hooks.
+type payload struct {
+	ID    string      `json:"id"`
+	Event event.Kind  `json:"event"`
+	Time  time.Time   `json:"time"`
+	Data  event.Event `json:"data"`
+}
+
+// newID returns random ID of delivery.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newDelivery creates delivery of event e to hook h.
+func newDelivery(h *Hook, e event.Event, now time.Time) (Delivery, error) {
+	id, err := newID()
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	body, err := json.Marshal(payload{ID: id, Event: e.Kind(), Time: now.UTC(), Data: e})
+	if err != nil {
+		return Delivery{}, err
+	}
+	return Delivery{ID: id, URL: h.URL, Event: e.Kind(), Payload: body}, nil
+}
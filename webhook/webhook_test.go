@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"parking_lot/event"
+)
+
+func TestHookValidate(t *testing.T) {
+	tests := []struct {
+		hook Hook
+		want string
+	}{
+		{Hook{URL: "https://example.com/hooks", Events: []event.Kind{event.KindCarParked}}, ""},
+		{Hook{URL: "http://localhost:8080"}, ""},
+		{Hook{URL: "ftp://example.com"}, `invalid webhook URL "ftp://example.com": use http or https URL`},
+		{Hook{URL: "example.com/hooks"}, `invalid webhook URL "example.com/hooks": use http or https URL`},
+		{Hook{URL: "https://example.com", Events: []event.Kind{"car_washed"}}, `webhook https://example.com: unknown event "car_washed"`},
+	}
+
+	for _, tt := range tests {
+		err := tt.hook.Validate()
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s invalid error - want: %q, got: %q", tt.hook.URL, tt.want, got)
+		}
+	}
+}
+
+func TestHookAccepts(t *testing.T) {
+	all := Hook{}
+	some := Hook{Events: []event.Kind{event.KindCarParked, event.KindCarLeft}}
+
+	for _, k := range event.Kinds {
+		if !all.accepts(k) {
+			t.Errorf("hook without filter does not accept %s", k)
+		}
+		if want := k == event.KindCarParked || k == event.KindCarLeft; some.accepts(k) != want {
+			t.Errorf("invalid accepts %s - want: %t, got: %t", k, want, some.accepts(k))
+		}
+	}
+	if all.accepts("result") {
+		t.Errorf("hook without filter accepts unknown kind")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// printf '{"id":"1"}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=6146142a2ce0159e84c0767881e4ec80bc397da62526e7d19f70795eb79460c0"
+	if got := Sign("secret", []byte(`{"id":"1"}`)); got != want {
+		t.Errorf("invalid signature - want: %s, got: %s", want, got)
+	}
+}
+
+func TestNewDelivery(t *testing.T) {
+	h := &Hook{URL: "https://example.com"}
+	now := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+	d, err := newDelivery(h, event.CarParked{Slot: 1, RegistrationNumber: "KA-01-HH-1234", Colour: "White"}, now)
+	if err != nil {
+		t.Fatalf("new delivery error: %s", err)
+	}
+
+	if d.URL != h.URL || d.Event != event.KindCarParked || len(d.ID) != 32 {
+		t.Errorf("invalid delivery %+v", d)
+	}
+	want := `{"id":"` + d.ID + `","event":"car_parked","time":"2026-10-19T08:30:00Z","data":{"slot":1,"registration":"KA-01-HH-1234","colour":"White"}}`
+	if string(d.Payload) != want {
+		t.Errorf("invalid payload - want: %s, got: %s", want, d.Payload)
+	}
+	if !json.Valid(d.Payload) {
+		t.Errorf("invalid JSON payload %s", d.Payload)
+	}
+}